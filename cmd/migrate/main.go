@@ -0,0 +1,124 @@
+package main
+
+import (
+	"GoCleanArch/configs"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/database/migration"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: migrate [-config path] <command>
+
+Commands:
+  up          apply every pending migration
+  down [N]    roll back the N most recent migrations (default 1)
+  status      list migrations and whether they have been applied
+  force V     record the schema as being at version V without running any SQL
+`
+
+func main() {
+	// Configuration
+	configPath := flag.String("config", "./configs/config.yaml", "path to config file")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := configs.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	dbCfg := cfg.Database()
+	if dbCfg.Driver == "" {
+		log.Fatalf("no database is configured for the %s environment", cfg.Env)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.Open(dbCfg.Driver, dbCfg.DSN)
+	if err != nil {
+		log.Fatalf("could not connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrations, err := migration.Migrations(dbCfg.Driver)
+	if err != nil {
+		log.Fatalf("could not load migrations: %v", err)
+	}
+	migrator := migration.NewMigrator(db, dbCfg.Driver, migrations)
+
+	if err := run(ctx, migrator, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run executes one migrate command.
+func run(ctx context.Context, migrator *migration.Migrator, args []string) error {
+	switch command := args[0]; command {
+	case "up":
+		count, err := migrator.Up(ctx)
+		log.Printf("Applied %d migrations", count)
+		return err
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("down: N must be a positive integer, got %q", args[1])
+			}
+		}
+		count, err := migrator.Down(ctx, n)
+		log.Printf("Rolled back %d migrations", count)
+		return err
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "-"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+		}
+		return w.Flush()
+
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force: missing version")
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return fmt.Errorf("force: version must be a non-negative integer, got %q", args[1])
+		}
+		if err := migrator.Force(ctx, version); err != nil {
+			return err
+		}
+		log.Printf("Forced schema version to %d", version)
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", command, usage)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"GoCleanArch/configs"
+	"GoCleanArch/internal/infra/messaging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const usage = `Usage: ordersctl [-config path] <command>
+
+Commands:
+  dlq attach              set the order queue's redrive policy to worker.max_receives
+  dlq list [N]            list the first N dead-lettered order messages (default all)
+  dlq inspect ID          show a dead-lettered message with its attributes and body
+  dlq redrive ID...|all   move dead-lettered messages back to the order queue
+  dlq purge ID...|all     delete dead-lettered messages
+`
+
+func main() {
+	// Configuration
+	configPath := flag.String("config", "./configs/config.yaml", "path to config file")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := configs.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	if cfg.Env == "dev" {
+		// The in-memory queue cannot be shared between processes.
+		log.Fatalf("ordersctl only runs in production mode; in development mode the dead-letter queue lives in the server")
+	}
+	if cfg.Messaging.Driver != "" && cfg.Messaging.Driver != messaging.DriverSQS {
+		log.Fatalf("dead-letter queues are only supported with the %q messaging driver", messaging.DriverSQS)
+	}
+	if cfg.Prod.AWS.SQSDeadLetterQueueURL == "" {
+		log.Fatalf("prod.aws.sqs_dlq_url is required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	awsCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(cfg.Prod.AWS.Region))
+	if err != nil {
+		log.Fatalf("unable to load AWS config, %v", err)
+	}
+	deadLetterQueue := messaging.NewDeadLetterQueueSQS(sqs.NewFromConfig(awsCfg), cfg.Prod.AWS.SQSDeadLetterQueueURL, cfg.Prod.AWS.SQSQueueURL)
+
+	if err := run(ctx, deadLetterQueue, cfg.Worker.MaxReceives, flag.Args()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run executes one ordersctl command. maxReceives is the worker.max_receives setting.
+func run(ctx context.Context, deadLetterQueue *messaging.DeadLetterQueueSQS, maxReceives int, args []string) error {
+	if args[0] != "dlq" {
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], usage)
+	}
+	if len(args) < 2 {
+		return fmt.Errorf("dlq: missing command\n\n%s", usage)
+	}
+
+	switch command, args := args[1], args[2:]; command {
+	case "attach":
+		if maxReceives < 1 {
+			return fmt.Errorf("dlq attach: worker.max_receives must be set")
+		}
+		changed, err := deadLetterQueue.Attach(ctx, maxReceives)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Printf("Set the redrive policy to move messages to the dead-letter queue after %d deliveries", maxReceives)
+		} else {
+			log.Printf("The redrive policy already moves messages to the dead-letter queue after %d deliveries", maxReceives)
+		}
+		return nil
+
+	case "list":
+		limit := 0
+		if len(args) > 0 {
+			var err error
+			if limit, err = strconv.Atoi(args[0]); err != nil || limit < 1 {
+				return fmt.Errorf("dlq list: N must be a positive integer, got %q", args[0])
+			}
+		}
+		deadLetters, err := deadLetterQueue.List(ctx, limit)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTYPE\tSENT AT\tRECEIVES")
+		for _, deadLetter := range deadLetters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", deadLetter.ID, deadLetter.Type, deadLetter.SentAt.Format(time.RFC3339), deadLetter.ReceiveCount)
+		}
+		return w.Flush()
+
+	case "inspect":
+		if len(args) != 1 {
+			return fmt.Errorf("dlq inspect: expected one message ID")
+		}
+		deadLetters, err := deadLetterQueue.List(ctx, 0)
+		if err != nil {
+			return err
+		}
+		index := slices.IndexFunc(deadLetters, func(deadLetter *messaging.DeadLetter) bool { return deadLetter.ID == args[0] })
+		if index < 0 {
+			return fmt.Errorf("dlq inspect: no dead-lettered message %q", args[0])
+		}
+		printDeadLetter(deadLetters[index])
+		return nil
+
+	case "redrive":
+		ids, err := messageIDs("redrive", args)
+		if err != nil {
+			return err
+		}
+		count, err := deadLetterQueue.Redrive(ctx, ids)
+		log.Printf("Redrove %d messages", count)
+		return err
+
+	case "purge":
+		ids, err := messageIDs("purge", args)
+		if err != nil {
+			return err
+		}
+		count, err := deadLetterQueue.Purge(ctx, ids)
+		log.Printf("Purged %d messages", count)
+		return err
+
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", "dlq "+command, usage)
+	}
+}
+
+// messageIDs returns the message IDs a command acts on, or nil for "all".
+// Acting on every message has to be asked for, so that a command run without
+// arguments does nothing.
+func messageIDs(command string, args []string) ([]string, error) {
+	switch {
+	case len(args) == 0:
+		return nil, fmt.Errorf("dlq %s: expected message IDs or \"all\"", command)
+	case len(args) == 1 && args[0] == "all":
+		return nil, nil
+	default:
+		return args, nil
+	}
+}
+
+// printDeadLetter prints a dead letter, indenting its body when it is JSON.
+func printDeadLetter(deadLetter *messaging.DeadLetter) {
+	fmt.Printf("ID:        %s\n", deadLetter.ID)
+	fmt.Printf("Type:      %s\n", deadLetter.Type)
+	fmt.Printf("Sent at:   %s\n", deadLetter.SentAt.Format(time.RFC3339))
+	fmt.Printf("Receives:  %d\n", deadLetter.ReceiveCount)
+	fmt.Println("Attributes:")
+	for _, name := range slices.Sorted(maps.Keys(deadLetter.Attributes)) {
+		fmt.Printf("  %s: %s\n", name, deadLetter.Attributes[name])
+	}
+	fmt.Println("Body:")
+	var body bytes.Buffer
+	if err := json.Indent(&body, deadLetter.Body, "", "  "); err != nil {
+		fmt.Println(string(deadLetter.Body))
+		return
+	}
+	fmt.Println(body.String())
+}
@@ -5,11 +5,13 @@ import (
 	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/domain/repository"
 	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/database/migration"
 	"GoCleanArch/internal/infra/handler"
 	"GoCleanArch/internal/infra/messaging"
+	"GoCleanArch/internal/infra/payment"
+	"GoCleanArch/internal/infra/worker"
 	"GoCleanArch/internal/usecase"
 	"context"
-	"database/sql"
 	"flag"
 	"log"
 	"net/http"
@@ -19,7 +21,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
@@ -32,62 +33,188 @@ func main() {
 		log.Fatalf("could not load config: %v", err)
 	}
 
+	messageFormat, err := messaging.ParseMessageFormat(cfg.Prod.AWS.SQSMessageFormat)
+	if err != nil {
+		log.Fatalf("invalid prod.aws.sqs_message_format: %v", err)
+	}
+
 	var orderRepo repository.OrderRepository
-	var orderMessageQueue repository.OrderMessageQueue
+	var orderOutbox repository.OrderOutbox
+	var eventPublisher repository.EventPublisher
+	var orderMessageQueueMock *messaging.OrderMessageQueueMock
+	var idempotencyStore repository.IdempotencyStore
+	var paymentRepo repository.PaymentRepository
+	var refundRepo repository.RefundRepository
 
 	if cfg.Env == "dev" {
 		log.Println("Running in development mode")
 		// Mocks for dev environment
-		orderRepoMock := database.NewOrderRepositoryMock()
-		orderMessageQueue = messaging.NewOrderMessageQueueMock()
-
-		// Pre-populating the mock database for the GET endpoint
-		prePopulatedOrder := &entity.Order{
-			ID:        "123",
-			Data:      "Sample Order Data",
-			OrderID:   123,
-			Status:    "Completed",
-			Paid:      true,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+		orderMessageQueueMock = messaging.NewOrderMessageQueueMock()
+		// The in-memory queue encodes messages like the SQS queue does, so
+		// that consumers can be tried against the production format.
+		orderMessageQueueMock.Format = messageFormat
+		orderMessageQueueMock.MaxReceives = cfg.Worker.MaxReceives
+		eventPublisher = orderMessageQueueMock
+
+		if cfg.Dev.DB.Driver == "" {
+			orderRepoMock := database.NewOrderRepositoryMock()
+			refundRepoMock := database.NewRefundRepositoryMock()
+			orderRepoMock.Refunds = refundRepoMock
+
+			// Pre-populating the mock database for the GET endpoint
+			prePopulatedOrder := &entity.Order{
+				ID:        "0197a3c0-0000-7000-8000-000000000123",
+				Data:      "Sample Order Data",
+				OrderID:   123,
+				Status:    entity.OrderStatusDelivered,
+				Paid:      true,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			orderRepoMock.Save(context.Background(), prePopulatedOrder)
+			orderRepo = orderRepoMock
+			orderOutbox = orderRepoMock
+			idempotencyStore = database.NewIdempotencyStoreMock()
+			paymentRepo = database.NewPaymentRepositoryMock()
+			refundRepo = refundRepoMock
 		}
-		orderRepoMock.Save(prePopulatedOrder)
-		orderRepo = orderRepoMock
 	} else {
 		log.Println("Running in production mode")
 		// Real implementations for prod environment
 
-		// SQS
-		awsCfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(cfg.Prod.AWS.Region))
-		if err != nil {
-			log.Fatalf("unable to load AWS config, %v", err)
+		// Message broker
+		switch cfg.Messaging.Driver {
+		case "", messaging.DriverSQS:
+			awsCfg, err := awsConfig.LoadDefaultConfig(context.TODO(), awsConfig.WithRegion(cfg.Prod.AWS.Region))
+			if err != nil {
+				log.Fatalf("unable to load AWS config, %v", err)
+			}
+			sqsClient := sqs.NewFromConfig(awsCfg)
+			eventPublisher = messaging.NewOrderMessageQueueSQS(sqsClient, cfg.Prod.AWS.SQSQueueURL, messageFormat)
+		case messaging.DriverKafka:
+			kafkaCfg := cfg.Messaging.Kafka
+			kafkaFormat, err := messaging.ParseMessageFormat(kafkaCfg.MessageFormat)
+			if err != nil {
+				log.Fatalf("invalid messaging.kafka.message_format: %v", err)
+			}
+			kafkaClient, err := messaging.NewKafkaClient(kafkaCfg.Brokers, kafkaCfg.Topic, "", kafkaCfg.Acks, kafkaCfg.Idempotent)
+			if err != nil {
+				log.Fatalf("could not create Kafka client: %v", err)
+			}
+			defer kafkaClient.Close()
+			eventPublisher = messaging.NewOrderMessageQueueKafka(kafkaClient, kafkaCfg.Topic, kafkaFormat)
+		case messaging.DriverAMQP:
+			amqpCfg := cfg.Messaging.AMQP
+			amqpFormat, err := messaging.ParseMessageFormat(amqpCfg.MessageFormat)
+			if err != nil {
+				log.Fatalf("invalid messaging.amqp.message_format: %v", err)
+			}
+			amqpQueue := messaging.NewOrderMessageQueueAMQP(amqpCfg.URL, amqpCfg.Exchange, "", nil, 0, amqpFormat)
+			defer amqpQueue.Close()
+			eventPublisher = amqpQueue
+		case messaging.DriverNATS:
+			natsCfg := cfg.Messaging.NATS
+			natsFormat, err := messaging.ParseMessageFormat(natsCfg.MessageFormat)
+			if err != nil {
+				log.Fatalf("invalid messaging.nats.message_format: %v", err)
+			}
+			natsConn, js, err := messaging.ConnectNATS(context.TODO(), natsCfg.URL, natsCfg.Stream, natsCfg.Subject, natsCfg.DuplicateWindow)
+			if err != nil {
+				log.Fatalf("could not connect to NATS: %v", err)
+			}
+			defer natsConn.Close()
+			eventPublisher = messaging.NewOrderMessageQueueNATS(js, natsCfg.Stream, natsCfg.Subject, "", natsFormat)
+		default:
+			log.Fatalf("unsupported messaging driver %q", cfg.Messaging.Driver)
 		}
-		sqsClient := sqs.NewFromConfig(awsCfg)
-		orderMessageQueue = messaging.NewOrderMessageQueueSQS(sqsClient, cfg.Prod.AWS.SQSQueueURL)
+	}
 
-		// MySQL
-		db, err := sql.Open(cfg.Prod.DB.Driver, cfg.Prod.DB.DSN)
+	// Database
+	if cfg.Env != "dev" && cfg.Prod.DB.Driver == "" {
+		log.Fatalf("prod.db.driver is required: mysql, postgres or sqlite")
+	}
+	if dbCfg := cfg.Database(); dbCfg.Driver != "" {
+		db, err := database.Open(dbCfg.Driver, dbCfg.DSN)
 		if err != nil {
 			log.Fatalf("could not connect to database: %v", err)
 		}
 		defer db.Close()
-		orderRepo = database.NewOrderRepository(db)
+
+		migrations, err := migration.Migrations(dbCfg.Driver)
+		if err != nil {
+			log.Fatalf("could not load migrations: %v", err)
+		}
+		migrator := migration.NewMigrator(db, dbCfg.Driver, migrations)
+		if cfg.Env == "dev" {
+			// A development database is brought up to date on every start.
+			applied, err := migrator.Up(context.Background())
+			if err != nil {
+				log.Fatalf("could not migrate database: %v", err)
+			}
+			log.Printf("Applied %d migrations", applied)
+		} else if dbCfg.RequireCurrentSchema {
+			if err := migrator.Verify(context.Background()); err != nil {
+				log.Fatalf("refusing to start: %v; run the migrate command", err)
+			}
+		}
+
+		orderRepoSQL := database.NewOrderRepositorySQL(db, dbCfg.Driver)
+		orderRepo = orderRepoSQL
+		orderOutbox = orderRepoSQL
+		paymentRepo = database.NewPaymentRepositorySQL(db, dbCfg.Driver)
+		refundRepo = database.NewRefundRepositorySQL(db, dbCfg.Driver)
+		idempotencyStore = database.NewIdempotencyStoreSQL(db, dbCfg.Driver)
+	}
+
+	// Payments
+	var paymentGateway repository.PaymentGateway
+	switch cfg.Payments.Provider {
+	case "", "fake":
+		log.Println("Using the fake payment gateway")
+		paymentGateway = payment.NewPaymentGatewayMock(cfg.Payments.Declines)
+	default:
+		log.Fatalf("unsupported payment provider %q", cfg.Payments.Provider)
+	}
+
+	if orderMessageQueueMock != nil {
+		// The in-memory queue only lives in this process, so the consumer that
+		// drains it into the database subscribes to it here.
+		consumeOrdersUseCase := usecase.NewConsumeOrdersUseCase(orderMessageQueueMock, orderRepo, cfg.Worker.RetryBaseDelay, cfg.Worker.RetryMaxDelay)
+		orderMessageQueueMock.Subscribe(consumeOrdersUseCase.Handle)
 	}
 
 	// Use Cases
-	createOrderUseCase := usecase.NewCreateOrderUseCase(orderMessageQueue)
+	createOrderUseCase := usecase.NewCreateOrderUseCase(orderOutbox)
 	getOrderUseCase := usecase.NewGetOrderByIDUseCase(orderRepo)
+	getOrderByOrderIDUseCase := usecase.NewGetOrderByOrderIDUseCase(orderRepo)
 	getAllOrdersUseCase := usecase.NewGetAllOrdersUseCase(orderRepo)
+	updateOrderUseCase := usecase.NewUpdateOrderUseCase(orderRepo, orderOutbox)
+	patchOrderUseCase := usecase.NewPatchOrderUseCase(orderRepo, orderOutbox)
+	deleteOrderUseCase := usecase.NewDeleteOrderUseCase(orderRepo)
+	payOrderUseCase := usecase.NewPayOrderUseCase(orderRepo, paymentRepo, paymentGateway, orderOutbox)
+	refundOrderUseCase := usecase.NewRefundOrderUseCase(orderRepo, paymentRepo, refundRepo, paymentGateway, orderOutbox)
+	relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderOutbox, eventPublisher, cfg.Outbox.BatchSize, cfg.Outbox.MaxAttempts)
+
+	// Outbox relay
+	go worker.NewOutboxRelay(relayOutboxUseCase, cfg.Outbox.PollInterval).Run(context.Background())
 
 	// Handlers
-	orderHandler := handler.NewOrderHandler(createOrderUseCase, getOrderUseCase, getAllOrdersUseCase)
+	orderHandler := handler.NewOrderHandler(createOrderUseCase, getOrderUseCase, getOrderByOrderIDUseCase, getAllOrdersUseCase, updateOrderUseCase, patchOrderUseCase, deleteOrderUseCase)
+	paymentHandler := handler.NewPaymentHandler(payOrderUseCase, refundOrderUseCase)
 
 	// Router
 	r := chi.NewRouter()
 	r.Use(middleware.Logger) // Add a logger middleware
-	r.Post("/orders", orderHandler.CreateOrder)
-	r.Get("/orders/{orderId}", orderHandler.GetOrder)
+	r.Use(handler.CorrelationID)
+	r.With(handler.Idempotency(idempotencyStore, cfg.Server.IdempotencyTTL)).Post("/orders", orderHandler.CreateOrder)
+	r.Get("/orders/{id}", orderHandler.GetOrder)
+	r.Get("/orders/by-order-id/{orderId}", orderHandler.GetOrderByOrderID)
 	r.Get("/orders", orderHandler.GetAllOrders)
+	r.Put("/orders/by-order-id/{orderId}", orderHandler.UpdateOrder)
+	r.Patch("/orders/by-order-id/{orderId}", orderHandler.PatchOrder)
+	r.Delete("/orders/by-order-id/{orderId}", orderHandler.DeleteOrder)
+	r.With(handler.Idempotency(idempotencyStore, cfg.Server.IdempotencyTTL)).Post("/orders/by-order-id/{orderId}/payments", paymentHandler.PayOrder)
+	r.With(handler.Idempotency(idempotencyStore, cfg.Server.IdempotencyTTL)).Post("/orders/by-order-id/{orderId}/refunds", paymentHandler.RefundOrder)
 
 	log.Printf("Server is running on port %s", cfg.Server.Port)
 	if err := http.ListenAndServe(cfg.Server.Port, r); err != nil {
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"GoCleanArch/configs"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/messaging"
+	"GoCleanArch/internal/infra/worker"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"syscall"
+
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func main() {
+	// Configuration
+	configPath := flag.String("config", "./configs/config.yaml", "path to config file")
+	flag.Parse()
+
+	cfg, err := configs.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("could not load config: %v", err)
+	}
+
+	if cfg.Env == "dev" {
+		// The in-memory queue cannot be shared between processes.
+		log.Fatalf("the worker only runs in production mode; in development mode the server drains the in-memory queue itself")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Message broker
+	var orderMessageConsumer repository.OrderMessageConsumer
+	switch cfg.Messaging.Driver {
+	case "", messaging.DriverSQS:
+		messageFormat, err := messaging.ParseMessageFormat(cfg.Prod.AWS.SQSMessageFormat)
+		if err != nil {
+			log.Fatalf("invalid prod.aws.sqs_message_format: %v", err)
+		}
+		awsCfg, err := awsConfig.LoadDefaultConfig(ctx, awsConfig.WithRegion(cfg.Prod.AWS.Region))
+		if err != nil {
+			log.Fatalf("unable to load AWS config, %v", err)
+		}
+		// The redrive policy of the queue is set once with "ordersctl dlq attach".
+		orderMessageConsumer = messaging.NewOrderMessageQueueSQS(sqs.NewFromConfig(awsCfg), cfg.Prod.AWS.SQSQueueURL, messageFormat)
+	case messaging.DriverKafka:
+		kafkaCfg := cfg.Messaging.Kafka
+		if kafkaCfg.ConsumerGroup == "" {
+			log.Fatalf("messaging.kafka.consumer_group is required")
+		}
+		messageFormat, err := messaging.ParseMessageFormat(kafkaCfg.MessageFormat)
+		if err != nil {
+			log.Fatalf("invalid messaging.kafka.message_format: %v", err)
+		}
+		kafkaQueue, err := messaging.NewKafkaConsumer(kafkaCfg.Brokers, kafkaCfg.Topic, kafkaCfg.ConsumerGroup, kafkaCfg.Acks, kafkaCfg.Idempotent, messageFormat)
+		if err != nil {
+			log.Fatalf("could not create Kafka client: %v", err)
+		}
+		defer kafkaQueue.Client.Close()
+		if cfg.Worker.MaxReceives > 0 {
+			kafkaQueue.MaxReceives = cfg.Worker.MaxReceives
+		}
+		kafkaQueue.DeadLetterTopic = kafkaCfg.DeadLetterTopic
+		orderMessageConsumer = kafkaQueue
+	case messaging.DriverAMQP:
+		amqpCfg := cfg.Messaging.AMQP
+		if amqpCfg.Queue == "" {
+			log.Fatalf("messaging.amqp.queue is required")
+		}
+		if cfg.Worker.MaxReceives > 0 && amqpCfg.DeadLetterQueue == "" {
+			log.Fatalf("worker.max_receives needs messaging.amqp.dead_letter_queue")
+		}
+		messageFormat, err := messaging.ParseMessageFormat(amqpCfg.MessageFormat)
+		if err != nil {
+			log.Fatalf("invalid messaging.amqp.message_format: %v", err)
+		}
+		amqpQueue := messaging.NewOrderMessageQueueAMQP(amqpCfg.URL, amqpCfg.Exchange, amqpCfg.Queue, amqpCfg.BindingKeys, amqpCfg.Prefetch, messageFormat)
+		defer amqpQueue.Close()
+		amqpQueue.DeadLetterQueue = amqpCfg.DeadLetterQueue
+		amqpQueue.MaxReceives = cfg.Worker.MaxReceives
+		orderMessageConsumer = amqpQueue
+	case messaging.DriverNATS:
+		natsCfg := cfg.Messaging.NATS
+		if natsCfg.Consumer == "" {
+			log.Fatalf("messaging.nats.consumer is required")
+		}
+		messageFormat, err := messaging.ParseMessageFormat(natsCfg.MessageFormat)
+		if err != nil {
+			log.Fatalf("invalid messaging.nats.message_format: %v", err)
+		}
+		natsConn, js, err := messaging.ConnectNATS(ctx, natsCfg.URL, natsCfg.Stream, natsCfg.Subject, natsCfg.DuplicateWindow)
+		if err != nil {
+			log.Fatalf("could not connect to NATS: %v", err)
+		}
+		defer natsConn.Close()
+		natsQueue := messaging.NewOrderMessageQueueNATS(js, natsCfg.Stream, natsCfg.Subject, natsCfg.Consumer, messageFormat)
+		natsQueue.MaxDeliver = cfg.Worker.MaxReceives
+		orderMessageConsumer = natsQueue
+	default:
+		log.Fatalf("unsupported messaging driver %q", cfg.Messaging.Driver)
+	}
+
+	// Database
+	db, err := database.Open(cfg.Prod.DB.Driver, cfg.Prod.DB.DSN)
+	if err != nil {
+		log.Fatalf("could not connect to database: %v", err)
+	}
+	defer db.Close()
+	orderRepo := database.NewOrderRepositorySQL(db, cfg.Prod.DB.Driver)
+
+	// Use Cases
+	consumeOrdersUseCase := usecase.NewConsumeOrdersUseCase(orderMessageConsumer, orderRepo, cfg.Worker.RetryBaseDelay, cfg.Worker.RetryMaxDelay)
+
+	worker.NewOrderWorker(consumeOrdersUseCase, cfg.Worker.PollInterval).Run(ctx)
+}
@@ -2,25 +2,119 @@ package configs
 
 import (
 	"os"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config holds the application configuration.
 type Config struct {
-	Env    string `yaml:"env"`
-	Server ServerConfig `yaml:"server"`
-	Dev    DevConfig    `yaml:"dev"`
-	Prod   ProdConfig   `yaml:"prod"`
+	Env       string          `yaml:"env"`
+	Server    ServerConfig    `yaml:"server"`
+	Worker    WorkerConfig    `yaml:"worker"`
+	Outbox    OutboxConfig    `yaml:"outbox"`
+	Payments  PaymentsConfig  `yaml:"payments"`
+	Messaging MessagingConfig `yaml:"messaging"`
+	Dev       DevConfig       `yaml:"dev"`
+	Prod      ProdConfig      `yaml:"prod"`
 }
 
 // ServerConfig holds the server configuration.
 type ServerConfig struct {
-	Port string `yaml:"port"`
+	Port           string        `yaml:"port"`
+	IdempotencyTTL time.Duration `yaml:"idempotency_ttl"`
+}
+
+// WorkerConfig holds the order worker configuration. A message that fails is
+// delivered again after RetryBaseDelay, doubling with every delivery up to
+// RetryMaxDelay, until it has been delivered MaxReceives times and is moved to
+// the dead-letter queue.
+type WorkerConfig struct {
+	PollInterval   time.Duration `yaml:"poll_interval"`
+	MaxReceives    int           `yaml:"max_receives"`
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay"`
+	RetryMaxDelay  time.Duration `yaml:"retry_max_delay"`
+}
+
+// OutboxConfig holds the outbox relay configuration.
+type OutboxConfig struct {
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	MaxAttempts  int           `yaml:"max_attempts"`
+}
+
+// PaymentsConfig holds the payment provider configuration. The only provider
+// is "fake", an in-process gateway that approves every payment except those
+// whose payment method is a key of Declines, which it declines with the mapped reason.
+type PaymentsConfig struct {
+	Provider string            `yaml:"provider"`
+	Declines map[string]string `yaml:"declines"`
+}
+
+// MessagingConfig holds the production message broker configuration. Driver
+// is "sqs" (the default), which uses the queue in prod.aws, "kafka", "amqp" or
+// "nats". Development mode always uses the in-memory queue.
+type MessagingConfig struct {
+	Driver string      `yaml:"driver"`
+	Kafka  KafkaConfig `yaml:"kafka"`
+	AMQP   AMQPConfig  `yaml:"amqp"`
+	NATS   NATSConfig  `yaml:"nats"`
+}
+
+// KafkaConfig holds the Kafka configuration. Acks is how many replicas must
+// acknowledge a produced message: "all" (the default), "leader" or "none".
+// Idempotent enables the idempotent producer, which needs acks "all".
+// ConsumerGroup is the group the worker consumes Topic in. Records delivered
+// WorkerConfig.MaxReceives times are produced to DeadLetterTopic, or dropped
+// when it is empty. MessageFormat takes the same values as
+// AWSConfig.SQSMessageFormat; attributes are sent as headers.
+type KafkaConfig struct {
+	Brokers         []string `yaml:"brokers"`
+	Topic           string   `yaml:"topic"`
+	ConsumerGroup   string   `yaml:"consumer_group"`
+	DeadLetterTopic string   `yaml:"dead_letter_topic"`
+	Acks            string   `yaml:"acks"`
+	Idempotent      bool     `yaml:"idempotent"`
+	MessageFormat   string   `yaml:"message_format"`
+}
+
+// AMQPConfig holds the RabbitMQ configuration. Events are published to the
+// durable topic exchange Exchange with routing keys such as "order.created".
+// The worker consumes Queue, which is bound to the exchange with BindingKeys
+// ("order.#" when empty), and is sent at most Prefetch unacknowledged messages
+// at a time. Messages received WorkerConfig.MaxReceives times are moved to
+// DeadLetterQueue, which makes Queue a quorum queue. MessageFormat takes the
+// same values as AWSConfig.SQSMessageFormat; attributes are sent as message headers.
+type AMQPConfig struct {
+	URL             string   `yaml:"url"`
+	Exchange        string   `yaml:"exchange"`
+	Queue           string   `yaml:"queue"`
+	DeadLetterQueue string   `yaml:"dead_letter_queue"`
+	BindingKeys     []string `yaml:"binding_keys"`
+	Prefetch        int      `yaml:"prefetch"`
+	MessageFormat   string   `yaml:"message_format"`
+}
+
+// NATSConfig holds the NATS JetStream configuration. Events are published to
+// Subject followed by their type, such as "orders.OrderCreated", and stored in
+// Stream, which drops an event published twice within DuplicateWindow. The
+// worker pulls from the durable consumer Consumer, which delivers a message at
+// most WorkerConfig.MaxReceives times. MessageFormat takes the same
+// values as AWSConfig.SQSMessageFormat; attributes are sent as message headers.
+type NATSConfig struct {
+	URL             string        `yaml:"url"`
+	Stream          string        `yaml:"stream"`
+	Subject         string        `yaml:"subject"`
+	Consumer        string        `yaml:"consumer"`
+	DuplicateWindow time.Duration `yaml:"duplicate_window"`
+	MessageFormat   string        `yaml:"message_format"`
 }
 
 // DevConfig holds the development environment configuration.
-type DevConfig struct{}
+// Without a DB driver, development mode keeps orders in memory.
+type DevConfig struct {
+	DB DBConfig `yaml:"db"`
+}
 
 // ProdConfig holds the production environment configuration.
 type ProdConfig struct {
@@ -28,16 +122,31 @@ type ProdConfig struct {
 	DB  DBConfig  `yaml:"db"`
 }
 
-// AWSConfig holds the AWS configuration.
+// AWSConfig holds the AWS configuration. SQSMessageFormat is the format events
+// are published in on the SQS queue: "envelope" (the default),
+// "cloudevents-structured" or "cloudevents-binary". SQSDeadLetterQueueURL is
+// the queue that messages delivered WorkerConfig.MaxReceives times are moved to.
 type AWSConfig struct {
-	Region      string `yaml:"region"`
-	SQSQueueURL string `yaml:"sqs_queue_url"`
+	Region                string `yaml:"region"`
+	SQSQueueURL           string `yaml:"sqs_queue_url"`
+	SQSMessageFormat      string `yaml:"sqs_message_format"`
+	SQSDeadLetterQueueURL string `yaml:"sqs_dlq_url"`
 }
 
 // DBConfig holds the database configuration.
 type DBConfig struct {
 	Driver string `yaml:"driver"`
 	DSN    string `yaml:"dsn"`
+	// RequireCurrentSchema makes the server refuse to start while migrations are pending.
+	RequireCurrentSchema bool `yaml:"require_current_schema"`
+}
+
+// Database returns the database configuration of the current environment.
+func (c *Config) Database() DBConfig {
+	if c.Env == "dev" {
+		return c.Dev.DB
+	}
+	return c.Prod.DB
 }
 
 var cfg *Config
@@ -0,0 +1,19 @@
+// Package correlation carries the correlation ID of a request through a
+// context, so that the events a request causes can be traced back to it.
+package correlation
+
+import "context"
+
+// contextKey is the type of the context key for the correlation ID.
+type contextKey struct{}
+
+// WithID returns a copy of ctx that carries the correlation ID id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// ID returns the correlation ID carried by ctx, or "" if there is none.
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
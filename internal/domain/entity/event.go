@@ -0,0 +1,108 @@
+package entity
+
+import "time"
+
+// Types of the events raised by orders.
+const (
+	EventTypeOrderCreated       = "OrderCreated"
+	EventTypeOrderStatusChanged = "OrderStatusChanged"
+	EventTypeOrderPaid          = "OrderPaid"
+	EventTypeOrderCancelled     = "OrderCancelled"
+	EventTypeOrderRefunded      = "OrderRefunded"
+)
+
+// Event is a domain event: something that happened to an order. Events are
+// raised by the methods of Order and collected with Order.PullEvents.
+type Event interface {
+	// EventType names the event, such as "OrderCreated".
+	EventType() string
+	// EventVersion is the version of the event's schema. It goes up whenever
+	// a field is removed or changes meaning; adding a field keeps the version.
+	EventVersion() int
+	// EventOrderID is the OrderID of the order the event happened to.
+	EventOrderID() int
+	// EventTime is when the event happened.
+	EventTime() time.Time
+}
+
+// OrderEvent holds the fields every order event has. OccurredAt travels in the
+// event envelope rather than in the event's own JSON.
+type OrderEvent struct {
+	OrderID    int       `json:"OrderId"`
+	OccurredAt time.Time `json:"-"`
+}
+
+// EventOrderID returns the OrderID of the order the event happened to.
+func (e OrderEvent) EventOrderID() int { return e.OrderID }
+
+// EventTime returns when the event happened.
+func (e OrderEvent) EventTime() time.Time { return e.OccurredAt }
+
+// OrderCreated is raised when an order is created. Order is the order as it
+// was created.
+type OrderCreated struct {
+	OrderEvent
+	Order Order `json:"order"`
+}
+
+func (OrderCreated) EventType() string { return EventTypeOrderCreated }
+func (OrderCreated) EventVersion() int { return 1 }
+
+// OrderStatusChanged is raised whenever an order moves to a new status.
+type OrderStatusChanged struct {
+	OrderEvent
+	From OrderStatus `json:"from"`
+	To   OrderStatus `json:"to"`
+}
+
+func (OrderStatusChanged) EventType() string { return EventTypeOrderStatusChanged }
+func (OrderStatusChanged) EventVersion() int { return 1 }
+
+// OrderPaid is raised, after OrderStatusChanged, when an order moves to Paid.
+// PaymentID is empty when the status was set by hand rather than by a payment.
+type OrderPaid struct {
+	OrderEvent
+	PaymentID string `json:"payment_id,omitempty"`
+	Amount    Money  `json:"amount,omitzero"`
+}
+
+func (OrderPaid) EventType() string { return EventTypeOrderPaid }
+func (OrderPaid) EventVersion() int { return 1 }
+
+// OrderCancelled is raised, after OrderStatusChanged, when an order is cancelled.
+type OrderCancelled struct {
+	OrderEvent
+	From OrderStatus `json:"from"`
+}
+
+func (OrderCancelled) EventType() string { return EventTypeOrderCancelled }
+func (OrderCancelled) EventVersion() int { return 1 }
+
+// OrderRefunded is raised when money is returned from an order's payment.
+// RefundedTotal is the sum of every refund of the order so far, and Status is
+// the order's status after the refund.
+type OrderRefunded struct {
+	OrderEvent
+	RefundID      string      `json:"refund_id"`
+	PaymentID     string      `json:"payment_id"`
+	Amount        Money       `json:"amount"`
+	RefundedTotal Money       `json:"refunded_total"`
+	Status        OrderStatus `json:"status"`
+	Reason        string      `json:"reason,omitempty"`
+}
+
+func (OrderRefunded) EventType() string { return EventTypeOrderRefunded }
+func (OrderRefunded) EventVersion() int { return 1 }
+
+// PullEvents returns the events raised since the last call and forgets them,
+// so that each event is collected once.
+func (o *Order) PullEvents() []Event {
+	events := o.events
+	o.events = nil
+	return events
+}
+
+// raise records an event that happened to the order.
+func (o *Order) raise(event Event) {
+	o.events = append(o.events, event)
+}
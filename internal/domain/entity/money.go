@@ -0,0 +1,76 @@
+package entity
+
+import (
+	"GoCleanArch/internal/domain"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/text/currency"
+)
+
+var (
+	// ErrUnknownCurrency is returned when a value is not an upper-case ISO 4217 currency code.
+	// Errors carrying it also match domain.ErrValidation.
+	ErrUnknownCurrency = errors.New("unknown currency")
+	// ErrCurrencyMismatch is returned when amounts in different currencies are combined.
+	// Errors carrying it also match domain.ErrValidation.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	// ErrAmountOverflow is returned when a calculation does not fit in an amount.
+	// Errors carrying it also match domain.ErrValidation.
+	ErrAmountOverflow = errors.New("amount overflow")
+)
+
+// Money is an amount in the minor unit of its currency, such as cents for USD
+// or yen for JPY. Amounts are integers so that sums are exact.
+type Money struct {
+	Amount   int64  `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// NewMoney creates Money after checking that currency is an ISO 4217 code.
+func NewMoney(amount int64, currency string) (Money, error) {
+	if err := ValidateCurrency(currency); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ValidateCurrency checks that code is an upper-case ISO 4217 currency code such as "USD".
+func ValidateCurrency(code string) error {
+	unit, err := currency.ParseISO(code)
+	if err != nil || unit == (currency.Unit{}) || code != strings.ToUpper(code) {
+		return fmt.Errorf("%w: %w: %q", domain.ErrValidation, ErrUnknownCurrency, code)
+	}
+	return nil
+}
+
+// Add returns m + other. Both must be in the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: %w: %s and %s", domain.ErrValidation, ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, fmt.Errorf("%w: %w", domain.ErrValidation, ErrAmountOverflow)
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Multiply returns m times n.
+func (m Money) Multiply(n int64) (Money, error) {
+	if m.Amount != 0 && n != 0 {
+		product := m.Amount * n
+		if product/n != m.Amount || (m.Amount == -1 && n == math.MinInt64) || (n == -1 && m.Amount == math.MinInt64) {
+			return Money{}, fmt.Errorf("%w: %w", domain.ErrValidation, ErrAmountOverflow)
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+	return Money{Amount: 0, Currency: m.Currency}, nil
+}
+
+// IsZero reports whether m is the zero value, with no amount and no currency.
+func (m Money) IsZero() bool {
+	return m == Money{}
+}
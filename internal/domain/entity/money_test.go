@@ -0,0 +1,71 @@
+package entity_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"math"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Money", func() {
+	Describe("NewMoney", func() {
+		It("should accept ISO 4217 currency codes", func() {
+			money, err := entity.NewMoney(1999, "EUR")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(money).To(Equal(entity.Money{Amount: 1999, Currency: "EUR"}))
+		})
+
+		DescribeTable("should reject anything else",
+			func(currency string) {
+				_, err := entity.NewMoney(1999, currency)
+
+				Expect(err).To(MatchError(entity.ErrUnknownCurrency))
+				Expect(err).To(MatchError(domain.ErrValidation))
+			},
+			Entry("empty", ""),
+			Entry("lower case", "eur"),
+			Entry("unknown code", "ABC"),
+			Entry("no currency", "XXX"),
+			Entry("too long", "EURO"),
+		)
+	})
+
+	Describe("Add", func() {
+		It("should add amounts in the same currency", func() {
+			sum, err := entity.Money{Amount: 150, Currency: "USD"}.Add(entity.Money{Amount: 275, Currency: "USD"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sum).To(Equal(entity.Money{Amount: 425, Currency: "USD"}))
+		})
+
+		It("should reject different currencies", func() {
+			_, err := entity.Money{Amount: 150, Currency: "USD"}.Add(entity.Money{Amount: 275, Currency: "EUR"})
+
+			Expect(err).To(MatchError(entity.ErrCurrencyMismatch))
+		})
+
+		It("should reject an overflow", func() {
+			_, err := entity.Money{Amount: math.MaxInt64, Currency: "USD"}.Add(entity.Money{Amount: 1, Currency: "USD"})
+
+			Expect(err).To(MatchError(entity.ErrAmountOverflow))
+		})
+	})
+
+	Describe("Multiply", func() {
+		It("should multiply the amount", func() {
+			product, err := entity.Money{Amount: 1250, Currency: "USD"}.Multiply(3)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(product).To(Equal(entity.Money{Amount: 3750, Currency: "USD"}))
+		})
+
+		It("should reject an overflow", func() {
+			_, err := entity.Money{Amount: math.MaxInt64 / 2, Currency: "USD"}.Multiply(3)
+
+			Expect(err).To(MatchError(entity.ErrAmountOverflow))
+		})
+	})
+})
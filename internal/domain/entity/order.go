@@ -1,14 +1,209 @@
 package entity
 
-import "time"
+import (
+	"GoCleanArch/internal/domain"
+	"fmt"
+	"time"
 
-// Order represents a customer order.
+	"github.com/google/uuid"
+)
+
+// Order represents a customer order. ID is generated by the server and
+// identifies the order internally; OrderID is the business identifier chosen
+// by the client. Both are unique.
+//
+// Items are priced in a single currency. TaxRate is in basis points, so 825
+// is 8.25%; Subtotal, Tax and Total are set from the items by CalculateTotals
+// and are zero for an order without items.
+//
+// The methods that change an order raise domain events, which use cases
+// collect with PullEvents and publish once the change is stored.
+//
+// Version counts the updates the order has been through. Repositories update
+// an order only while its stored version is still the one it was read with,
+// so that of two concurrent updates, the second fails instead of overwriting
+// the first.
 type Order struct {
-	ID        string    `json:"id"`
-	Data      string    `json:"Data"`
-	OrderID   int       `json:"OrderId"`
-	Status    string    `json:"Status"`
-	Paid      bool      `json:"Paid"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        string      `json:"id"`
+	Data      string      `json:"Data"`
+	OrderID   int         `json:"OrderId"`
+	Status    OrderStatus `json:"Status"`
+	Paid      bool        `json:"Paid"`
+	Items     []OrderItem `json:"items,omitempty"`
+	TaxRate   int         `json:"tax_rate_bps,omitempty"`
+	Subtotal  Money       `json:"subtotal,omitzero"`
+	Tax       Money       `json:"tax,omitzero"`
+	Total     Money       `json:"total,omitzero"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Version   int         `json:"-"`
+
+	events []Event
+}
+
+// MaxTaxRate is the highest tax rate, in basis points, that an order may have.
+const MaxTaxRate = 10000
+
+// NewOrderID returns a new internal order ID. IDs are UUIDv7, so they sort in
+// the order they were generated.
+func NewOrderID() (string, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return "", err
+	}
+	return id.String(), nil
+}
+
+// NewOrder creates an order with a new internal ID and totals calculated from
+// its items, and raises OrderCreated. It returns an error matching
+// domain.ErrValidation when status is not one an order may start in.
+func NewOrder(orderID int, data string, status OrderStatus, items []OrderItem, taxRate int) (*Order, error) {
+	if !status.IsInitial() {
+		return nil, fmt.Errorf("%w: an order cannot be created %s", domain.ErrValidation, status)
+	}
+	id, err := NewOrderID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	order := &Order{
+		ID:        id,
+		Data:      data,
+		OrderID:   orderID,
+		Status:    status,
+		Items:     items,
+		TaxRate:   taxRate,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := order.CalculateTotals(); err != nil {
+		return nil, err
+	}
+
+	snapshot := *order
+	order.raise(OrderCreated{OrderEvent: OrderEvent{OrderID: orderID, OccurredAt: now}, Order: snapshot})
+	return order, nil
+}
+
+// CalculateTotals sets Subtotal to the sum of the item totals, Tax to TaxRate
+// of the subtotal rounded half up to the minor unit, and Total to their sum.
+// It returns an error matching domain.ErrValidation when an item has no
+// positive quantity or a negative price, the items mix currencies or a sum
+// overflows.
+func (o *Order) CalculateTotals() error {
+	if len(o.Items) == 0 {
+		o.Subtotal, o.Tax, o.Total = Money{}, Money{}, Money{}
+		return nil
+	}
+	if o.TaxRate < 0 || o.TaxRate > MaxTaxRate {
+		return fmt.Errorf("%w: tax rate must be between 0 and %d basis points", domain.ErrValidation, MaxTaxRate)
+	}
+
+	subtotal := Money{Currency: o.Items[0].UnitPrice.Currency}
+	for _, item := range o.Items {
+		if item.Quantity <= 0 {
+			return fmt.Errorf("%w: item %s has a quantity of %d", domain.ErrValidation, item.SKU, item.Quantity)
+		}
+		if item.UnitPrice.Amount < 0 {
+			return fmt.Errorf("%w: item %s has a negative unit price", domain.ErrValidation, item.SKU)
+		}
+		if err := ValidateCurrency(item.UnitPrice.Currency); err != nil {
+			return err
+		}
+		lineTotal, err := item.Total()
+		if err != nil {
+			return err
+		}
+		if subtotal, err = subtotal.Add(lineTotal); err != nil {
+			return err
+		}
+	}
+
+	// Split the amount so that multiplying by the rate cannot overflow.
+	whole, rest := subtotal.Amount/MaxTaxRate, subtotal.Amount%MaxTaxRate
+	tax := Money{Amount: whole*int64(o.TaxRate) + (rest*int64(o.TaxRate)+MaxTaxRate/2)/MaxTaxRate, Currency: subtotal.Currency}
+	total, err := subtotal.Add(tax)
+	if err != nil {
+		return err
+	}
+
+	o.Subtotal, o.Tax, o.Total = subtotal, tax, total
+	return nil
+}
+
+// Transition moves the order to a new status, returning an *InvalidTransitionError
+// if the status table does not allow it. It raises OrderStatusChanged, followed
+// by OrderCancelled when the order is cancelled. Settlement statuses are
+// reached only through Pay and Refund, so moving to one returns an error
+// matching domain.ErrValidation.
+func (o *Order) Transition(to OrderStatus) error {
+	if to.IsSettlement() {
+		return fmt.Errorf("%w: an order becomes %s only through a payment or a refund", domain.ErrValidation, to)
+	}
+	from := o.Status
+	if err := o.transition(to); err != nil {
+		return err
+	}
+	if to == OrderStatusCancelled {
+		o.raise(OrderCancelled{OrderEvent: o.event(), From: from})
+	}
+	return nil
+}
+
+// Pay moves the order to Paid with a captured payment and raises
+// OrderStatusChanged and OrderPaid.
+func (o *Order) Pay(payment *Payment) error {
+	if err := o.transition(OrderStatusPaid); err != nil {
+		return err
+	}
+	o.raise(OrderPaid{OrderEvent: o.event(), PaymentID: payment.ID, Amount: payment.Amount})
+	return nil
+}
+
+// Refund records a refund from the order's payment. refundedTotal is the sum of
+// every refund so far, including this one, and captured is the amount the
+// payment collected. The order moves to Refunded once they are equal and to
+// PartiallyRefunded before that, raising OrderStatusChanged when the status
+// changes, and then raises OrderRefunded.
+func (o *Order) Refund(refund *Refund, refundedTotal, captured Money) error {
+	next := OrderStatusPartiallyRefunded
+	if refundedTotal == captured {
+		next = OrderStatusRefunded
+	}
+	if o.Status != next {
+		if err := o.transition(next); err != nil {
+			return err
+		}
+	}
+	o.raise(OrderRefunded{
+		OrderEvent:    OrderEvent{OrderID: o.OrderID, OccurredAt: refund.CreatedAt},
+		RefundID:      refund.ID,
+		PaymentID:     refund.PaymentID,
+		Amount:        refund.Amount,
+		RefundedTotal: refundedTotal,
+		Status:        o.Status,
+		Reason:        refund.Reason,
+	})
+	return nil
+}
+
+// transition moves the order to a new status and raises OrderStatusChanged.
+func (o *Order) transition(to OrderStatus) error {
+	if !o.Status.CanTransitionTo(to) {
+		return &InvalidTransitionError{From: o.Status, To: to}
+	}
+
+	from := o.Status
+	o.Status = to
+	if to == OrderStatusPaid {
+		o.Paid = true
+	}
+	o.UpdatedAt = time.Now()
+	o.raise(OrderStatusChanged{OrderEvent: o.event(), From: from, To: to})
+	return nil
+}
+
+// event returns the common fields of an event happening to the order now.
+func (o *Order) event() OrderEvent {
+	return OrderEvent{OrderID: o.OrderID, OccurredAt: o.UpdatedAt}
 }
@@ -0,0 +1,14 @@
+package entity
+
+// OrderItem is one line of an order: a quantity of a product at a unit price.
+type OrderItem struct {
+	SKU         string `json:"sku"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	UnitPrice   Money  `json:"unit_price"`
+}
+
+// Total returns the unit price times the quantity.
+func (i OrderItem) Total() (Money, error) {
+	return i.UnitPrice.Multiply(int64(i.Quantity))
+}
@@ -0,0 +1,106 @@
+package entity
+
+import (
+	"GoCleanArch/internal/domain"
+	"errors"
+	"fmt"
+)
+
+// OrderStatus is the lifecycle state of an order.
+type OrderStatus string
+
+const (
+	OrderStatusPending   OrderStatus = "Pending"
+	OrderStatusPaid      OrderStatus = "Paid"
+	OrderStatusShipped   OrderStatus = "Shipped"
+	OrderStatusDelivered OrderStatus = "Delivered"
+	OrderStatusCancelled OrderStatus = "Cancelled"
+	OrderStatusRefunded  OrderStatus = "Refunded"
+	// OrderStatusPartiallyRefunded means part of the captured amount has been
+	// refunded. An order stays PartiallyRefunded through further partial refunds.
+	OrderStatusPartiallyRefunded OrderStatus = "PartiallyRefunded"
+)
+
+// orderStatusTransitions lists, for every status, the statuses an order may move to next.
+// Cancelled and Refunded are terminal. A PartiallyRefunded order is not
+// shipped, as a Shipped order could no longer be refunded.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusPending:           {OrderStatusPaid, OrderStatusCancelled},
+	OrderStatusPaid:              {OrderStatusShipped, OrderStatusCancelled, OrderStatusRefunded, OrderStatusPartiallyRefunded},
+	OrderStatusShipped:           {OrderStatusDelivered},
+	OrderStatusDelivered:         {OrderStatusRefunded, OrderStatusPartiallyRefunded},
+	OrderStatusCancelled:         {},
+	OrderStatusRefunded:          {},
+	OrderStatusPartiallyRefunded: {OrderStatusRefunded},
+}
+
+// ErrUnknownOrderStatus is returned when a value does not name an order status.
+// Errors carrying it also match domain.ErrValidation.
+var ErrUnknownOrderStatus = errors.New("unknown order status")
+
+// InvalidTransitionError is returned when an order cannot move from one status to another.
+// It matches domain.ErrConflict.
+type InvalidTransitionError struct {
+	From OrderStatus
+	To   OrderStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("invalid order status transition from %s to %s", e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Unwrap() error {
+	return domain.ErrConflict
+}
+
+// OrderStatuses returns every order status, in lifecycle order.
+func OrderStatuses() []OrderStatus {
+	return []OrderStatus{
+		OrderStatusPending,
+		OrderStatusPaid,
+		OrderStatusShipped,
+		OrderStatusDelivered,
+		OrderStatusCancelled,
+		OrderStatusRefunded,
+		OrderStatusPartiallyRefunded,
+	}
+}
+
+// ParseOrderStatus converts a string into an OrderStatus.
+func ParseOrderStatus(s string) (OrderStatus, error) {
+	status := OrderStatus(s)
+	if !status.IsValid() {
+		return "", fmt.Errorf("%w: %w: %q", domain.ErrValidation, ErrUnknownOrderStatus, s)
+	}
+	return status, nil
+}
+
+// IsValid reports whether s is a known order status.
+func (s OrderStatus) IsValid() bool {
+	_, ok := orderStatusTransitions[s]
+	return ok
+}
+
+// IsInitial reports whether an order may be created in status s. Every order
+// starts out Pending and only becomes Paid or Refunded through a payment or a
+// refund.
+func (s OrderStatus) IsInitial() bool {
+	return s == OrderStatusPending
+}
+
+// IsSettlement reports whether s records money changing hands: Paid,
+// PartiallyRefunded and Refunded. An order reaches them only through
+// Order.Pay and Order.Refund, never through Order.Transition.
+func (s OrderStatus) IsSettlement() bool {
+	return s == OrderStatusPaid || s == OrderStatusPartiallyRefunded || s == OrderStatusRefunded
+}
+
+// CanTransitionTo reports whether an order in status s may move to status to.
+func (s OrderStatus) CanTransitionTo(to OrderStatus) bool {
+	for _, next := range orderStatusTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
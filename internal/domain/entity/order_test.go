@@ -0,0 +1,219 @@
+package entity_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Order", func() {
+	Describe("ParseOrderStatus", func() {
+		It("should accept known statuses", func() {
+			status, err := entity.ParseOrderStatus("Shipped")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status).To(Equal(entity.OrderStatusShipped))
+		})
+
+		It("should reject unknown statuses", func() {
+			_, err := entity.ParseOrderStatus("Completed")
+
+			Expect(err).To(MatchError(entity.ErrUnknownOrderStatus))
+		})
+	})
+
+	Describe("Transition", func() {
+		var order *entity.Order
+
+		BeforeEach(func() {
+			order = &entity.Order{OrderID: 1, Status: entity.OrderStatusPending}
+		})
+
+		It("should follow the happy path to Delivered", func() {
+			Expect(order.Pay(&entity.Payment{ID: "payment-1"})).To(Succeed())
+			Expect(order.Paid).To(BeTrue())
+			Expect(order.Transition(entity.OrderStatusShipped)).To(Succeed())
+			Expect(order.Transition(entity.OrderStatusDelivered)).To(Succeed())
+			Expect(order.Status).To(Equal(entity.OrderStatusDelivered))
+			Expect(order.UpdatedAt).NotTo(BeZero())
+		})
+
+		It("should allow refunding a delivered order", func() {
+			Expect(entity.OrderStatusDelivered.CanTransitionTo(entity.OrderStatusRefunded)).To(BeTrue())
+		})
+
+		It("should allow fully refunding a partially refunded order", func() {
+			Expect(entity.OrderStatusPartiallyRefunded.CanTransitionTo(entity.OrderStatusRefunded)).To(BeTrue())
+		})
+
+		It("should not ship a partially refunded order, whose balance could then no longer be refunded", func() {
+			order.Status = entity.OrderStatusPartiallyRefunded
+
+			Expect(order.Transition(entity.OrderStatusShipped)).To(MatchError(domain.ErrConflict))
+			Expect(order.Status).To(Equal(entity.OrderStatusPartiallyRefunded))
+		})
+
+		It("should reject settlement statuses, which only payments and refunds reach", func() {
+			for _, status := range []entity.OrderStatus{entity.OrderStatusPaid, entity.OrderStatusPartiallyRefunded, entity.OrderStatusRefunded} {
+				order.Status = entity.OrderStatusPending
+				if status != entity.OrderStatusPaid {
+					order.Status = entity.OrderStatusDelivered
+				}
+
+				Expect(order.Transition(status)).To(MatchError(domain.ErrValidation))
+				Expect(order.Paid).To(BeFalse())
+				Expect(order.PullEvents()).To(BeEmpty())
+			}
+		})
+
+		It("should reject skipping a step", func() {
+			err := order.Transition(entity.OrderStatusShipped)
+
+			var transitionErr *entity.InvalidTransitionError
+			Expect(errors.As(err, &transitionErr)).To(BeTrue())
+			Expect(err).To(MatchError(domain.ErrConflict))
+			Expect(transitionErr.From).To(Equal(entity.OrderStatusPending))
+			Expect(transitionErr.To).To(Equal(entity.OrderStatusShipped))
+			Expect(order.Status).To(Equal(entity.OrderStatusPending))
+		})
+
+		It("should not leave a terminal status", func() {
+			order.Status = entity.OrderStatusCancelled
+
+			Expect(order.Transition(entity.OrderStatusShipped)).NotTo(Succeed())
+		})
+
+		It("should reject unknown statuses", func() {
+			Expect(order.Transition(entity.OrderStatus("Lost"))).NotTo(Succeed())
+		})
+	})
+
+	Describe("CalculateTotals", func() {
+		var order *entity.Order
+
+		BeforeEach(func() {
+			order = &entity.Order{
+				OrderID: 1,
+				Items: []entity.OrderItem{
+					{SKU: "BOOK", Quantity: 2, UnitPrice: entity.Money{Amount: 1250, Currency: "USD"}},
+					{SKU: "PEN", Quantity: 3, UnitPrice: entity.Money{Amount: 199, Currency: "USD"}},
+				},
+				TaxRate: 825,
+			}
+		})
+
+		It("should sum the items and round the tax half up", func() {
+			Expect(order.CalculateTotals()).To(Succeed())
+
+			// 8.25% of 30.97 is 2.555025, which rounds to 2.56.
+			Expect(order.Subtotal).To(Equal(entity.Money{Amount: 3097, Currency: "USD"}))
+			Expect(order.Tax).To(Equal(entity.Money{Amount: 256, Currency: "USD"}))
+			Expect(order.Total).To(Equal(entity.Money{Amount: 3353, Currency: "USD"}))
+		})
+
+		It("should leave an order without items with zero totals", func() {
+			order.Items = nil
+
+			Expect(order.CalculateTotals()).To(Succeed())
+			Expect(order.Subtotal.IsZero()).To(BeTrue())
+			Expect(order.Total.IsZero()).To(BeTrue())
+		})
+
+		It("should reject items in different currencies", func() {
+			order.Items[1].UnitPrice.Currency = "EUR"
+
+			Expect(order.CalculateTotals()).To(MatchError(entity.ErrCurrencyMismatch))
+		})
+
+		It("should reject items without a positive quantity", func() {
+			order.Items[0].Quantity = 0
+
+			Expect(order.CalculateTotals()).To(MatchError(domain.ErrValidation))
+		})
+
+		It("should reject a tax rate over 100%", func() {
+			order.TaxRate = entity.MaxTaxRate + 1
+
+			Expect(order.CalculateTotals()).To(MatchError(domain.ErrValidation))
+		})
+	})
+
+	Describe("events", func() {
+		It("should raise OrderCreated when an order is created", func() {
+			order, err := entity.NewOrder(7, "new", entity.OrderStatusPending, nil, 0)
+			Expect(err).NotTo(HaveOccurred())
+
+			events := order.PullEvents()
+
+			Expect(events).To(HaveLen(1))
+			created, ok := events[0].(entity.OrderCreated)
+			Expect(ok).To(BeTrue())
+			Expect(created.EventOrderID()).To(Equal(7))
+			Expect(created.EventTime()).To(Equal(order.CreatedAt))
+			Expect(created.Order.ID).To(Equal(order.ID))
+			Expect(order.PullEvents()).To(BeEmpty())
+		})
+
+		It("should not create an order in a status it cannot start in", func() {
+			order, err := entity.NewOrder(7, "new", entity.OrderStatusPaid, nil, 0)
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+			Expect(order).To(BeNil())
+		})
+
+		It("should raise OrderStatusChanged and then OrderPaid or OrderCancelled", func() {
+			order := &entity.Order{OrderID: 1, Status: entity.OrderStatusPending}
+			Expect(order.Pay(&entity.Payment{ID: "payment-1"})).To(Succeed())
+			Expect(order.Transition(entity.OrderStatusCancelled)).To(Succeed())
+
+			events := order.PullEvents()
+
+			Expect(events).To(HaveLen(4))
+			Expect(events[0]).To(Equal(entity.OrderStatusChanged{OrderEvent: entity.OrderEvent{OrderID: 1, OccurredAt: events[0].EventTime()}, From: entity.OrderStatusPending, To: entity.OrderStatusPaid}))
+			Expect(events[1].EventType()).To(Equal(entity.EventTypeOrderPaid))
+			Expect(events[2].EventType()).To(Equal(entity.EventTypeOrderStatusChanged))
+			Expect(events[3]).To(Equal(entity.OrderCancelled{OrderEvent: entity.OrderEvent{OrderID: 1, OccurredAt: events[3].EventTime()}, From: entity.OrderStatusPaid}))
+		})
+
+		It("should raise no event for a rejected transition", func() {
+			order := &entity.Order{OrderID: 1, Status: entity.OrderStatusPending}
+
+			Expect(order.Transition(entity.OrderStatusDelivered)).NotTo(Succeed())
+			Expect(order.PullEvents()).To(BeEmpty())
+		})
+
+		It("should record the payment in OrderPaid", func() {
+			order := &entity.Order{OrderID: 1, Status: entity.OrderStatusPending}
+			payment := &entity.Payment{ID: "payment-1", OrderID: 1, Amount: entity.Money{Amount: 900, Currency: "USD"}}
+
+			Expect(order.Pay(payment)).To(Succeed())
+
+			events := order.PullEvents()
+			Expect(events).To(HaveLen(2))
+			paid := events[1].(entity.OrderPaid)
+			Expect(paid.PaymentID).To(Equal("payment-1"))
+			Expect(paid.Amount).To(Equal(payment.Amount))
+		})
+
+		It("should raise OrderRefunded and change the status only when it changes", func() {
+			order := &entity.Order{OrderID: 1, Status: entity.OrderStatusPaid}
+			captured := entity.Money{Amount: 1000, Currency: "USD"}
+			refund := &entity.Refund{ID: "refund-1", PaymentID: "payment-1", Amount: entity.Money{Amount: 400, Currency: "USD"}}
+
+			Expect(order.Refund(refund, entity.Money{Amount: 400, Currency: "USD"}, captured)).To(Succeed())
+			Expect(order.Status).To(Equal(entity.OrderStatusPartiallyRefunded))
+			Expect(order.PullEvents()).To(HaveLen(2))
+
+			Expect(order.Refund(refund, entity.Money{Amount: 800, Currency: "USD"}, captured)).To(Succeed())
+			events := order.PullEvents()
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].(entity.OrderRefunded).Status).To(Equal(entity.OrderStatusPartiallyRefunded))
+
+			Expect(order.Refund(refund, captured, captured)).To(Succeed())
+			Expect(order.Status).To(Equal(entity.OrderStatusRefunded))
+		})
+	})
+})
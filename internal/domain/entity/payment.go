@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"GoCleanArch/internal/domain"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentStatus is the state of a payment with the payment provider.
+type PaymentStatus string
+
+const (
+	// PaymentStatusPending means the payment has not been sent to the provider yet.
+	PaymentStatusPending PaymentStatus = "Pending"
+	// PaymentStatusAuthorized means the provider reserved the amount but has not collected it.
+	PaymentStatusAuthorized PaymentStatus = "Authorized"
+	// PaymentStatusCaptured means the provider collected the amount.
+	PaymentStatusCaptured PaymentStatus = "Captured"
+	// PaymentStatusDeclined means the provider refused the payment.
+	PaymentStatusDeclined PaymentStatus = "Declined"
+	// PaymentStatusAbandoned means the payment was never sent to the provider
+	// because another payment of the order went ahead instead.
+	PaymentStatusAbandoned PaymentStatus = "Abandoned"
+)
+
+// Payment is one attempt to pay for an order. ProviderReference identifies the
+// payment with the payment provider once it has been authorized.
+type Payment struct {
+	ID                string        `json:"id"`
+	OrderID           int           `json:"OrderId"`
+	Amount            Money         `json:"amount"`
+	Status            PaymentStatus `json:"status"`
+	ProviderReference string        `json:"provider_reference,omitempty"`
+	DeclineReason     string        `json:"decline_reason,omitempty"`
+	CreatedAt         time.Time     `json:"created_at"`
+	UpdatedAt         time.Time     `json:"updated_at"`
+	CapturedAt        time.Time     `json:"captured_at,omitzero"`
+}
+
+// PaymentDeclinedError is returned when the payment provider refuses a payment.
+// It matches domain.ErrPaymentDeclined.
+type PaymentDeclinedError struct {
+	Reason string
+}
+
+func (e *PaymentDeclinedError) Error() string {
+	return "payment declined: " + e.Reason
+}
+
+func (e *PaymentDeclinedError) Unwrap() error {
+	return domain.ErrPaymentDeclined
+}
+
+// NewPayment creates a Pending payment of amount for an order, with a new UUIDv7 ID.
+func NewPayment(orderID int, amount Money) (*Payment, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Payment{
+		ID:        id.String(),
+		OrderID:   orderID,
+		Amount:    amount,
+		Status:    PaymentStatusPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Authorize records that the provider reserved the amount under reference.
+func (p *Payment) Authorize(reference string) {
+	p.Status = PaymentStatusAuthorized
+	p.ProviderReference = reference
+	p.UpdatedAt = time.Now()
+}
+
+// Capture records that the provider collected the amount.
+func (p *Payment) Capture() {
+	p.Status = PaymentStatusCaptured
+	p.UpdatedAt = time.Now()
+	p.CapturedAt = p.UpdatedAt
+}
+
+// Abandon records that the payment will never be sent to the provider.
+func (p *Payment) Abandon() {
+	p.Status = PaymentStatusAbandoned
+	p.UpdatedAt = time.Now()
+}
+
+// Decline records that the provider refused the payment.
+func (p *Payment) Decline(reason string) {
+	p.Status = PaymentStatusDeclined
+	p.DeclineReason = reason
+	p.UpdatedAt = time.Now()
+}
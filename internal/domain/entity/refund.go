@@ -0,0 +1,65 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefundStatus is the state of a refund with the payment provider.
+type RefundStatus string
+
+const (
+	// RefundStatusPending means the refund is recorded but the provider has
+	// not confirmed it yet.
+	RefundStatusPending RefundStatus = "Pending"
+	// RefundStatusCompleted means the provider returned the amount.
+	RefundStatusCompleted RefundStatus = "Completed"
+	// RefundStatusAbandoned means the refund was never made: the provider
+	// refused it, or another refund of the order went ahead instead.
+	RefundStatusAbandoned RefundStatus = "Abandoned"
+)
+
+// Refund is money returned to the customer from a captured payment. An order
+// may have several partial refunds, which together never exceed the captured
+// amount. ProviderReference identifies the refund with the payment provider.
+type Refund struct {
+	ID                string       `json:"id"`
+	OrderID           int          `json:"OrderId"`
+	PaymentID         string       `json:"payment_id"`
+	Amount            Money        `json:"amount"`
+	Reason            string       `json:"reason,omitempty"`
+	Status            RefundStatus `json:"status"`
+	ProviderReference string       `json:"provider_reference"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+// NewRefund creates a Pending refund of amount from a payment, with a new
+// UUIDv7 ID. The ID is what the payment provider is asked to refund under,
+// and ProviderReference is set once it has made the refund.
+func NewRefund(payment *Payment, amount Money, reason string) (*Refund, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	return &Refund{
+		ID:        id.String(),
+		OrderID:   payment.OrderID,
+		PaymentID: payment.ID,
+		Amount:    amount,
+		Reason:    reason,
+		Status:    RefundStatusPending,
+		CreatedAt: time.Now(),
+	}, nil
+}
+
+// Complete records that the provider made the refund under reference.
+func (r *Refund) Complete(reference string) {
+	r.Status = RefundStatusCompleted
+	r.ProviderReference = reference
+}
+
+// Abandon records that the refund will never be made.
+func (r *Refund) Abandon() {
+	r.Status = RefundStatusAbandoned
+}
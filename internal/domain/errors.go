@@ -0,0 +1,22 @@
+// Package domain holds the errors shared by every layer of the order domain.
+// Adapters translate their own failures into these so that callers can react
+// with errors.Is regardless of which implementation is behind a port.
+package domain
+
+import "errors"
+
+var (
+	// ErrOrderNotFound is returned when no order matches a lookup.
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrPaymentNotFound is returned when no payment matches a lookup.
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrRefundNotFound is returned when no refund matches a lookup.
+	ErrRefundNotFound = errors.New("refund not found")
+	// ErrConflict is returned when a change clashes with the current state,
+	// such as saving a duplicate order or making an illegal status transition.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation is returned when input breaks a domain rule.
+	ErrValidation = errors.New("validation failed")
+	// ErrPaymentDeclined is returned when the payment provider refuses a payment.
+	ErrPaymentDeclined = errors.New("payment declined")
+)
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"GoCleanArch/internal/correlation"
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventEnvelope wraps a domain event for publishing. ID is unique to the
+// envelope, so consumers can drop duplicate deliveries. SchemaVersion is the
+// version of Data's schema for this Type, and CorrelationID ties the event to
+// the request that caused it. Data holds the event's own JSON.
+type EventEnvelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	SchemaVersion int             `json:"schema_version"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	OrderID       int             `json:"OrderId"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// NewEventEnvelope wraps an event in an envelope with a new UUIDv7 ID and the
+// correlation ID carried by ctx.
+func NewEventEnvelope(ctx context.Context, event entity.Event) (*EventEnvelope, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return &EventEnvelope{
+		ID:            id.String(),
+		Type:          event.EventType(),
+		SchemaVersion: event.EventVersion(),
+		OccurredAt:    event.EventTime(),
+		CorrelationID: correlation.ID(ctx),
+		OrderID:       event.EventOrderID(),
+		Data:          data,
+	}, nil
+}
+
+// EventPublisher is an interface for publishing domain events to other services.
+type EventPublisher interface {
+	Publish(ctx context.Context, envelope *EventEnvelope) error
+}
+
+// BatchEventPublisher is implemented by publishers that can publish several
+// events in one request. PublishBatch returns one error for each envelope, in
+// order, which is nil when that envelope was published.
+type BatchEventPublisher interface {
+	EventPublisher
+	PublishBatch(ctx context.Context, envelopes []*EventEnvelope) []error
+}
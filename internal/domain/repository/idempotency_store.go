@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrIdempotencyRecordNotFound is returned when no live record exists for an idempotency key.
+var ErrIdempotencyRecordNotFound = errors.New("idempotency record not found")
+
+// IdempotencyRecord is the stored outcome of a request made with an Idempotency-Key.
+// RequestHash fingerprints the request so that a key reused for a different
+// request can be told apart from a retry. A record with no StatusCode is a
+// reservation for a request that is still being handled.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Pending reports whether the record reserves its key for a request that is
+// still being handled.
+func (r *IdempotencyRecord) Pending() bool {
+	return r.StatusCode == 0
+}
+
+// IdempotencyStore is an interface for storing the responses of idempotent requests.
+// Records past their ExpiresAt are treated as if they did not exist.
+type IdempotencyStore interface {
+	// Get returns the live record for a key, or ErrIdempotencyRecordNotFound.
+	Get(ctx context.Context, key string) (*IdempotencyRecord, error)
+	// Save stores a record, returning domain.ErrConflict if the key already has a live record.
+	// Saving a pending record reserves the key for one request.
+	Save(ctx context.Context, record *IdempotencyRecord) error
+	// Complete stores the response of a pending record and keeps it until
+	// record.ExpiresAt, or returns ErrIdempotencyRecordNotFound when the key
+	// is no longer reserved.
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	// Release removes the pending record for a key so that the request can be
+	// made again. Completed records are left alone.
+	Release(ctx context.Context, key string) error
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"time"
+)
+
+// OutboxMessage is an order message waiting in the outbox to be published.
+// Payload holds the JSON of the EventEnvelope to publish. Messages stored
+// before events were introduced hold an entity.Order instead.
+type OutboxMessage struct {
+	ID            int64
+	OrderID       int
+	Payload       []byte
+	Attempts      int
+	LastError     string
+	CreatedAt     time.Time
+	NextAttemptAt time.Time
+	SentAt        *time.Time
+}
+
+// OutboxLease is how long the messages FetchPending returns stay claimed.
+// Another relay fetches a claimed message only once MarkSent or MarkFailed has
+// released it or the lease has run out, so a relay that stops after fetching
+// a message holds it back for at most this long.
+const OutboxLease = time.Minute
+
+// OrderOutbox is an interface for saving orders together with the messages
+// that announce them, so that a message is never lost once its order is stored.
+type OrderOutbox interface {
+	// SaveWithMessage saves an order and adds a message to the outbox atomically.
+	SaveWithMessage(ctx context.Context, order *entity.Order, message *OutboxMessage) error
+	// UpdateWithMessages updates an order like OrderRepository.Update and adds
+	// messages to the outbox atomically.
+	UpdateWithMessages(ctx context.Context, order *entity.Order, messages []*OutboxMessage) error
+	// UpdateWithRefund updates an order and adds messages like
+	// UpdateWithMessages, and stores the status and provider reference of a
+	// saved refund like RefundRepository.Update, atomically. It returns
+	// domain.ErrRefundNotFound and stores nothing when no refund has its ID.
+	UpdateWithRefund(ctx context.Context, order *entity.Order, refund *entity.Refund, messages []*OutboxMessage) error
+	// FetchPending claims and returns up to limit unsent messages that are
+	// due, have been attempted fewer than maxAttempts times and are not claimed
+	// by another fetch, oldest first. The claim lasts OutboxLease.
+	FetchPending(ctx context.Context, maxAttempts, limit int) ([]*OutboxMessage, error)
+	// MarkSent records that a message was published and releases it.
+	MarkSent(ctx context.Context, id int64, sentAt time.Time) error
+	// MarkFailed records a failed attempt and when the message should next be
+	// tried, and releases it.
+	MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error
+}
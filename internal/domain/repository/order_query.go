@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OrderSortField is a field that order listings can be sorted by.
+type OrderSortField string
+
+const (
+	OrderSortByCreatedAt OrderSortField = "created_at"
+	OrderSortByUpdatedAt OrderSortField = "updated_at"
+	OrderSortByOrderID   OrderSortField = "order_id"
+)
+
+// SortDirection is the direction of a sort.
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+const (
+	// DefaultOrderPageSize is the page size used when a query sets no limit.
+	DefaultOrderPageSize = 50
+	// MaxOrderPageSize is the largest page a single query may ask for.
+	MaxOrderPageSize = 100
+)
+
+// OrderQuery filters, sorts and pages a listing of orders. Zero values mean
+// "no filter" and the defaults chosen by Normalize.
+type OrderQuery struct {
+	Status        entity.OrderStatus
+	Paid          *bool
+	CreatedFrom   time.Time // inclusive
+	CreatedTo     time.Time // exclusive
+	SortBy        OrderSortField
+	SortDirection SortDirection
+	Limit         int
+	Cursor        string
+}
+
+// OrderPage is one page of an order listing. NextCursor is empty on the last page.
+type OrderPage struct {
+	Orders     []*entity.Order
+	NextCursor string
+}
+
+// OrderCursor is the decoded position after which the next page starts.
+// Pages are ordered by the sort field and then by OrderID, which breaks ties.
+type OrderCursor struct {
+	SortBy        OrderSortField `json:"s"`
+	SortDirection SortDirection  `json:"d"`
+	Time          time.Time      `json:"t,omitempty"`
+	OrderID       int            `json:"o"`
+}
+
+// Normalize fills in defaults and checks that the query is well formed. It
+// returns an error matching domain.ErrValidation when it is not.
+func (q OrderQuery) Normalize() (OrderQuery, error) {
+	if q.Status != "" && !q.Status.IsValid() {
+		return q, fmt.Errorf("%w: %w: %q", domain.ErrValidation, entity.ErrUnknownOrderStatus, q.Status)
+	}
+	switch q.SortBy {
+	case "":
+		q.SortBy = OrderSortByCreatedAt
+	case OrderSortByCreatedAt, OrderSortByUpdatedAt, OrderSortByOrderID:
+	default:
+		return q, fmt.Errorf("%w: unknown sort field %q", domain.ErrValidation, q.SortBy)
+	}
+	switch q.SortDirection {
+	case "":
+		q.SortDirection = SortDescending
+	case SortAscending, SortDescending:
+	default:
+		return q, fmt.Errorf("%w: unknown sort direction %q", domain.ErrValidation, q.SortDirection)
+	}
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultOrderPageSize
+	case q.Limit < 0 || q.Limit > MaxOrderPageSize:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrValidation, MaxOrderPageSize)
+	}
+	if !q.CreatedFrom.IsZero() && !q.CreatedTo.IsZero() && !q.CreatedFrom.Before(q.CreatedTo) {
+		return q, fmt.Errorf("%w: created_from must be before created_to", domain.ErrValidation)
+	}
+	if _, err := q.DecodeCursor(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// DecodeCursor decodes the query's cursor. It returns nil for the first page
+// and an error matching domain.ErrValidation if the cursor is malformed or was
+// issued for a different sort.
+func (q OrderQuery) DecodeCursor() (*OrderCursor, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", domain.ErrValidation)
+	}
+	var cursor OrderCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", domain.ErrValidation)
+	}
+	if cursor.SortBy != q.SortBy || cursor.SortDirection != q.SortDirection {
+		return nil, fmt.Errorf("%w: cursor does not match the requested sort", domain.ErrValidation)
+	}
+	return &cursor, nil
+}
+
+// EncodeCursor returns the cursor that starts the page after order.
+func (q OrderQuery) EncodeCursor(order *entity.Order) string {
+	cursor := OrderCursor{SortBy: q.SortBy, SortDirection: q.SortDirection, OrderID: order.OrderID}
+	switch q.SortBy {
+	case OrderSortByCreatedAt:
+		cursor.Time = order.CreatedAt
+	case OrderSortByUpdatedAt:
+		cursor.Time = order.UpdatedAt
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Page builds an OrderPage from orders fetched in query order. Implementations
+// fetch one order more than Limit so Page can tell whether another page follows.
+func (q OrderQuery) Page(orders []*entity.Order) *OrderPage {
+	page := &OrderPage{Orders: orders}
+	if len(orders) > q.Limit {
+		page.Orders = orders[:q.Limit]
+		page.NextCursor = q.EncodeCursor(page.Orders[q.Limit-1])
+	}
+	return page
+}
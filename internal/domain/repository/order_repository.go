@@ -1,15 +1,59 @@
 package repository
 
-import "GoCleanArch/internal/domain/entity"
+import (
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"time"
+)
 
 // OrderRepository is an interface for interacting with order data.
 type OrderRepository interface {
-	Save(order *entity.Order) error
-	GetByOrderID(orderID int) (*entity.Order, error)
-	GetAll() ([]*entity.Order, error)
+	Save(ctx context.Context, order *entity.Order) error
+	GetByID(ctx context.Context, id string) (*entity.Order, error)
+	GetByOrderID(ctx context.Context, orderID int) (*entity.Order, error)
+	Find(ctx context.Context, query OrderQuery) (*OrderPage, error)
+	// Update stores the mutable fields of an order and increments its Version.
+	// It returns domain.ErrConflict when the stored order is no longer at
+	// order.Version, because it changed after it was read, and
+	// domain.ErrOrderNotFound when there is no such order.
+	Update(ctx context.Context, order *entity.Order) error
+	Delete(ctx context.Context, orderID int) error
 }
 
-// OrderMessageQueue is an interface for sending order messages.
-type OrderMessageQueue interface {
-	Send(order *entity.Order) error
+// OrderMessageTypeOrder is the type of the order snapshots that were sent
+// before domain events replaced them.
+const OrderMessageTypeOrder = "Order"
+
+// OrderMessage is a message received from the order queue. Body is an
+// EventEnvelope and Type is its event type. Messages sent before events were
+// introduced have no type or OrderMessageTypeOrder, and their body is an entity.Order.
+// ReceiptHandle identifies this particular delivery and is what Ack uses.
+// ReceiveCount is how many times the message has been delivered, this time
+// included, or 0 when the queue doesn't count deliveries.
+type OrderMessage struct {
+	ID            string
+	Type          string
+	Body          []byte
+	ReceiptHandle string
+	ReceiveCount  int
+}
+
+// OrderMessageConsumer is an interface for receiving order messages.
+// A received message stays on the queue until it is acknowledged.
+type OrderMessageConsumer interface {
+	Receive(ctx context.Context) ([]*OrderMessage, error)
+	Ack(ctx context.Context, message *OrderMessage) error
+}
+
+// OrderMessageRetrier is implemented by consumers that can put off delivering
+// a message again after it failed, rather than waiting for their usual timeout.
+type OrderMessageRetrier interface {
+	Retry(ctx context.Context, message *OrderMessage, delay time.Duration) error
+}
+
+// OrderMessageReleaser is implemented by consumers that hold on to a failed
+// message until it is handed back, rather than delivering it again after a
+// timeout. Release makes the message available for delivery again.
+type OrderMessageReleaser interface {
+	Release(ctx context.Context, message *OrderMessage) error
 }
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"context"
+)
+
+// PaymentRequest asks the payment provider to authorize an amount. PaymentID is
+// unique to each attempt, so providers can use it as an idempotency key.
+type PaymentRequest struct {
+	PaymentID     string
+	OrderID       int
+	Amount        entity.Money
+	PaymentMethod string
+}
+
+// PaymentGateway is an interface for charging customers through a payment provider.
+// A payment the provider refuses returns an *entity.PaymentDeclinedError.
+type PaymentGateway interface {
+	// Authorize reserves the amount and returns the provider's reference for
+	// the payment. Authorizing a PaymentID again returns the reference of the
+	// first authorization without reserving the amount twice.
+	Authorize(ctx context.Context, request PaymentRequest) (string, error)
+	// Capture collects an authorized amount. Capturing the same amount again
+	// succeeds without collecting it twice, so a capture can be retried.
+	Capture(ctx context.Context, reference string, amount entity.Money) error
+	// Refund returns part or all of a captured amount to the customer and
+	// returns the provider's reference for the refund. refundID is unique to
+	// each refund, so providers can use it as an idempotency key.
+	Refund(ctx context.Context, reference, refundID string, amount entity.Money) (string, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"context"
+)
+
+// PaymentRepository is an interface for recording payments.
+type PaymentRepository interface {
+	// Save records a payment. Saving an ID twice returns domain.ErrConflict.
+	Save(ctx context.Context, payment *entity.Payment) error
+	// Update stores the status, provider reference, decline reason and times
+	// of a saved payment. It returns domain.ErrPaymentNotFound when no payment
+	// has its ID.
+	Update(ctx context.Context, payment *entity.Payment) error
+	// ListByOrderID returns the payments of an order, oldest first.
+	ListByOrderID(ctx context.Context, orderID int) ([]*entity.Payment, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"context"
+)
+
+// RefundRepository is an interface for recording refunds.
+type RefundRepository interface {
+	// Save records a refund. Saving an ID twice returns domain.ErrConflict.
+	Save(ctx context.Context, refund *entity.Refund) error
+	// Update stores the status and provider reference of a saved refund. It
+	// returns domain.ErrRefundNotFound when no refund has its ID.
+	Update(ctx context.Context, refund *entity.Refund) error
+	// ListByOrderID returns the refunds of an order, oldest first.
+	ListByOrderID(ctx context.Context, orderID int) ([]*entity.Refund, error)
+}
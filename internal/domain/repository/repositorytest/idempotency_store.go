@@ -0,0 +1,108 @@
+package repositorytest
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// NewIdempotencyRecord returns a pending record for key that expires after
+// ttl, with a creation time that is a whole second in UTC.
+func NewIdempotencyRecord(key string, now time.Time, ttl time.Duration) *repository.IdempotencyRecord {
+	createdAt := now.UTC().Truncate(time.Second)
+	return &repository.IdempotencyRecord{
+		Key:         key,
+		RequestHash: "hash-" + key,
+		Body:        []byte{},
+		CreatedAt:   createdAt,
+		ExpiresAt:   createdAt.Add(ttl),
+	}
+}
+
+// DescribeIdempotencyStore declares the specs every IdempotencyStore must pass.
+// newStore is called before each spec and must return an empty store.
+func DescribeIdempotencyStore(newStore func() repository.IdempotencyStore) {
+	var (
+		ctx   context.Context
+		store repository.IdempotencyStore
+		now   time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		store = newStore()
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	It("should reserve a key only once", func() {
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now, time.Hour))).To(Succeed())
+
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now, time.Hour))).To(MatchError(domain.ErrConflict))
+
+		record, err := store.Get(ctx, "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(record.Pending()).To(BeTrue())
+		Expect(record.RequestHash).To(Equal("hash-key-1"))
+	})
+
+	It("should store the response of a reserved key", func() {
+		record := NewIdempotencyRecord("key-1", now, time.Minute)
+		Expect(store.Save(ctx, record)).To(Succeed())
+
+		record.StatusCode = 201
+		record.ContentType = "application/json"
+		record.Body = []byte(`{"OrderId":1}`)
+		record.ExpiresAt = now.Add(time.Hour)
+		Expect(store.Complete(ctx, record)).To(Succeed())
+
+		stored, err := store.Get(ctx, "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.Pending()).To(BeFalse())
+		Expect(stored.StatusCode).To(Equal(201))
+		Expect(stored.ContentType).To(Equal("application/json"))
+		Expect(string(stored.Body)).To(Equal(`{"OrderId":1}`))
+		Expect(stored.ExpiresAt).To(BeTemporally("==", now.Add(time.Hour)))
+	})
+
+	It("should return ErrIdempotencyRecordNotFound when completing a key that is not reserved", func() {
+		record := NewIdempotencyRecord("key-1", now, time.Hour)
+		record.StatusCode = 201
+
+		Expect(store.Complete(ctx, record)).To(MatchError(repository.ErrIdempotencyRecordNotFound))
+	})
+
+	It("should release a reserved key so that it can be reserved again", func() {
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now, time.Hour))).To(Succeed())
+
+		Expect(store.Release(ctx, "key-1")).To(Succeed())
+
+		_, err := store.Get(ctx, "key-1")
+		Expect(err).To(MatchError(repository.ErrIdempotencyRecordNotFound))
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now, time.Hour))).To(Succeed())
+	})
+
+	It("should not release a completed key", func() {
+		record := NewIdempotencyRecord("key-1", now, time.Hour)
+		Expect(store.Save(ctx, record)).To(Succeed())
+		record.StatusCode = 201
+		Expect(store.Complete(ctx, record)).To(Succeed())
+
+		Expect(store.Release(ctx, "key-1")).To(Succeed())
+
+		stored, err := store.Get(ctx, "key-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stored.StatusCode).To(Equal(201))
+	})
+
+	It("should treat an expired reservation as missing and replace it", func() {
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now.Add(-time.Hour), time.Minute))).To(Succeed())
+
+		_, err := store.Get(ctx, "key-1")
+		Expect(err).To(MatchError(repository.ErrIdempotencyRecordNotFound))
+		Expect(store.Save(ctx, NewIdempotencyRecord("key-1", now, time.Hour))).To(Succeed())
+	})
+}
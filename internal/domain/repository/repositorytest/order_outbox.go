@@ -0,0 +1,263 @@
+package repositorytest
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// OrderRepositoryWithOutbox is implemented by adapters that store orders and
+// their outbox messages together.
+type OrderRepositoryWithOutbox interface {
+	repository.OrderRepository
+	repository.OrderOutbox
+}
+
+// DescribeOrderOutbox declares the specs every OrderOutbox must pass.
+// newOutbox is called before each spec and must return an empty repository.
+func DescribeOrderOutbox(newOutbox func() OrderRepositoryWithOutbox) {
+	var (
+		ctx    context.Context
+		outbox OrderRepositoryWithOutbox
+		now    time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		outbox = newOutbox()
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	newMessage := func(orderID int) *repository.OutboxMessage {
+		return &repository.OutboxMessage{OrderID: orderID, Payload: []byte(`{"OrderId":1}`), CreatedAt: now, NextAttemptAt: now}
+	}
+
+	It("should save the order together with its message", func() {
+		message := newMessage(1)
+
+		Expect(outbox.SaveWithMessage(ctx, NewOrder(1, now), message)).To(Succeed())
+
+		Expect(message.ID).NotTo(BeZero())
+		_, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+		Expect(pending[0].ID).To(Equal(message.ID))
+		Expect(pending[0].OrderID).To(Equal(1))
+		Expect(pending[0].Payload).To(MatchJSON(`{"OrderId":1}`))
+	})
+
+	It("should store nothing when the order is a duplicate", func() {
+		Expect(outbox.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+		Expect(outbox.SaveWithMessage(ctx, NewOrder(1, now), newMessage(1))).To(MatchError(domain.ErrConflict))
+
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("should return pending messages oldest first, up to the limit", func() {
+		for orderID := 1; orderID <= 3; orderID++ {
+			Expect(outbox.SaveWithMessage(ctx, NewOrder(orderID, now), newMessage(orderID))).To(Succeed())
+		}
+
+		pending, err := outbox.FetchPending(ctx, 3, 2)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(2))
+		Expect(pending[0].OrderID).To(Equal(1))
+		Expect(pending[1].OrderID).To(Equal(2))
+	})
+
+	It("should stop returning a message once it is sent", func() {
+		message := newMessage(1)
+		Expect(outbox.SaveWithMessage(ctx, NewOrder(1, now), message)).To(Succeed())
+
+		Expect(outbox.MarkSent(ctx, message.ID, now)).To(Succeed())
+
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("should hold a failed message back until its next attempt and its attempts run out", func() {
+		message := newMessage(1)
+		Expect(outbox.SaveWithMessage(ctx, NewOrder(1, now), message)).To(Succeed())
+
+		Expect(outbox.MarkFailed(ctx, message.ID, "queue unavailable", now.Add(time.Hour))).To(Succeed())
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+
+		Expect(outbox.MarkFailed(ctx, message.ID, "queue unavailable", now)).To(Succeed())
+		pending, err = outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+		Expect(pending[0].Attempts).To(Equal(2))
+		Expect(pending[0].LastError).To(Equal("queue unavailable"))
+
+		pending, err = outbox.FetchPending(ctx, 2, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("should not return a message another fetch has claimed until it is released", func() {
+		message := newMessage(1)
+		Expect(outbox.SaveWithMessage(ctx, NewOrder(1, now), message)).To(Succeed())
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+
+		pending, err = outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+
+		Expect(outbox.MarkFailed(ctx, message.ID, "queue unavailable", now)).To(Succeed())
+		pending, err = outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+		Expect(pending[0].ID).To(Equal(message.ID))
+	})
+
+	It("should update the order together with its messages", func() {
+		Expect(outbox.Save(ctx, NewOrder(1, now))).To(Succeed())
+		order, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		order.Data = "updated"
+		messages := []*repository.OutboxMessage{newMessage(1), newMessage(1)}
+
+		Expect(outbox.UpdateWithMessages(ctx, order, messages)).To(Succeed())
+
+		Expect(messages[0].ID).NotTo(BeZero())
+		Expect(messages[1].ID).To(BeNumerically(">", messages[0].ID))
+		updated, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Data).To(Equal("updated"))
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(2))
+		Expect(pending[0].ID).To(Equal(messages[0].ID))
+		Expect(pending[1].ID).To(Equal(messages[1].ID))
+	})
+
+	It("should store nothing when the order changed after it was read", func() {
+		Expect(outbox.Save(ctx, NewOrder(1, now))).To(Succeed())
+		stale, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		current, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outbox.Update(ctx, current)).To(Succeed())
+		stale.Data = "stale"
+
+		Expect(outbox.UpdateWithMessages(ctx, stale, []*repository.OutboxMessage{newMessage(1)})).To(MatchError(domain.ErrConflict))
+
+		updated, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Data).NotTo(Equal("stale"))
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("should store no messages when the updated order does not exist", func() {
+		Expect(outbox.UpdateWithMessages(ctx, NewOrder(1, now), []*repository.OutboxMessage{newMessage(1)})).To(MatchError(domain.ErrOrderNotFound))
+
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+}
+
+// DescribeRefundOutbox declares the specs every OrderOutbox must pass when it
+// stores refunds with orders. newStores is called before each spec and must
+// return an empty outbox and the empty refund repository it updates refunds in.
+func DescribeRefundOutbox(newStores func() (OrderRepositoryWithOutbox, repository.RefundRepository)) {
+	var (
+		ctx        context.Context
+		outbox     OrderRepositoryWithOutbox
+		refundRepo repository.RefundRepository
+		now        time.Time
+		order      *entity.Order
+		refund     *entity.Refund
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		outbox, refundRepo = newStores()
+		now = time.Now().UTC().Truncate(time.Second)
+
+		Expect(outbox.Save(ctx, NewOrder(1, now))).To(Succeed())
+		var err error
+		order, err = outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		refund = NewRefund(1, 1, now)
+		refund.Status = entity.RefundStatusPending
+		refund.ProviderReference = ""
+		Expect(refundRepo.Save(ctx, refund)).To(Succeed())
+	})
+
+	newMessage := func() *repository.OutboxMessage {
+		return &repository.OutboxMessage{OrderID: 1, Payload: []byte(`{"OrderId":1}`), CreatedAt: now, NextAttemptAt: now}
+	}
+
+	// storedRefund returns the stored refund of the order.
+	storedRefund := func() *entity.Refund {
+		refunds, err := refundRepo.ListByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds).To(HaveLen(1))
+		return refunds[0]
+	}
+
+	It("should update the order and the refund together with the messages", func() {
+		order.Data = "refunded"
+		refund.Complete("refund-ref-1")
+		message := newMessage()
+
+		Expect(outbox.UpdateWithRefund(ctx, order, refund, []*repository.OutboxMessage{message})).To(Succeed())
+
+		Expect(message.ID).NotTo(BeZero())
+		updated, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Data).To(Equal("refunded"))
+		Expect(storedRefund().Status).To(Equal(entity.RefundStatusCompleted))
+		Expect(storedRefund().ProviderReference).To(Equal("refund-ref-1"))
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(HaveLen(1))
+	})
+
+	It("should store nothing when the refund does not exist", func() {
+		order.Data = "refunded"
+		missing := NewRefund(2, 1, now)
+
+		Expect(outbox.UpdateWithRefund(ctx, order, missing, []*repository.OutboxMessage{newMessage()})).To(MatchError(domain.ErrRefundNotFound))
+
+		updated, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Data).NotTo(Equal("refunded"))
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+
+	It("should store nothing when the order changed after it was read", func() {
+		current, err := outbox.GetByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outbox.Update(ctx, current)).To(Succeed())
+		refund.Complete("refund-ref-1")
+
+		Expect(outbox.UpdateWithRefund(ctx, order, refund, []*repository.OutboxMessage{newMessage()})).To(MatchError(domain.ErrConflict))
+
+		Expect(storedRefund().Status).To(Equal(entity.RefundStatusPending))
+		pending, err := outbox.FetchPending(ctx, 3, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pending).To(BeEmpty())
+	})
+}
@@ -0,0 +1,428 @@
+// Package repositorytest provides contract test suites that every
+// implementation of the repository interfaces must pass. Adapter packages
+// run them from their own Ginkgo suites.
+package repositorytest
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// concurrentWriters is how many goroutines the concurrency specs write with.
+const concurrentWriters = 20
+
+// NewOrder returns a Pending order with the internal ID "order-<orderID>" and
+// times that are whole seconds in UTC, the precision every adapter stores.
+func NewOrder(orderID int, createdAt time.Time) *entity.Order {
+	createdAt = createdAt.UTC().Truncate(time.Second)
+	return &entity.Order{
+		ID:        "order-" + strconv.Itoa(orderID),
+		Data:      "order " + strconv.Itoa(orderID),
+		OrderID:   orderID,
+		Status:    entity.OrderStatusPending,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+	}
+}
+
+// NewOrderWithItems returns NewOrder with two items priced in USD, a tax rate
+// of 8.25% and the totals calculated.
+func NewOrderWithItems(orderID int, createdAt time.Time) *entity.Order {
+	order := NewOrder(orderID, createdAt)
+	order.Items = []entity.OrderItem{
+		{SKU: "SKU-" + strconv.Itoa(orderID) + "-A", Description: "First item", Quantity: 2, UnitPrice: entity.Money{Amount: 1250, Currency: "USD"}},
+		{SKU: "SKU-" + strconv.Itoa(orderID) + "-B", Description: "Second item", Quantity: 1, UnitPrice: entity.Money{Amount: 499, Currency: "USD"}},
+	}
+	order.TaxRate = 825
+	Expect(order.CalculateTotals()).To(Succeed())
+	return order
+}
+
+// DescribeOrderRepository declares the specs every OrderRepository must pass.
+// newRepository is called before each spec and must return an empty repository.
+func DescribeOrderRepository(newRepository func() repository.OrderRepository) {
+	var (
+		ctx       context.Context
+		orderRepo repository.OrderRepository
+		now       time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		orderRepo = newRepository()
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	findAll := func(query repository.OrderQuery) []int {
+		var orderIDs []int
+		for {
+			page, err := orderRepo.Find(ctx, query)
+			Expect(err).NotTo(HaveOccurred())
+			for _, order := range page.Orders {
+				orderIDs = append(orderIDs, order.OrderID)
+			}
+			if page.NextCursor == "" {
+				return orderIDs
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	Describe("Save, GetByID and GetByOrderID", func() {
+		It("should return the saved order", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+			order, err := orderRepo.GetByOrderID(ctx, 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(order.OrderID).To(Equal(1))
+			Expect(order.Data).To(Equal("order 1"))
+			Expect(order.Status).To(Equal(entity.OrderStatusPending))
+			Expect(order.Paid).To(BeFalse())
+			Expect(order.CreatedAt).To(BeTemporally("==", now))
+			Expect(order.UpdatedAt).To(BeTemporally("==", now))
+		})
+
+		It("should find the saved order by its internal ID", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			Expect(orderRepo.Save(ctx, NewOrder(2, now))).To(Succeed())
+
+			order, err := orderRepo.GetByID(ctx, "order-2")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(order.ID).To(Equal("order-2"))
+			Expect(order.OrderID).To(Equal(2))
+		})
+
+		It("should not let callers change the stored order", func() {
+			order := NewOrder(1, now)
+			Expect(orderRepo.Save(ctx, order)).To(Succeed())
+			order.Data = "changed after saving"
+
+			found, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			found.Data = "changed after getting"
+
+			found, err = orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Data).To(Equal("order 1"))
+		})
+
+		It("should return domain.ErrConflict for a duplicate order", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(MatchError(domain.ErrConflict))
+		})
+
+		It("should return domain.ErrConflict for a duplicate OrderID under a new ID", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order := NewOrder(1, now)
+			order.ID = "another-id"
+
+			Expect(orderRepo.Save(ctx, order)).To(MatchError(domain.ErrConflict))
+		})
+
+		It("should return domain.ErrConflict for a duplicate ID under a new OrderID", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order := NewOrder(2, now)
+			order.ID = "order-1"
+
+			Expect(orderRepo.Save(ctx, order)).To(MatchError(domain.ErrConflict))
+		})
+
+		It("should return domain.ErrOrderNotFound for a missing order", func() {
+			_, err := orderRepo.GetByOrderID(ctx, 404)
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+
+			_, err = orderRepo.GetByID(ctx, "order-404")
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Describe("items and totals", func() {
+		It("should return the items in order with the totals", func() {
+			saved := NewOrderWithItems(1, now)
+			Expect(orderRepo.Save(ctx, saved)).To(Succeed())
+
+			byOrderID, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			byID, err := orderRepo.GetByID(ctx, "order-1")
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, order := range []*entity.Order{byOrderID, byID} {
+				Expect(order.Items).To(Equal(saved.Items))
+				Expect(order.TaxRate).To(Equal(825))
+				Expect(order.Subtotal).To(Equal(entity.Money{Amount: 2999, Currency: "USD"}))
+				Expect(order.Tax).To(Equal(entity.Money{Amount: 247, Currency: "USD"}))
+				Expect(order.Total).To(Equal(entity.Money{Amount: 3246, Currency: "USD"}))
+			}
+		})
+
+		It("should leave an order without items with zero totals", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+			order, err := orderRepo.GetByOrderID(ctx, 1)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(order.Items).To(BeEmpty())
+			Expect(order.Total.IsZero()).To(BeTrue())
+		})
+
+		It("should return the items of every order in a page", func() {
+			Expect(orderRepo.Save(ctx, NewOrderWithItems(1, now))).To(Succeed())
+			Expect(orderRepo.Save(ctx, NewOrder(2, now))).To(Succeed())
+			Expect(orderRepo.Save(ctx, NewOrderWithItems(3, now))).To(Succeed())
+
+			page, err := orderRepo.Find(ctx, repository.OrderQuery{SortBy: repository.OrderSortByOrderID, SortDirection: repository.SortAscending})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.Orders).To(HaveLen(3))
+			Expect(page.Orders[0].Items).To(Equal(NewOrderWithItems(1, now).Items))
+			Expect(page.Orders[1].Items).To(BeEmpty())
+			Expect(page.Orders[2].Items).To(Equal(NewOrderWithItems(3, now).Items))
+		})
+
+		It("should not let callers change the stored items", func() {
+			order := NewOrderWithItems(1, now)
+			Expect(orderRepo.Save(ctx, order)).To(Succeed())
+			order.Items[0].Quantity = 99
+
+			found, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			found.Items[0].Quantity = 98
+
+			found, err = orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found.Items[0].Quantity).To(Equal(2))
+		})
+
+		It("should keep the items and totals on Update", func() {
+			Expect(orderRepo.Save(ctx, NewOrderWithItems(1, now))).To(Succeed())
+			order := NewOrder(1, now)
+			order.Status = entity.OrderStatusPaid
+
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+
+			updated, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Status).To(Equal(entity.OrderStatusPaid))
+			Expect(updated.Items).To(HaveLen(2))
+			Expect(updated.Total).To(Equal(entity.Money{Amount: 3246, Currency: "USD"}))
+		})
+
+		It("should remove the items with the order", func() {
+			Expect(orderRepo.Save(ctx, NewOrderWithItems(1, now))).To(Succeed())
+			Expect(orderRepo.Delete(ctx, 1)).To(Succeed())
+
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+			order, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(order.Items).To(BeEmpty())
+		})
+	})
+
+	Describe("Update", func() {
+		It("should overwrite the mutable fields", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order := NewOrder(1, now)
+			order.Data = "changed"
+			order.Status = entity.OrderStatusPaid
+			order.Paid = true
+			order.UpdatedAt = now.Add(time.Minute)
+
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+
+			updated, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Data).To(Equal("changed"))
+			Expect(updated.Status).To(Equal(entity.OrderStatusPaid))
+			Expect(updated.Paid).To(BeTrue())
+			Expect(updated.UpdatedAt).To(BeTemporally("==", now.Add(time.Minute)))
+		})
+
+		It("should keep the ID and creation time", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order := NewOrder(1, now.Add(time.Hour))
+			order.ID = "another-id"
+
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+
+			updated, err := orderRepo.GetByID(ctx, "order-1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.CreatedAt).To(BeTemporally("==", now))
+		})
+
+		It("should succeed when nothing changes", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order := NewOrder(1, now)
+
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+		})
+
+		It("should increment the version", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			order, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(orderRepo.Update(ctx, order)).To(Succeed())
+
+			Expect(order.Version).To(Equal(1))
+			updated, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Version).To(Equal(1))
+		})
+
+		It("should return domain.ErrConflict when the order changed after it was read", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+			first, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			second, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			first.Data = "first"
+			second.Data = "second"
+
+			Expect(orderRepo.Update(ctx, first)).To(Succeed())
+			Expect(orderRepo.Update(ctx, second)).To(MatchError(domain.ErrConflict))
+
+			updated, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.Data).To(Equal("first"))
+			Expect(second.Version).To(Equal(0))
+		})
+
+		It("should return domain.ErrOrderNotFound for a missing order", func() {
+			Expect(orderRepo.Update(ctx, NewOrder(404, now))).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Describe("Delete", func() {
+		It("should remove the order", func() {
+			Expect(orderRepo.Save(ctx, NewOrder(1, now))).To(Succeed())
+
+			Expect(orderRepo.Delete(ctx, 1)).To(Succeed())
+
+			_, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+			_, err = orderRepo.GetByID(ctx, "order-1")
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+
+		It("should return domain.ErrOrderNotFound for a missing order", func() {
+			Expect(orderRepo.Delete(ctx, 404)).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Describe("Find", func() {
+		BeforeEach(func() {
+			// Orders 1 to 3 share a creation time, so order_id has to break the tie.
+			for orderID, offset := range map[int]time.Duration{1: 0, 2: 0, 3: 0, 4: time.Second, 5: 2 * time.Second} {
+				order := NewOrder(orderID, now.Add(offset))
+				order.UpdatedAt = now.Add(-offset)
+				if orderID%2 == 0 {
+					order.Status = entity.OrderStatusPaid
+					order.Paid = true
+				}
+				Expect(orderRepo.Save(ctx, order)).To(Succeed())
+			}
+		})
+
+		It("should list every order newest first by default", func() {
+			Expect(findAll(repository.OrderQuery{Limit: 2})).To(Equal([]int{5, 4, 3, 2, 1}))
+		})
+
+		It("should list orders in each sort order", func() {
+			Expect(findAll(repository.OrderQuery{SortBy: repository.OrderSortByCreatedAt, SortDirection: repository.SortAscending, Limit: 2})).To(Equal([]int{1, 2, 3, 4, 5}))
+			Expect(findAll(repository.OrderQuery{SortBy: repository.OrderSortByUpdatedAt, SortDirection: repository.SortAscending, Limit: 2})).To(Equal([]int{5, 4, 1, 2, 3}))
+			Expect(findAll(repository.OrderQuery{SortBy: repository.OrderSortByOrderID, SortDirection: repository.SortDescending, Limit: 2})).To(Equal([]int{5, 4, 3, 2, 1}))
+		})
+
+		It("should filter by status, paid and creation time", func() {
+			paid := true
+			Expect(findAll(repository.OrderQuery{Status: entity.OrderStatusPaid, SortBy: repository.OrderSortByOrderID, SortDirection: repository.SortAscending})).To(Equal([]int{2, 4}))
+			Expect(findAll(repository.OrderQuery{Paid: &paid, SortBy: repository.OrderSortByOrderID, SortDirection: repository.SortAscending})).To(Equal([]int{2, 4}))
+			Expect(findAll(repository.OrderQuery{CreatedFrom: now.Add(time.Second), CreatedTo: now.Add(2 * time.Second)})).To(Equal([]int{4}))
+		})
+
+		It("should return an empty last page without a cursor", func() {
+			page, err := orderRepo.Find(ctx, repository.OrderQuery{Status: entity.OrderStatusCancelled})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(page.Orders).To(BeEmpty())
+			Expect(page.NextCursor).To(BeEmpty())
+		})
+
+		It("should reject a malformed cursor", func() {
+			_, err := orderRepo.Find(ctx, repository.OrderQuery{Cursor: "not a cursor"})
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+		})
+	})
+
+	Describe("concurrent writes", func() {
+		It("should keep every order saved at the same time", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, concurrentWriters)
+			for i := 1; i <= concurrentWriters; i++ {
+				wg.Add(1)
+				go func(orderID int) {
+					defer wg.Done()
+					errs <- orderRepo.Save(ctx, NewOrder(orderID, now))
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(findAll(repository.OrderQuery{Limit: repository.MaxOrderPageSize})).To(HaveLen(concurrentWriters))
+		})
+
+		It("should let exactly one of many saves of the same order succeed", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, concurrentWriters)
+			for i := 0; i < concurrentWriters; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- orderRepo.Save(ctx, NewOrder(1, now))
+				}()
+			}
+			wg.Wait()
+			close(errs)
+
+			saved := 0
+			for err := range errs {
+				if err == nil {
+					saved++
+					continue
+				}
+				Expect(errors.Is(err, domain.ErrConflict)).To(BeTrue(), "unexpected error: %v", err)
+			}
+			Expect(saved).To(Equal(1))
+		})
+	})
+
+	Describe("a cancelled context", func() {
+		It("should fail instead of saving", func() {
+			cancelled, cancel := context.WithCancel(ctx)
+			cancel()
+
+			Expect(orderRepo.Save(cancelled, NewOrder(1, now))).NotTo(Succeed())
+
+			_, err := orderRepo.GetByOrderID(ctx, 1)
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+}
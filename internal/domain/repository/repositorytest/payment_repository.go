@@ -0,0 +1,140 @@
+package repositorytest
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// NewPayment returns a captured payment of 10.00 USD with the ID
+// "payment-<n>" and times that are whole seconds in UTC.
+func NewPayment(n, orderID int, createdAt time.Time) *entity.Payment {
+	createdAt = createdAt.UTC().Truncate(time.Second)
+	return &entity.Payment{
+		ID:                "payment-" + strconv.Itoa(n),
+		OrderID:           orderID,
+		Amount:            entity.Money{Amount: 1000, Currency: "USD"},
+		Status:            entity.PaymentStatusCaptured,
+		ProviderReference: "ref-" + strconv.Itoa(n),
+		CreatedAt:         createdAt,
+		UpdatedAt:         createdAt,
+		CapturedAt:        createdAt,
+	}
+}
+
+// DescribePaymentRepository declares the specs every PaymentRepository must pass.
+// newRepository is called before each spec and must return an empty repository.
+func DescribePaymentRepository(newRepository func() repository.PaymentRepository) {
+	var (
+		ctx         context.Context
+		paymentRepo repository.PaymentRepository
+		now         time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		paymentRepo = newRepository()
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	It("should list the payments of an order oldest first", func() {
+		Expect(paymentRepo.Save(ctx, NewPayment(2, 1, now.Add(time.Second)))).To(Succeed())
+		Expect(paymentRepo.Save(ctx, NewPayment(1, 1, now))).To(Succeed())
+		Expect(paymentRepo.Save(ctx, NewPayment(3, 2, now))).To(Succeed())
+
+		payments, err := paymentRepo.ListByOrderID(ctx, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments).To(HaveLen(2))
+		Expect(payments[0].ID).To(Equal("payment-1"))
+		Expect(payments[1].ID).To(Equal("payment-2"))
+	})
+
+	It("should return every field of the saved payment", func() {
+		saved := NewPayment(1, 1, now)
+		Expect(paymentRepo.Save(ctx, saved)).To(Succeed())
+
+		payments, err := paymentRepo.ListByOrderID(ctx, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments).To(HaveLen(1))
+		payment := payments[0]
+		Expect(payment.ID).To(Equal(saved.ID))
+		Expect(payment.Amount).To(Equal(saved.Amount))
+		Expect(payment.Status).To(Equal(entity.PaymentStatusCaptured))
+		Expect(payment.ProviderReference).To(Equal("ref-1"))
+		Expect(payment.CreatedAt).To(BeTemporally("==", now))
+		Expect(payment.CapturedAt).To(BeTemporally("==", now))
+	})
+
+	It("should keep a declined payment without a capture time", func() {
+		declined := NewPayment(1, 1, now)
+		declined.Status = entity.PaymentStatusDeclined
+		declined.DeclineReason = "insufficient_funds"
+		declined.CapturedAt = time.Time{}
+		Expect(paymentRepo.Save(ctx, declined)).To(Succeed())
+
+		payments, err := paymentRepo.ListByOrderID(ctx, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments[0].Status).To(Equal(entity.PaymentStatusDeclined))
+		Expect(payments[0].DeclineReason).To(Equal("insufficient_funds"))
+		Expect(payments[0].CapturedAt.IsZero()).To(BeTrue())
+	})
+
+	It("should update the status, reference and times of a payment", func() {
+		payment := NewPayment(1, 1, now)
+		payment.Status = entity.PaymentStatusPending
+		payment.ProviderReference = ""
+		payment.CapturedAt = time.Time{}
+		Expect(paymentRepo.Save(ctx, payment)).To(Succeed())
+
+		payment.Status = entity.PaymentStatusCaptured
+		payment.ProviderReference = "ref-1"
+		payment.UpdatedAt = now.Add(time.Minute)
+		payment.CapturedAt = now.Add(time.Minute)
+		Expect(paymentRepo.Update(ctx, payment)).To(Succeed())
+
+		payments, err := paymentRepo.ListByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments).To(HaveLen(1))
+		Expect(payments[0].Status).To(Equal(entity.PaymentStatusCaptured))
+		Expect(payments[0].ProviderReference).To(Equal("ref-1"))
+		Expect(payments[0].UpdatedAt).To(BeTemporally("==", now.Add(time.Minute)))
+		Expect(payments[0].CapturedAt).To(BeTemporally("==", now.Add(time.Minute)))
+		Expect(payments[0].CreatedAt).To(BeTemporally("==", now))
+	})
+
+	It("should return domain.ErrPaymentNotFound when updating a missing payment", func() {
+		Expect(paymentRepo.Update(ctx, NewPayment(1, 1, now))).To(MatchError(domain.ErrPaymentNotFound))
+	})
+
+	It("should return no payments for an order without any", func() {
+		payments, err := paymentRepo.ListByOrderID(ctx, 404)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments).To(BeEmpty())
+	})
+
+	It("should return domain.ErrConflict for a duplicate ID", func() {
+		Expect(paymentRepo.Save(ctx, NewPayment(1, 1, now))).To(Succeed())
+
+		Expect(paymentRepo.Save(ctx, NewPayment(1, 2, now))).To(MatchError(domain.ErrConflict))
+	})
+
+	It("should not let callers change the stored payment", func() {
+		payment := NewPayment(1, 1, now)
+		Expect(paymentRepo.Save(ctx, payment)).To(Succeed())
+		payment.Status = entity.PaymentStatusDeclined
+
+		payments, err := paymentRepo.ListByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(payments[0].Status).To(Equal(entity.PaymentStatusCaptured))
+	})
+}
@@ -0,0 +1,120 @@
+package repositorytest
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// NewRefund returns a completed refund of 2.50 USD from "payment-1" with the
+// ID "refund-<n>" and a creation time that is a whole second in UTC.
+func NewRefund(n, orderID int, createdAt time.Time) *entity.Refund {
+	return &entity.Refund{
+		ID:                "refund-" + strconv.Itoa(n),
+		OrderID:           orderID,
+		PaymentID:         "payment-1",
+		Amount:            entity.Money{Amount: 250, Currency: "USD"},
+		Reason:            "damaged item",
+		Status:            entity.RefundStatusCompleted,
+		ProviderReference: "refund-ref-" + strconv.Itoa(n),
+		CreatedAt:         createdAt.UTC().Truncate(time.Second),
+	}
+}
+
+// DescribeRefundRepository declares the specs every RefundRepository must pass.
+// newRepository is called before each spec and must return an empty repository.
+func DescribeRefundRepository(newRepository func() repository.RefundRepository) {
+	var (
+		ctx        context.Context
+		refundRepo repository.RefundRepository
+		now        time.Time
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		refundRepo = newRepository()
+		now = time.Now().UTC().Truncate(time.Second)
+	})
+
+	It("should list the refunds of an order oldest first", func() {
+		Expect(refundRepo.Save(ctx, NewRefund(2, 1, now.Add(time.Second)))).To(Succeed())
+		Expect(refundRepo.Save(ctx, NewRefund(1, 1, now))).To(Succeed())
+		Expect(refundRepo.Save(ctx, NewRefund(3, 2, now))).To(Succeed())
+
+		refunds, err := refundRepo.ListByOrderID(ctx, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds).To(HaveLen(2))
+		Expect(refunds[0].ID).To(Equal("refund-1"))
+		Expect(refunds[1].ID).To(Equal("refund-2"))
+	})
+
+	It("should return every field of the saved refund", func() {
+		saved := NewRefund(1, 1, now)
+		Expect(refundRepo.Save(ctx, saved)).To(Succeed())
+
+		refunds, err := refundRepo.ListByOrderID(ctx, 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds).To(HaveLen(1))
+		refund := refunds[0]
+		Expect(refund.ID).To(Equal(saved.ID))
+		Expect(refund.OrderID).To(Equal(1))
+		Expect(refund.PaymentID).To(Equal("payment-1"))
+		Expect(refund.Amount).To(Equal(saved.Amount))
+		Expect(refund.Reason).To(Equal("damaged item"))
+		Expect(refund.Status).To(Equal(entity.RefundStatusCompleted))
+		Expect(refund.ProviderReference).To(Equal("refund-ref-1"))
+		Expect(refund.CreatedAt).To(BeTemporally("==", now))
+	})
+
+	It("should update the status and provider reference of a refund", func() {
+		refund := NewRefund(1, 1, now)
+		refund.Status = entity.RefundStatusPending
+		refund.ProviderReference = ""
+		Expect(refundRepo.Save(ctx, refund)).To(Succeed())
+
+		refund.Complete("refund-ref-1")
+		Expect(refundRepo.Update(ctx, refund)).To(Succeed())
+
+		refunds, err := refundRepo.ListByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds).To(HaveLen(1))
+		Expect(refunds[0].Status).To(Equal(entity.RefundStatusCompleted))
+		Expect(refunds[0].ProviderReference).To(Equal("refund-ref-1"))
+		Expect(refunds[0].Amount).To(Equal(refund.Amount))
+	})
+
+	It("should return domain.ErrRefundNotFound when updating a missing refund", func() {
+		Expect(refundRepo.Update(ctx, NewRefund(1, 1, now))).To(MatchError(domain.ErrRefundNotFound))
+	})
+
+	It("should return no refunds for an order without any", func() {
+		refunds, err := refundRepo.ListByOrderID(ctx, 404)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds).To(BeEmpty())
+	})
+
+	It("should return domain.ErrConflict for a duplicate ID", func() {
+		Expect(refundRepo.Save(ctx, NewRefund(1, 1, now))).To(Succeed())
+
+		Expect(refundRepo.Save(ctx, NewRefund(1, 2, now))).To(MatchError(domain.ErrConflict))
+	})
+
+	It("should not let callers change the stored refund", func() {
+		refund := NewRefund(1, 1, now)
+		Expect(refundRepo.Save(ctx, refund)).To(Succeed())
+		refund.Amount.Amount = 1
+
+		refunds, err := refundRepo.ListByOrderID(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(refunds[0].Amount.Amount).To(Equal(int64(250)))
+	})
+}
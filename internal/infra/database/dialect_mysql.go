@@ -0,0 +1,19 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlErrDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlErrDuplicateEntry = 1062
+
+// mysqlDialect is the dialect of MySQL.
+var mysqlDialect = sqlDialect{bind: questionMark, isUniqueViolation: isDuplicateEntry}
+
+// isDuplicateEntry reports whether err is a MySQL unique key violation.
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// postgresUniqueViolation is the PostgreSQL SQLSTATE for a unique key violation.
+const postgresUniqueViolation = "23505"
+
+// postgresDialect is the dialect of PostgreSQL, which has no LastInsertId.
+var postgresDialect = sqlDialect{bind: dollarNumber, isUniqueViolation: isUniqueViolation, returning: true}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique key violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == postgresUniqueViolation
+}
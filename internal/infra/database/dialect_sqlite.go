@@ -0,0 +1,21 @@
+package database
+
+import (
+	"errors"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteDialect is the dialect of SQLite.
+var sqliteDialect = sqlDialect{bind: questionMark, isUniqueViolation: isConstraintUnique}
+
+// isConstraintUnique reports whether err is a SQLite unique or primary key violation.
+func isConstraintUnique(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code()
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
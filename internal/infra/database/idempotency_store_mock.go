@@ -0,0 +1,81 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"sync"
+	"time"
+)
+
+// IdempotencyStoreMock is an in-memory implementation of the IdempotencyStore interface.
+type IdempotencyStoreMock struct {
+	mu      sync.Mutex
+	records map[string]*repository.IdempotencyRecord
+}
+
+// NewIdempotencyStoreMock creates a new IdempotencyStoreMock.
+func NewIdempotencyStoreMock() *IdempotencyStoreMock {
+	return &IdempotencyStoreMock{
+		records: make(map[string]*repository.IdempotencyRecord),
+	}
+}
+
+// Get retrieves the live record for a key from memory.
+func (s *IdempotencyStoreMock) Get(ctx context.Context, key string) (*repository.IdempotencyRecord, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if !ok || !record.ExpiresAt.After(time.Now()) {
+		return nil, repository.ErrIdempotencyRecordNotFound
+	}
+	found := *record
+	return &found, nil
+}
+
+// Save stores a record in memory, replacing an expired record for the same key.
+func (s *IdempotencyStoreMock) Save(ctx context.Context, record *repository.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[record.Key]; ok && existing.ExpiresAt.After(time.Now()) {
+		return domain.ErrConflict
+	}
+	stored := *record
+	s.records[record.Key] = &stored
+	return nil
+}
+
+// Complete stores the response of a pending record in memory.
+func (s *IdempotencyStoreMock) Complete(ctx context.Context, record *repository.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.records[record.Key]
+	if !ok || !existing.Pending() || !existing.ExpiresAt.After(time.Now()) {
+		return repository.ErrIdempotencyRecordNotFound
+	}
+	stored := *record
+	s.records[record.Key] = &stored
+	return nil
+}
+
+// Release removes the pending record for a key from memory.
+func (s *IdempotencyStoreMock) Release(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.records[key]; ok && existing.Pending() {
+		delete(s.records, key)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// idempotencyColumns lists the idempotency_keys columns in the order Get reads them.
+const idempotencyColumns = "idempotency_key, request_hash, status_code, content_type, response_body, created_at, expires_at"
+
+// IdempotencyStoreSQL implements the IdempotencyStore interface for MySQL,
+// PostgreSQL and SQLite. Like OrderRepositorySQL it writes every time in UTC.
+type IdempotencyStoreSQL struct {
+	DB      *sql.DB
+	dialect sqlDialect
+}
+
+// NewIdempotencyStoreSQL creates a new idempotency store for a database
+// opened with Open for driver.
+func NewIdempotencyStoreSQL(db *sql.DB, driver string) *IdempotencyStoreSQL {
+	return &IdempotencyStoreSQL{DB: db, dialect: sqlDialectFor(driver)}
+}
+
+// Get retrieves the live record for a key from the database.
+func (s *IdempotencyStoreSQL) Get(ctx context.Context, key string) (*repository.IdempotencyRecord, error) {
+	row := s.DB.QueryRowContext(ctx, s.dialect.rebind("SELECT "+idempotencyColumns+" FROM idempotency_keys WHERE idempotency_key = ? AND expires_at > ?"), key, time.Now().UTC())
+
+	var record repository.IdempotencyRecord
+	err := row.Scan(&record.Key, &record.RequestHash, &record.StatusCode, &record.ContentType, &record.Body, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, repository.ErrIdempotencyRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// Save stores a record in the database, replacing an expired record for the
+// same key. The expired record is deleted first and the insert fails on the
+// primary key when a live record remains, so of two requests that reserve a
+// key at the same time only one succeeds.
+func (s *IdempotencyStoreSQL) Save(ctx context.Context, record *repository.IdempotencyRecord) error {
+	if _, err := s.DB.ExecContext(ctx, s.dialect.rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND expires_at <= ?"), record.Key, time.Now().UTC()); err != nil {
+		return err
+	}
+
+	_, err := s.DB.ExecContext(ctx, s.dialect.rebind("INSERT INTO idempotency_keys ("+idempotencyColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)"), record.Key, record.RequestHash, record.StatusCode, record.ContentType, record.Body, record.CreatedAt.UTC(), record.ExpiresAt.UTC())
+	if s.dialect.isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// Complete stores the response of a pending record in the database.
+func (s *IdempotencyStoreSQL) Complete(ctx context.Context, record *repository.IdempotencyRecord) error {
+	result, err := s.DB.ExecContext(ctx, s.dialect.rebind("UPDATE idempotency_keys SET status_code = ?, content_type = ?, response_body = ?, expires_at = ? WHERE idempotency_key = ? AND status_code = 0 AND expires_at > ?"), record.StatusCode, record.ContentType, record.Body, record.ExpiresAt.UTC(), record.Key, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrIdempotencyRecordNotFound
+	}
+	return nil
+}
+
+// Release removes the pending record for a key from the database.
+func (s *IdempotencyStoreSQL) Release(ctx context.Context, key string) error {
+	_, err := s.DB.ExecContext(ctx, s.dialect.rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND status_code = 0"), key)
+	return err
+}
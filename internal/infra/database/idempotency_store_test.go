@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/domain/repository/repositorytest"
+	"GoCleanArch/internal/infra/database"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("IdempotencyStoreMock", func() {
+	repositorytest.DescribeIdempotencyStore(func() repository.IdempotencyStore {
+		return database.NewIdempotencyStoreMock()
+	})
+})
+
+var _ = Describe("IdempotencyStoreSQL on MySQL", func() {
+	describeSQLIdempotencyStore(database.DriverMySQL, envDSN("MYSQL_TEST_DSN"))
+})
+
+var _ = Describe("IdempotencyStoreSQL on PostgreSQL", func() {
+	describeSQLIdempotencyStore(database.DriverPostgres, envDSN("POSTGRES_TEST_DSN"))
+})
+
+var _ = Describe("IdempotencyStoreSQL on SQLite", func() {
+	describeSQLIdempotencyStore(database.DriverSQLite, sqliteDSN)
+})
+
+// describeSQLIdempotencyStore runs the idempotency store contract against a
+// migrated, emptied database for each spec.
+func describeSQLIdempotencyStore(driver string, dsn func() string) {
+	var db *sql.DB
+
+	BeforeEach(func() {
+		db = openTestDB(driver, dsn)
+	})
+
+	repositorytest.DescribeIdempotencyStore(func() repository.IdempotencyStore {
+		return database.NewIdempotencyStoreSQL(db, driver)
+	})
+}
@@ -0,0 +1,89 @@
+package migration_test
+
+import (
+	"GoCleanArch/internal/infra/database/migration"
+	"testing/fstest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrations", func() {
+	Describe("Load", func() {
+		It("should pair up and down files and sort them by version", func() {
+			fsys := fstest.MapFS{
+				"0002_add_index.up.sql":       {Data: []byte("CREATE INDEX idx ON things (name);")},
+				"0001_create_things.up.sql":   {Data: []byte("CREATE TABLE things (name TEXT);")},
+				"0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+			}
+
+			migrations, err := migration.Load(fsys)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrations).To(HaveLen(2))
+			Expect(migrations[0].Version).To(Equal(int64(1)))
+			Expect(migrations[0].Name).To(Equal("create_things"))
+			Expect(migrations[0].Down).To(Equal("DROP TABLE things;"))
+			Expect(migrations[0].Checksum).To(HaveLen(64))
+			Expect(migrations[1].Version).To(Equal(int64(2)))
+			Expect(migrations[1].Down).To(BeEmpty())
+		})
+
+		It("should reject a migration without an up file", func() {
+			fsys := fstest.MapFS{
+				"0001_create_things.down.sql": {Data: []byte("DROP TABLE things;")},
+			}
+
+			_, err := migration.Load(fsys)
+
+			Expect(err).To(MatchError(ContainSubstring("no up file")))
+		})
+
+		It("should reject two migrations with the same version", func() {
+			fsys := fstest.MapFS{
+				"0001_create_things.up.sql": {Data: []byte("CREATE TABLE things (name TEXT);")},
+				"0001_create_others.up.sql": {Data: []byte("CREATE TABLE others (name TEXT);")},
+			}
+
+			_, err := migration.Load(fsys)
+
+			Expect(err).To(MatchError(ContainSubstring("is used by both")))
+		})
+
+		It("should reject badly named files", func() {
+			fsys := fstest.MapFS{
+				"create_things.sql": {Data: []byte("CREATE TABLE things (name TEXT);")},
+			}
+
+			_, err := migration.Load(fsys)
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Migrations", func() {
+		It("should embed the MySQL migrations", func() {
+			migrations, err := migration.Migrations("mysql")
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrations).NotTo(BeEmpty())
+			for _, m := range migrations {
+				Expect(m.Down).NotTo(BeEmpty(), "migration %d_%s has no down file", m.Version, m.Name)
+			}
+		})
+
+		It("should reject unknown drivers", func() {
+			_, err := migration.Migrations("oracle")
+
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Statements", func() {
+		It("should split statements that end a line with a semicolon", func() {
+			statements := migration.Statements("CREATE TABLE a (x TEXT);\nINSERT INTO a VALUES ('b;c');\n\n")
+
+			Expect(statements).To(Equal([]string{"CREATE TABLE a (x TEXT)", "INSERT INTO a VALUES ('b;c')"}))
+		})
+	})
+})
@@ -0,0 +1,301 @@
+package migration
+
+import (
+	"GoCleanArch/internal/infra/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrDirty is returned when a migration failed part way through. The schema
+	// has to be repaired by hand and the version recorded with Force.
+	ErrDirty = errors.New("database schema is dirty")
+	// ErrChecksumMismatch is returned when an applied migration has been edited since it ran.
+	ErrChecksumMismatch = errors.New("applied migration has changed")
+	// ErrSchemaBehind is returned by Verify when migrations are pending.
+	ErrSchemaBehind = errors.New("database schema is behind")
+)
+
+// State is the state of a migration in the database.
+type State string
+
+const (
+	// StatePending means the migration has not been applied.
+	StatePending State = "pending"
+	// StateApplied means the migration has been applied.
+	StateApplied State = "applied"
+	// StateDirty means the migration failed part way through.
+	StateDirty State = "dirty"
+	// StateModified means the migration has been edited since it was applied.
+	StateModified State = "modified"
+	// StateMissing means the migration was applied but is no longer embedded.
+	StateMissing State = "missing"
+)
+
+// MigrationStatus describes one migration as recorded in the database.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt *time.Time
+}
+
+// appliedMigration is a row of the schema_migrations table.
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations and records them in the schema_migrations table.
+// Driver is the database driver name from the configuration, which decides
+// the placeholder style of the bookkeeping statements.
+type Migrator struct {
+	DB         *sql.DB
+	Driver     string
+	Migrations []Migration
+}
+
+// NewMigrator creates a new Migrator.
+func NewMigrator(db *sql.DB, driver string, migrations []Migration) *Migrator {
+	return &Migrator{DB: db, Driver: driver, Migrations: migrations}
+}
+
+// Up applies every pending migration in version order and returns how many it applied.
+// It refuses to run when the schema is dirty or an applied migration has changed.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.check(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := m.run(ctx, migration, migration.Up, true); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Down rolls back the n most recently applied migrations and returns how many it rolled back.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.check(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(m.Migrations) - 1; i >= 0 && count < n; i-- {
+		migration := m.Migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+		if err := m.run(ctx, migration, migration.Down, false); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Status reports every embedded migration, and every applied migration that
+// is no longer embedded, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[int64]bool)
+	for _, migration := range m.Migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, State: StatePending}
+		if row, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &row.AppliedAt
+			switch {
+			case row.Dirty:
+				status.State = StateDirty
+			case row.Checksum != migration.Checksum:
+				status.State = StateModified
+			default:
+				status.State = StateApplied
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	for version, row := range applied {
+		if !known[version] {
+			statuses = append(statuses, MigrationStatus{Version: version, Name: row.Name, State: StateMissing, AppliedAt: &row.AppliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Verify returns ErrSchemaBehind when migrations are pending, and ErrDirty or
+// ErrChecksumMismatch when the recorded schema cannot be trusted.
+func (m *Migrator) Verify(ctx context.Context) error {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+	if err := m.check(applied); err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d pending migrations", ErrSchemaBehind, pending)
+	}
+	return nil
+}
+
+// Force records the schema as being exactly at version without running any
+// SQL, clearing the dirty flag. It is used to recover from a failed migration
+// once the schema has been repaired by hand. Version 0 records no migrations.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && !m.has(version) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version > ?"), version); err != nil {
+		return err
+	}
+	for _, migration := range m.Migrations {
+		if migration.Version > version {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			if _, err := tx.ExecContext(ctx, m.bind("UPDATE schema_migrations SET checksum = ?, dirty = ? WHERE version = ?"), migration.Checksum, false, migration.Version); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)"), migration.Version, migration.Name, migration.Checksum, false, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// run executes the statements of one migration. The migration is recorded as
+// dirty before it runs, because DDL is not transactional in every database,
+// and the record is settled once every statement has succeeded.
+func (m *Migrator) run(ctx context.Context, migration Migration, script string, up bool) error {
+	if up {
+		if _, err := m.DB.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at) VALUES (?, ?, ?, ?, ?)"), migration.Version, migration.Name, migration.Checksum, true, time.Now().UTC()); err != nil {
+			return err
+		}
+	} else {
+		if _, err := m.DB.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), true, migration.Version); err != nil {
+			return err
+		}
+	}
+
+	for _, statement := range Statements(script) {
+		if _, err := m.DB.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if up {
+		_, err := m.DB.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, migration.Version)
+		return err
+	}
+	_, err := m.DB.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version)
+	return err
+}
+
+// check returns an error when the applied migrations cannot be trusted.
+func (m *Migrator) check(applied map[int64]appliedMigration) error {
+	for version, row := range applied {
+		if row.Dirty {
+			return fmt.Errorf("%w: migration %d_%s failed; repair the schema and run force", ErrDirty, version, row.Name)
+		}
+	}
+	for _, migration := range m.Migrations {
+		if row, ok := applied[migration.Version]; ok && row.Checksum != migration.Checksum {
+			return fmt.Errorf("%w: migration %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// has reports whether version is one of the migrations.
+func (m *Migrator) has(version int64) bool {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// applied creates the schema_migrations table if needed and reads it.
+func (m *Migrator) applied(ctx context.Context) (map[int64]appliedMigration, error) {
+	if _, err := m.DB.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, checksum CHAR(64) NOT NULL, dirty BOOLEAN NOT NULL, applied_at TIMESTAMP NOT NULL)"); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, "SELECT version, name, checksum, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]appliedMigration)
+	for rows.Next() {
+		var row appliedMigration
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.Dirty, &row.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[row.Version] = row
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// bind rewrites the ? placeholders of a bookkeeping statement for the driver.
+func (m *Migrator) bind(query string) string {
+	return database.Rebind(m.Driver, query)
+}
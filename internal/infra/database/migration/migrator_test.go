@@ -0,0 +1,88 @@
+package migration_test
+
+import (
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/database/migration"
+	"context"
+	"database/sql"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrator", func() {
+	var (
+		db         *sql.DB
+		migrations []migration.Migration
+		migrator   *migration.Migrator
+	)
+
+	BeforeEach(func() {
+		var err error
+		db, err = database.Open(database.DriverSQLite, "file:"+filepath.Join(GinkgoT().TempDir(), "orders.db"))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(db.Close)
+
+		migrations, err = migration.Migrations(database.DriverSQLite)
+		Expect(err).NotTo(HaveOccurred())
+		migrator = migration.NewMigrator(db, database.DriverSQLite, migrations)
+	})
+
+	It("should apply every pending migration once", func() {
+		applied, err := migrator.Up(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(len(migrations)))
+		Expect(migrator.Verify(context.Background())).To(Succeed())
+
+		applied, err = migrator.Up(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(applied).To(Equal(0))
+
+		_, err = db.Exec("SELECT COUNT(*) FROM orders")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should roll back the most recent migrations", func() {
+		_, err := migrator.Up(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+
+		rolledBack, err := migrator.Down(context.Background(), 1)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rolledBack).To(Equal(1))
+		Expect(migrator.Verify(context.Background())).To(MatchError(migration.ErrSchemaBehind))
+
+		statuses, err := migrator.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(len(migrations)))
+		Expect(statuses[0].State).To(Equal(migration.StateApplied))
+		Expect(statuses[len(statuses)-1].State).To(Equal(migration.StatePending))
+	})
+
+	It("should refuse to run when an applied migration has changed", func() {
+		_, err := migrator.Up(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		migrator.Migrations[0].Checksum = "edited"
+
+		_, err = migrator.Up(context.Background())
+		Expect(err).To(MatchError(migration.ErrChecksumMismatch))
+
+		statuses, err := migrator.Status(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses[0].State).To(Equal(migration.StateModified))
+	})
+
+	It("should leave a failed migration dirty until it is forced", func() {
+		migrator.Migrations = append(migrator.Migrations, migration.Migration{Version: 99, Name: "broken", Up: "CREATE TABLE orders (id TEXT);", Checksum: "broken"})
+
+		_, err := migrator.Up(context.Background())
+		Expect(err).To(HaveOccurred())
+		_, err = migrator.Up(context.Background())
+		Expect(err).To(MatchError(migration.ErrDirty))
+
+		Expect(migrator.Force(context.Background(), migrations[len(migrations)-1].Version)).To(Succeed())
+		migrator.Migrations = migrations
+		Expect(migrator.Verify(context.Background())).To(Succeed())
+	})
+})
@@ -0,0 +1,12 @@
+CREATE TABLE orders (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    data TEXT,
+    order_id INT,
+    status VARCHAR(255),
+    paid BOOLEAN,
+    created_at TIMESTAMP,
+    updated_at TIMESTAMP,
+    INDEX idx_orders_created_at (created_at, order_id),
+    INDEX idx_orders_updated_at (updated_at, order_id),
+    INDEX idx_orders_order_id (order_id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE idempotency_keys (
+    idempotency_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    request_hash CHAR(64) NOT NULL,
+    status_code INT NOT NULL,
+    content_type VARCHAR(255) NOT NULL,
+    response_body MEDIUMBLOB NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,11 @@
+CREATE TABLE order_outbox (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    order_id INT NOT NULL,
+    payload MEDIUMBLOB NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL,
+    next_attempt_at TIMESTAMP NOT NULL,
+    sent_at TIMESTAMP NULL,
+    INDEX idx_order_outbox_pending (sent_at, next_attempt_at)
+);
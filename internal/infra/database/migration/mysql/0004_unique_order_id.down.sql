@@ -0,0 +1 @@
+ALTER TABLE orders DROP INDEX uq_orders_order_id, ADD INDEX idx_orders_order_id (order_id);
@@ -0,0 +1 @@
+ALTER TABLE orders DROP INDEX idx_orders_order_id, ADD UNIQUE INDEX uq_orders_order_id (order_id);
@@ -0,0 +1,7 @@
+DROP TABLE order_items;
+ALTER TABLE orders
+    DROP COLUMN tax_rate,
+    DROP COLUMN currency,
+    DROP COLUMN subtotal,
+    DROP COLUMN tax,
+    DROP COLUMN total;
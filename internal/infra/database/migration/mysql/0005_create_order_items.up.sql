@@ -0,0 +1,17 @@
+ALTER TABLE orders
+    ADD COLUMN tax_rate INT NOT NULL DEFAULT 0,
+    ADD COLUMN currency CHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN subtotal BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN tax BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN total BIGINT NOT NULL DEFAULT 0;
+CREATE TABLE order_items (
+    order_id INT NOT NULL,
+    position INT NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    quantity INT NOT NULL,
+    unit_price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    PRIMARY KEY (order_id, position),
+    CONSTRAINT fk_order_items_order FOREIGN KEY (order_id) REFERENCES orders (order_id) ON DELETE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE order_outbox
+    DROP INDEX idx_order_outbox_locked_by,
+    DROP COLUMN locked_until,
+    DROP COLUMN locked_by;
@@ -0,0 +1,4 @@
+ALTER TABLE order_outbox
+    ADD COLUMN locked_by CHAR(36) NULL,
+    ADD COLUMN locked_until TIMESTAMP NULL,
+    ADD INDEX idx_order_outbox_locked_by (locked_by);
@@ -0,0 +1 @@
+ALTER TABLE refunds ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'Completed';
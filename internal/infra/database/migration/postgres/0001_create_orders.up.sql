@@ -0,0 +1,12 @@
+CREATE TABLE orders (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    data TEXT,
+    order_id INTEGER,
+    status VARCHAR(255),
+    paid BOOLEAN,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX idx_orders_created_at ON orders (created_at, order_id);
+CREATE INDEX idx_orders_updated_at ON orders (updated_at, order_id);
+CREATE INDEX idx_orders_order_id ON orders (order_id);
@@ -0,0 +1,2 @@
+DROP INDEX idx_orders_order_id;
+CREATE UNIQUE INDEX uq_orders_order_id ON orders (order_id);
@@ -0,0 +1,13 @@
+CREATE TABLE payments (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    provider_reference VARCHAR(255) NOT NULL DEFAULT '',
+    decline_reason VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    captured_at TIMESTAMPTZ
+);
+CREATE INDEX idx_payments_order_id ON payments (order_id, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE refunds (
+    id VARCHAR(255) NOT NULL PRIMARY KEY,
+    order_id INTEGER NOT NULL,
+    payment_id VARCHAR(255) NOT NULL,
+    amount BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    provider_reference VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_refunds_order_id ON refunds (order_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX idx_order_outbox_locked_by;
+ALTER TABLE order_outbox DROP COLUMN locked_until;
+ALTER TABLE order_outbox DROP COLUMN locked_by;
@@ -0,0 +1,3 @@
+ALTER TABLE order_outbox ADD COLUMN locked_by CHAR(36);
+ALTER TABLE order_outbox ADD COLUMN locked_until TIMESTAMPTZ;
+CREATE INDEX idx_order_outbox_locked_by ON order_outbox (locked_by);
@@ -0,0 +1,2 @@
+DROP INDEX uq_orders_order_id;
+CREATE INDEX idx_orders_order_id ON orders (order_id);
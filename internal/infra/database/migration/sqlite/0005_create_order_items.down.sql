@@ -0,0 +1,6 @@
+DROP TABLE order_items;
+ALTER TABLE orders DROP COLUMN total;
+ALTER TABLE orders DROP COLUMN tax;
+ALTER TABLE orders DROP COLUMN subtotal;
+ALTER TABLE orders DROP COLUMN currency;
+ALTER TABLE orders DROP COLUMN tax_rate;
@@ -0,0 +1,15 @@
+ALTER TABLE orders ADD COLUMN tax_rate INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN currency CHAR(3) NOT NULL DEFAULT '';
+ALTER TABLE orders ADD COLUMN subtotal BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN tax BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN total BIGINT NOT NULL DEFAULT 0;
+CREATE TABLE order_items (
+    order_id INTEGER NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    sku VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    quantity INTEGER NOT NULL,
+    unit_price BIGINT NOT NULL,
+    currency CHAR(3) NOT NULL,
+    PRIMARY KEY (order_id, position)
+);
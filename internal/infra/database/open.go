@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Supported values of the db.driver setting.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// sqlDriverNames maps each supported driver to the name its database/sql driver registers.
+var sqlDriverNames = map[string]string{
+	DriverMySQL:    "mysql",
+	DriverPostgres: "pgx",
+	DriverSQLite:   "sqlite",
+}
+
+// Open opens a database handle for one of the supported drivers.
+func Open(driver, dsn string) (*sql.DB, error) {
+	name, ok := sqlDriverNames[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database driver %q", driver)
+	}
+	switch driver {
+	case DriverMySQL:
+		var err error
+		if dsn, err = mysqlDSN(dsn); err != nil {
+			return nil, err
+		}
+	case DriverSQLite:
+		dsn = sqliteDSN(dsn)
+	}
+
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == DriverSQLite {
+		// SQLite allows one writer at a time; a single connection queues
+		// writers in database/sql instead of failing them with SQLITE_BUSY.
+		db.SetMaxOpenConns(1)
+	}
+	return db, nil
+}
+
+// mysqlDSN makes MySQL report the rows an UPDATE matched rather than the rows
+// it changed, so that updating an order to the values it already has is not
+// mistaken for updating an order that doesn't exist.
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	cfg.ClientFoundRows = true
+	return cfg.FormatDSN(), nil
+}
+
+// sqliteDSN adds the settings the SQLite adapters rely on to a DSN such as
+// "file:orders.db". Times are written in a fixed, sortable format, so that
+// keyset pagination can compare them as text, and foreign keys are enforced so
+// that deleting an order deletes its items.
+func sqliteDSN(dsn string) string {
+	params := url.Values{}
+	if !strings.Contains(dsn, "_time_format=") {
+		params.Set("_time_format", "sqlite")
+	}
+	if !strings.Contains(dsn, "busy_timeout") {
+		params.Add("_pragma", "busy_timeout(5000)")
+	}
+	if !strings.Contains(dsn, "foreign_keys") {
+		params.Add("_pragma", "foreign_keys(1)")
+	}
+	if len(params) == 0 {
+		return dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + params.Encode()
+}
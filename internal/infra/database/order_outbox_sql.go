@@ -0,0 +1,167 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// outboxColumns lists the order_outbox columns in the order scanOutboxMessages reads them.
+const outboxColumns = "id, order_id, payload, attempts, last_error, created_at, next_attempt_at"
+
+// SaveWithMessage saves an order and adds a message to the outbox in one transaction.
+// A duplicate order returns domain.ErrConflict and stores nothing.
+func (r *OrderRepositorySQL) SaveWithMessage(ctx context.Context, order *entity.Order, message *repository.OutboxMessage) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+	return r.commitWithMessages(ctx, tx, []*repository.OutboxMessage{message})
+}
+
+// UpdateWithMessages updates an order like Update and adds messages to the
+// outbox in one transaction. Nothing is stored when the update fails.
+func (r *OrderRepositorySQL) UpdateWithMessages(ctx context.Context, order *entity.Order, messages []*repository.OutboxMessage) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.updateOrder(ctx, tx, order); err != nil {
+		return err
+	}
+	if err := r.commitWithMessages(ctx, tx, messages); err != nil {
+		return err
+	}
+	order.Version++
+	return nil
+}
+
+// UpdateWithRefund updates an order and a refund and adds messages to the
+// outbox in one transaction. Nothing is stored when either update fails.
+func (r *OrderRepositorySQL) UpdateWithRefund(ctx context.Context, order *entity.Order, refund *entity.Refund, messages []*repository.OutboxMessage) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.updateOrder(ctx, tx, order); err != nil {
+		return err
+	}
+	if err := updateRefund(ctx, tx, r.dialect, refund); err != nil {
+		return err
+	}
+	if err := r.commitWithMessages(ctx, tx, messages); err != nil {
+		return err
+	}
+	order.Version++
+	return nil
+}
+
+// commitWithMessages adds messages to the outbox and commits the transaction,
+// then sets the IDs of the messages.
+func (r *OrderRepositorySQL) commitWithMessages(ctx context.Context, tx *sql.Tx, messages []*repository.OutboxMessage) error {
+	ids := make([]int64, len(messages))
+	for i, message := range messages {
+		var err error
+		ids[i], err = r.dialect.insertID(ctx, tx, "INSERT INTO order_outbox (order_id, payload, attempts, last_error, created_at, next_attempt_at) VALUES (?, ?, ?, ?, ?, ?)", message.OrderID, message.Payload, message.Attempts, message.LastError, message.CreatedAt.UTC(), message.NextAttemptAt.UTC())
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for i, message := range messages {
+		message.ID = ids[i]
+	}
+	return nil
+}
+
+// FetchPending claims the unsent outbox messages that are due and not claimed
+// by another relay, oldest first, and returns the ones it claimed. The claim
+// is an UPDATE that checks the lease again, so when two relays pick the same
+// message, only the first one to update it gets it.
+func (r *OrderRepositorySQL) FetchPending(ctx context.Context, maxAttempts, limit int) ([]*repository.OutboxMessage, error) {
+	now := time.Now().UTC()
+	rows, err := r.DB.QueryContext(ctx, r.dialect.rebind("SELECT id FROM order_outbox WHERE sent_at IS NULL AND next_attempt_at <= ? AND attempts < ? AND (locked_until IS NULL OR locked_until <= ?) ORDER BY id LIMIT ?"), now, maxAttempts, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var ids []interface{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	owner := uuid.NewString()
+	in := "(" + strings.Repeat("?, ", len(ids)-1) + "?)"
+	args := append([]interface{}{owner, now.Add(repository.OutboxLease)}, ids...)
+	args = append(args, now)
+	if _, err := r.DB.ExecContext(ctx, r.dialect.rebind("UPDATE order_outbox SET locked_by = ?, locked_until = ? WHERE id IN "+in+" AND sent_at IS NULL AND (locked_until IS NULL OR locked_until <= ?)"), args...); err != nil {
+		return nil, err
+	}
+
+	rows, err = r.DB.QueryContext(ctx, r.dialect.rebind("SELECT "+outboxColumns+" FROM order_outbox WHERE locked_by = ? ORDER BY id"), owner)
+	if err != nil {
+		return nil, err
+	}
+	return scanOutboxMessages(rows)
+}
+
+// scanOutboxMessages reads every row of a query for outboxColumns and closes the rows.
+func scanOutboxMessages(rows *sql.Rows) ([]*repository.OutboxMessage, error) {
+	defer rows.Close()
+
+	var messages []*repository.OutboxMessage
+	for rows.Next() {
+		var message repository.OutboxMessage
+		var lastError sql.NullString
+		if err := rows.Scan(&message.ID, &message.OrderID, &message.Payload, &message.Attempts, &lastError, &message.CreatedAt, &message.NextAttemptAt); err != nil {
+			return nil, err
+		}
+		message.LastError = lastError.String
+		messages = append(messages, &message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+// MarkSent records that an outbox message was published and releases it.
+func (r *OrderRepositorySQL) MarkSent(ctx context.Context, id int64, sentAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, r.dialect.rebind("UPDATE order_outbox SET sent_at = ?, locked_by = NULL, locked_until = NULL WHERE id = ?"), sentAt.UTC(), id)
+	return err
+}
+
+// MarkFailed records a failed attempt to publish an outbox message and releases it.
+func (r *OrderRepositorySQL) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := r.DB.ExecContext(ctx, r.dialect.rebind("UPDATE order_outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ?, locked_by = NULL, locked_until = NULL WHERE id = ?"), lastError, nextAttemptAt.UTC(), id)
+	return err
+}
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// placeholder returns the bind parameter for the n-th argument of a statement, counting from 1.
+type placeholder func(n int) string
+
+// questionMark is the placeholder style of MySQL and SQLite.
+func questionMark(int) string { return "?" }
+
+// dollarNumber is the placeholder style of PostgreSQL.
+func dollarNumber(n int) string { return "$" + strconv.Itoa(n) }
+
+// orderColumns lists the orders columns in the order scanOrder reads them.
+const orderColumns = "id, data, order_id, status, paid, tax_rate, currency, subtotal, tax, total, created_at, updated_at, version"
+
+// orderSortColumns maps each sort field to the column it sorts by.
+var orderSortColumns = map[repository.OrderSortField]string{
+	repository.OrderSortByCreatedAt: "created_at",
+	repository.OrderSortByUpdatedAt: "updated_at",
+	repository.OrderSortByOrderID:   "order_id",
+}
+
+// findOrdersStatement builds the statement that reads one page of the orders
+// matching a normalized query. Pages are read with keyset pagination on
+// (sort column, order_id), so every page costs the same however deep into the
+// listing it is. One row more than the page size is read so that the caller
+// can tell whether there is a next page.
+func findOrdersStatement(query repository.OrderQuery, cursor *repository.OrderCursor, bind placeholder) (string, []interface{}) {
+	column := orderSortColumns[query.SortBy]
+	direction, comparison := "ASC", ">"
+	if query.SortDirection == repository.SortDescending {
+		direction, comparison = "DESC", "<"
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return bind(len(args))
+	}
+	if query.Status != "" {
+		conditions = append(conditions, "status = "+arg(query.Status))
+	}
+	if query.Paid != nil {
+		conditions = append(conditions, "paid = "+arg(*query.Paid))
+	}
+	if !query.CreatedFrom.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(query.CreatedFrom))
+	}
+	if !query.CreatedTo.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(query.CreatedTo))
+	}
+	if cursor != nil {
+		if query.SortBy == repository.OrderSortByOrderID {
+			conditions = append(conditions, "order_id "+comparison+" "+arg(cursor.OrderID))
+		} else {
+			conditions = append(conditions, "("+column+" "+comparison+" "+arg(cursor.Time)+" OR ("+column+" = "+arg(cursor.Time)+" AND order_id "+comparison+" "+arg(cursor.OrderID)+"))")
+		}
+	}
+
+	statement := "SELECT " + orderColumns + " FROM orders"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY " + column + " " + direction
+	if query.SortBy != repository.OrderSortByOrderID {
+		statement += ", order_id " + direction
+	}
+	statement += " LIMIT " + arg(query.Limit+1)
+	return statement, args
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// scanOrder reads the orderColumns of one row. The totals of an order without
+// items are left as zero Money.
+func scanOrder(row rowScanner) (*entity.Order, error) {
+	var order entity.Order
+	var currency string
+	var subtotal, tax, total int64
+	if err := row.Scan(&order.ID, &order.Data, &order.OrderID, &order.Status, &order.Paid, &order.TaxRate, &currency, &subtotal, &tax, &total, &order.CreatedAt, &order.UpdatedAt, &order.Version); err != nil {
+		return nil, err
+	}
+	if currency != "" {
+		order.Subtotal = entity.Money{Amount: subtotal, Currency: currency}
+		order.Tax = entity.Money{Amount: tax, Currency: currency}
+		order.Total = entity.Money{Amount: total, Currency: currency}
+	}
+	return &order, nil
+}
+
+// scanOrders reads every row of a query for orderColumns and closes the rows.
+func scanOrders(rows *sql.Rows) ([]*entity.Order, error) {
+	defer rows.Close()
+
+	var orders []*entity.Order
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// insertOrderItems inserts the items of an order, keeping their order by position.
+func insertOrderItems(ctx context.Context, db execer, order *entity.Order, bind placeholder) error {
+	statement := "INSERT INTO order_items (order_id, position, sku, description, quantity, unit_price, currency) VALUES (" + bind(1) + ", " + bind(2) + ", " + bind(3) + ", " + bind(4) + ", " + bind(5) + ", " + bind(6) + ", " + bind(7) + ")"
+	for position, item := range order.Items {
+		if _, err := db.ExecContext(ctx, statement, order.OrderID, position, item.SKU, item.Description, item.Quantity, item.UnitPrice.Amount, item.UnitPrice.Currency); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadOrderItems reads the items of every order with one query and attaches
+// them to the orders.
+func loadOrderItems(ctx context.Context, db queryer, orders []*entity.Order, bind placeholder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byOrderID := make(map[int]*entity.Order, len(orders))
+	placeholders := make([]string, 0, len(orders))
+	args := make([]interface{}, 0, len(orders))
+	for _, order := range orders {
+		byOrderID[order.OrderID] = order
+		args = append(args, order.OrderID)
+		placeholders = append(placeholders, bind(len(args)))
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT order_id, sku, description, quantity, unit_price, currency FROM order_items WHERE order_id IN ("+strings.Join(placeholders, ", ")+") ORDER BY order_id, position", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID int
+		var item entity.OrderItem
+		if err := rows.Scan(&orderID, &item.SKU, &item.Description, &item.Quantity, &item.UnitPrice.Amount, &item.UnitPrice.Currency); err != nil {
+			return err
+		}
+		order := byOrderID[orderID]
+		order.Items = append(order.Items, item)
+	}
+	return rows.Err()
+}
@@ -1,50 +1,371 @@
 package database
 
 import (
+	"GoCleanArch/internal/domain"
 	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"cmp"
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"sync"
+	"time"
 )
 
-// OrderRepositoryMock is a mock implementation of the OrderRepository interface.
+// OrderRepositoryMock is a mock implementation of the OrderRepository and
+// OrderOutbox interfaces. It stores copies of orders and outbox messages so
+// callers can't change stored data by accident. UpdateWithRefund stores
+// refunds in Refunds, which must be set to use it.
 type OrderRepositoryMock struct {
-	mu     sync.Mutex
-	orders map[int]*entity.Order
+	Refunds *RefundRepositoryMock
+
+	mu           sync.Mutex
+	orders       map[int]*entity.Order
+	orderIDs     map[string]int
+	outbox       []*repository.OutboxMessage
+	nextOutboxID int64
+	lockedUntil  map[int64]time.Time
 }
 
 // NewOrderRepositoryMock creates a new OrderRepositoryMock.
 func NewOrderRepositoryMock() *OrderRepositoryMock {
 	return &OrderRepositoryMock{
-		orders: make(map[int]*entity.Order),
+		orders:      make(map[int]*entity.Order),
+		orderIDs:    make(map[string]int),
+		lockedUntil: make(map[int64]time.Time),
 	}
 }
 
-// Save saves an order to the mock database.
-func (r *OrderRepositoryMock) Save(order *entity.Order) error {
+// Save saves an order to the mock database. Saving an ID or an OrderID twice returns domain.ErrConflict.
+func (r *OrderRepositoryMock) Save(ctx context.Context, order *entity.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.orders[order.OrderID] = order
+	return r.save(order)
+}
+
+// save stores a copy of an order. The caller must hold r.mu.
+func (r *OrderRepositoryMock) save(order *entity.Order) error {
+	if _, ok := r.orders[order.OrderID]; ok {
+		return domain.ErrConflict
+	}
+	if _, ok := r.orderIDs[order.ID]; ok {
+		return domain.ErrConflict
+	}
+	r.orders[order.OrderID] = copyOrder(order)
+	r.orderIDs[order.ID] = order.OrderID
 	return nil
 }
 
+// copyOrder returns a copy of an order that shares no items with it.
+func copyOrder(order *entity.Order) *entity.Order {
+	copied := *order
+	if order.Items != nil {
+		copied.Items = append([]entity.OrderItem(nil), order.Items...)
+	}
+	return &copied
+}
+
+// GetByID retrieves an order by its internal ID from the mock database.
+func (r *OrderRepositoryMock) GetByID(ctx context.Context, id string) (*entity.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	orderID, ok := r.orderIDs[id]
+	if !ok {
+		return nil, domain.ErrOrderNotFound
+	}
+	return copyOrder(r.orders[orderID]), nil
+}
+
 // GetByOrderID retrieves an order by its ID from the mock database.
-func (r *OrderRepositoryMock) GetByOrderID(orderID int) (*entity.Order, error) {
+func (r *OrderRepositoryMock) GetByOrderID(ctx context.Context, orderID int) (*entity.Order, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	order, ok := r.orders[orderID]
 	if !ok {
-		return nil, errors.New("order not found")
+		return nil, domain.ErrOrderNotFound
 	}
-	return order, nil
+	return copyOrder(order), nil
 }
 
-func (r *OrderRepositoryMock) GetAll() ([]*entity.Order, error) {
+// Find retrieves one page of the orders matching a query from the mock database.
+func (r *OrderRepositoryMock) Find(ctx context.Context, query repository.OrderQuery) (*repository.OrderPage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := query.DecodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	var after *entity.Order
+	if cursor != nil {
+		after = &entity.Order{OrderID: cursor.OrderID, CreatedAt: cursor.Time, UpdatedAt: cursor.Time}
+	}
+
 	orders := make([]*entity.Order, 0, len(r.orders))
 	for _, order := range r.orders {
-		orders = append(orders, order)
+		if !matchesOrderQuery(query, order) {
+			continue
+		}
+		if after != nil && compareOrders(query, order, after) <= 0 {
+			continue
+		}
+		orders = append(orders, copyOrder(order))
+	}
+	sort.Slice(orders, func(i, j int) bool {
+		return compareOrders(query, orders[i], orders[j]) < 0
+	})
+	if len(orders) > query.Limit+1 {
+		orders = orders[:query.Limit+1]
+	}
+	return query.Page(orders), nil
+}
+
+// Update replaces the mutable fields of a stored order in the mock database and
+// increments its Version. Items and totals are fixed when the order is created
+// and are left unchanged. An order changed since it was read returns
+// domain.ErrConflict.
+func (r *OrderRepositoryMock) Update(ctx context.Context, order *entity.Order) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(order)
+}
+
+// update overwrites the mutable fields of a stored order still at
+// order.Version and increments the version. The caller must hold r.mu.
+func (r *OrderRepositoryMock) update(order *entity.Order) error {
+	existing, ok := r.orders[order.OrderID]
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+	if existing.Version != order.Version {
+		return fmt.Errorf("%w: order %d has changed since version %d was read", domain.ErrConflict, order.OrderID, order.Version)
+	}
+	order.Version++
+	stored := copyOrder(order)
+	stored.ID = existing.ID
+	stored.CreatedAt = existing.CreatedAt
+	stored.Items = existing.Items
+	stored.TaxRate = existing.TaxRate
+	stored.Subtotal, stored.Tax, stored.Total = existing.Subtotal, existing.Tax, existing.Total
+	r.orders[order.OrderID] = stored
+	return nil
+}
+
+// Delete removes an order from the mock database.
+func (r *OrderRepositoryMock) Delete(ctx context.Context, orderID int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	order, ok := r.orders[orderID]
+	if !ok {
+		return domain.ErrOrderNotFound
+	}
+	delete(r.orderIDs, order.ID)
+	delete(r.orders, orderID)
+	return nil
+}
+
+// matchesOrderQuery reports whether an order passes the filters of a query.
+func matchesOrderQuery(query repository.OrderQuery, order *entity.Order) bool {
+	if query.Status != "" && order.Status != query.Status {
+		return false
+	}
+	if query.Paid != nil && order.Paid != *query.Paid {
+		return false
+	}
+	if !query.CreatedFrom.IsZero() && order.CreatedAt.Before(query.CreatedFrom) {
+		return false
+	}
+	if !query.CreatedTo.IsZero() && !order.CreatedAt.Before(query.CreatedTo) {
+		return false
+	}
+	return true
+}
+
+// compareOrders orders a and b the way a query sorts them: by the sort field,
+// then by OrderID, both in the query's direction.
+func compareOrders(query repository.OrderQuery, a, b *entity.Order) int {
+	var c int
+	switch query.SortBy {
+	case repository.OrderSortByCreatedAt:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case repository.OrderSortByUpdatedAt:
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	if c == 0 {
+		c = cmp.Compare(a.OrderID, b.OrderID)
+	}
+	if query.SortDirection == repository.SortDescending {
+		c = -c
+	}
+	return c
+}
+
+// SaveWithMessage saves an order and adds a message to the mock outbox. Neither
+// is stored if the order already exists.
+func (r *OrderRepositoryMock) SaveWithMessage(ctx context.Context, order *entity.Order, message *repository.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.save(order); err != nil {
+		return err
+	}
+	r.addMessages([]*repository.OutboxMessage{message})
+	return nil
+}
+
+// UpdateWithMessages updates an order like Update and adds messages to the
+// mock outbox. Nothing is stored if the update fails.
+func (r *OrderRepositoryMock) UpdateWithMessages(ctx context.Context, order *entity.Order, messages []*repository.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.update(order); err != nil {
+		return err
+	}
+	r.addMessages(messages)
+	return nil
+}
+
+// UpdateWithRefund updates an order like Update, stores the status and
+// provider reference of a refund in Refunds and adds messages to the mock
+// outbox. Nothing is stored if either update fails.
+func (r *OrderRepositoryMock) UpdateWithRefund(ctx context.Context, order *entity.Order, refund *entity.Refund, messages []*repository.OutboxMessage) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if r.Refunds == nil {
+		return errors.New("the mock outbox has no refund repository")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Refunds.mu.Lock()
+	defer r.Refunds.mu.Unlock()
+	if !r.Refunds.has(refund.ID) {
+		return domain.ErrRefundNotFound
+	}
+	if err := r.update(order); err != nil {
+		return err
+	}
+	if err := r.Refunds.update(refund); err != nil {
+		return err
+	}
+	r.addMessages(messages)
+	return nil
+}
+
+// addMessages stores copies of messages in the mock outbox and sets their IDs.
+// The caller must hold r.mu.
+func (r *OrderRepositoryMock) addMessages(messages []*repository.OutboxMessage) {
+	for _, message := range messages {
+		r.nextOutboxID++
+		message.ID = r.nextOutboxID
+		storedMessage := *message
+		r.outbox = append(r.outbox, &storedMessage)
+	}
+}
+
+// OutboxMessages returns copies of every message in the mock outbox, sent or
+// not, in the order they were added. Unlike FetchPending it claims nothing.
+func (r *OrderRepositoryMock) OutboxMessages() []*repository.OutboxMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	messages := make([]*repository.OutboxMessage, len(r.outbox))
+	for i, message := range r.outbox {
+		copied := *message
+		messages[i] = &copied
+	}
+	return messages
+}
+
+// FetchPending claims the unsent messages in the mock outbox that are due and
+// not claimed by another fetch for repository.OutboxLease, and returns them.
+func (r *OrderRepositoryMock) FetchPending(ctx context.Context, maxAttempts, limit int) ([]*repository.OutboxMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var messages []*repository.OutboxMessage
+	for _, message := range r.outbox {
+		if len(messages) == limit {
+			break
+		}
+		if message.SentAt != nil || message.Attempts >= maxAttempts || message.NextAttemptAt.After(now) {
+			continue
+		}
+		if lockedUntil, ok := r.lockedUntil[message.ID]; ok && lockedUntil.After(now) {
+			continue
+		}
+		r.lockedUntil[message.ID] = now.Add(repository.OutboxLease)
+		found := *message
+		messages = append(messages, &found)
+	}
+	return messages, nil
+}
+
+// MarkSent records that a message in the mock outbox was published and releases it.
+func (r *OrderRepositoryMock) MarkSent(ctx context.Context, id int64, sentAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.outbox {
+		if message.ID == id {
+			message.SentAt = &sentAt
+			delete(r.lockedUntil, id)
+			return nil
+		}
+	}
+	return errors.New("outbox message not found")
+}
+
+// MarkFailed records a failed attempt to publish a message in the mock outbox
+// and releases it.
+func (r *OrderRepositoryMock) MarkFailed(ctx context.Context, id int64, lastError string, nextAttemptAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.outbox {
+		if message.ID == id {
+			message.Attempts++
+			message.LastError = lastError
+			message.NextAttemptAt = nextAttemptAt
+			delete(r.lockedUntil, id)
+			return nil
+		}
 	}
-	return orders, nil
+	return errors.New("outbox message not found")
 }
@@ -0,0 +1,31 @@
+package database_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/domain/repository/repositorytest"
+	"GoCleanArch/internal/infra/database"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("OrderRepositoryMock", func() {
+	Describe("as an OrderRepository", func() {
+		repositorytest.DescribeOrderRepository(func() repository.OrderRepository {
+			return database.NewOrderRepositoryMock()
+		})
+	})
+
+	Describe("as an OrderOutbox", func() {
+		repositorytest.DescribeOrderOutbox(func() repositorytest.OrderRepositoryWithOutbox {
+			return database.NewOrderRepositoryMock()
+		})
+	})
+
+	Describe("as an OrderOutbox with refunds", func() {
+		repositorytest.DescribeRefundOutbox(func() (repositorytest.OrderRepositoryWithOutbox, repository.RefundRepository) {
+			outbox := database.NewOrderRepositoryMock()
+			outbox.Refunds = database.NewRefundRepositoryMock()
+			return outbox, outbox.Refunds
+		})
+	})
+})
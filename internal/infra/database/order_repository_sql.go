@@ -0,0 +1,176 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// OrderRepositorySQL implements the OrderRepository and OrderOutbox interfaces
+// for MySQL, PostgreSQL and SQLite. Every time is written in UTC, which SQLite
+// needs to compare the times it stores as text.
+type OrderRepositorySQL struct {
+	DB      *sql.DB
+	dialect sqlDialect
+}
+
+// NewOrderRepositorySQL creates a new order repository for a database opened
+// with Open for driver.
+func NewOrderRepositorySQL(db *sql.DB, driver string) *OrderRepositorySQL {
+	return &OrderRepositorySQL{DB: db, dialect: sqlDialectFor(driver)}
+}
+
+// Save saves an order to the database. A duplicate key returns domain.ErrConflict.
+func (r *OrderRepositorySQL) Save(ctx context.Context, order *entity.Order) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := r.insertOrder(ctx, tx, order); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertOrder inserts an order row and its items. A duplicate key returns domain.ErrConflict.
+func (r *OrderRepositorySQL) insertOrder(ctx context.Context, db execer, order *entity.Order) error {
+	_, err := db.ExecContext(ctx, r.dialect.rebind("INSERT INTO orders ("+orderColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"), order.ID, order.Data, order.OrderID, order.Status, order.Paid, order.TaxRate, order.Subtotal.Currency, order.Subtotal.Amount, order.Tax.Amount, order.Total.Amount, order.CreatedAt.UTC(), order.UpdatedAt.UTC(), order.Version)
+	if r.dialect.isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return insertOrderItems(ctx, db, order, r.dialect.bind)
+}
+
+// GetByID retrieves an order from the database by its internal ID.
+// It returns domain.ErrOrderNotFound when there is no such order.
+func (r *OrderRepositorySQL) GetByID(ctx context.Context, id string) (*entity.Order, error) {
+	return r.get(ctx, "id", id)
+}
+
+// GetByOrderID retrieves an order from the database by its OrderID.
+// It returns domain.ErrOrderNotFound when there is no such order.
+func (r *OrderRepositorySQL) GetByOrderID(ctx context.Context, orderID int) (*entity.Order, error) {
+	return r.get(ctx, "order_id", orderID)
+}
+
+// get retrieves the order whose column has a value, with its items.
+func (r *OrderRepositorySQL) get(ctx context.Context, column string, value interface{}) (*entity.Order, error) {
+	row := r.DB.QueryRowContext(ctx, "SELECT "+orderColumns+" FROM orders WHERE "+column+" = "+r.dialect.bind(1), value)
+
+	order, err := scanOrder(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrOrderNotFound
+		}
+		return nil, err
+	}
+	if err := loadOrderItems(ctx, r.DB, []*entity.Order{order}, r.dialect.bind); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// Find retrieves one page of the orders matching a query from the database.
+func (r *OrderRepositorySQL) Find(ctx context.Context, query repository.OrderQuery) (*repository.OrderPage, error) {
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := query.DecodeCursor()
+	if err != nil {
+		return nil, err
+	}
+
+	statement, args := findOrdersStatement(query, cursor, r.dialect.bind)
+	for i, arg := range args {
+		if t, ok := arg.(time.Time); ok {
+			args[i] = t.UTC()
+		}
+	}
+	rows, err := r.DB.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := scanOrders(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := loadOrderItems(ctx, r.DB, orders, r.dialect.bind); err != nil {
+		return nil, err
+	}
+
+	return query.Page(orders), nil
+}
+
+// Update overwrites the mutable fields of an order in the database and
+// increments its Version. Items and totals are fixed when the order is created
+// and are left unchanged. It returns domain.ErrConflict when the stored order
+// is no longer at order.Version, and domain.ErrOrderNotFound when there is no
+// such order.
+func (r *OrderRepositorySQL) Update(ctx context.Context, order *entity.Order) error {
+	if err := r.updateOrder(ctx, r.DB, order); err != nil {
+		return err
+	}
+	order.Version++
+	return nil
+}
+
+// updateOrder overwrites the mutable fields of the order row at order.Version
+// and increments the stored version, leaving order.Version to the caller.
+func (r *OrderRepositorySQL) updateOrder(ctx context.Context, db execQueryer, order *entity.Order) error {
+	result, err := db.ExecContext(ctx, r.dialect.rebind("UPDATE orders SET data = ?, status = ?, paid = ?, updated_at = ?, version = version + 1 WHERE order_id = ? AND version = ?"), order.Data, order.Status, order.Paid, order.UpdatedAt.UTC(), order.OrderID, order.Version)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(result); !errors.Is(err, domain.ErrOrderNotFound) {
+		return err
+	}
+
+	var exists int
+	err = db.QueryRowContext(ctx, r.dialect.rebind("SELECT 1 FROM orders WHERE order_id = ?"), order.OrderID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.ErrOrderNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: order %d has changed since version %d was read", domain.ErrConflict, order.OrderID, order.Version)
+}
+
+// Delete removes an order from the database.
+// It returns domain.ErrOrderNotFound when there is no such order.
+func (r *OrderRepositorySQL) Delete(ctx context.Context, orderID int) error {
+	result, err := r.DB.ExecContext(ctx, r.dialect.rebind("DELETE FROM orders WHERE order_id = ?"), orderID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(result)
+}
+
+// checkRowsAffected turns a statement that matched no rows into domain.ErrOrderNotFound.
+func checkRowsAffected(result sql.Result) error {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrOrderNotFound
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"sort"
+	"sync"
+)
+
+// PaymentRepositoryMock is a mock implementation of the PaymentRepository
+// interface. Like OrderRepositoryMock it stores copies of payments.
+type PaymentRepositoryMock struct {
+	mu       sync.Mutex
+	payments []*entity.Payment
+	ids      map[string]bool
+}
+
+// NewPaymentRepositoryMock creates a new PaymentRepositoryMock.
+func NewPaymentRepositoryMock() *PaymentRepositoryMock {
+	return &PaymentRepositoryMock{ids: make(map[string]bool)}
+}
+
+// Save records a payment in memory. Saving an ID twice returns domain.ErrConflict.
+func (r *PaymentRepositoryMock) Save(ctx context.Context, payment *entity.Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ids[payment.ID] {
+		return domain.ErrConflict
+	}
+	stored := *payment
+	r.payments = append(r.payments, &stored)
+	r.ids[payment.ID] = true
+	return nil
+}
+
+// Update replaces a payment in memory. It returns domain.ErrPaymentNotFound
+// when there is no such payment.
+func (r *PaymentRepositoryMock) Update(ctx context.Context, payment *entity.Payment) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.payments {
+		if stored.ID == payment.ID {
+			updated := *payment
+			updated.OrderID, updated.Amount, updated.CreatedAt = stored.OrderID, stored.Amount, stored.CreatedAt
+			r.payments[i] = &updated
+			return nil
+		}
+	}
+	return domain.ErrPaymentNotFound
+}
+
+// ListByOrderID returns the payments of an order from memory, oldest first.
+func (r *PaymentRepositoryMock) ListByOrderID(ctx context.Context, orderID int) ([]*entity.Payment, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var payments []*entity.Payment
+	for _, payment := range r.payments {
+		if payment.OrderID == orderID {
+			found := *payment
+			payments = append(payments, &found)
+		}
+	}
+	sort.SliceStable(payments, func(i, j int) bool {
+		if !payments[i].CreatedAt.Equal(payments[j].CreatedAt) {
+			return payments[i].CreatedAt.Before(payments[j].CreatedAt)
+		}
+		return payments[i].ID < payments[j].ID
+	})
+	return payments, nil
+}
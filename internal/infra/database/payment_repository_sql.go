@@ -0,0 +1,85 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"database/sql"
+	"time"
+)
+
+// paymentColumns lists the payments columns in the order scanPayments reads them.
+const paymentColumns = "id, order_id, amount, currency, status, provider_reference, decline_reason, created_at, updated_at, captured_at"
+
+// PaymentRepositorySQL implements the PaymentRepository interface for MySQL,
+// PostgreSQL and SQLite. Like OrderRepositorySQL it writes every time in UTC.
+type PaymentRepositorySQL struct {
+	DB      *sql.DB
+	dialect sqlDialect
+}
+
+// NewPaymentRepositorySQL creates a new payment repository for a database
+// opened with Open for driver.
+func NewPaymentRepositorySQL(db *sql.DB, driver string) *PaymentRepositorySQL {
+	return &PaymentRepositorySQL{DB: db, dialect: sqlDialectFor(driver)}
+}
+
+// Save records a payment in the database. A duplicate ID returns domain.ErrConflict.
+func (r *PaymentRepositorySQL) Save(ctx context.Context, payment *entity.Payment) error {
+	_, err := r.DB.ExecContext(ctx, r.dialect.rebind("INSERT INTO payments ("+paymentColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"), payment.ID, payment.OrderID, payment.Amount.Amount, payment.Amount.Currency, payment.Status, payment.ProviderReference, payment.DeclineReason, payment.CreatedAt.UTC(), payment.UpdatedAt.UTC(), nullTime(payment.CapturedAt))
+	if r.dialect.isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// Update stores the changing fields of a payment in the database. It returns
+// domain.ErrPaymentNotFound when there is no such payment.
+func (r *PaymentRepositorySQL) Update(ctx context.Context, payment *entity.Payment) error {
+	result, err := r.DB.ExecContext(ctx, r.dialect.rebind("UPDATE payments SET status = ?, provider_reference = ?, decline_reason = ?, updated_at = ?, captured_at = ? WHERE id = ?"), payment.Status, payment.ProviderReference, payment.DeclineReason, payment.UpdatedAt.UTC(), nullTime(payment.CapturedAt), payment.ID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrPaymentNotFound
+	}
+	return nil
+}
+
+// ListByOrderID returns the payments of an order from the database, oldest first.
+func (r *PaymentRepositorySQL) ListByOrderID(ctx context.Context, orderID int) ([]*entity.Payment, error) {
+	rows, err := r.DB.QueryContext(ctx, r.dialect.rebind("SELECT "+paymentColumns+" FROM payments WHERE order_id = ? ORDER BY created_at, id"), orderID)
+	if err != nil {
+		return nil, err
+	}
+	return scanPayments(rows)
+}
+
+// nullTime stores the zero time as NULL and any other time in UTC.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t.UTC(), Valid: !t.IsZero()}
+}
+
+// scanPayments reads every row of a query for paymentColumns and closes the rows.
+func scanPayments(rows *sql.Rows) ([]*entity.Payment, error) {
+	defer rows.Close()
+
+	var payments []*entity.Payment
+	for rows.Next() {
+		var payment entity.Payment
+		var capturedAt sql.NullTime
+		if err := rows.Scan(&payment.ID, &payment.OrderID, &payment.Amount.Amount, &payment.Amount.Currency, &payment.Status, &payment.ProviderReference, &payment.DeclineReason, &payment.CreatedAt, &payment.UpdatedAt, &capturedAt); err != nil {
+			return nil, err
+		}
+		payment.CapturedAt = capturedAt.Time
+		payments = append(payments, &payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/domain/repository/repositorytest"
+	"GoCleanArch/internal/infra/database"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("PaymentRepositoryMock", func() {
+	repositorytest.DescribePaymentRepository(func() repository.PaymentRepository {
+		return database.NewPaymentRepositoryMock()
+	})
+})
+
+var _ = Describe("PaymentRepositorySQL on MySQL", func() {
+	describeSQLPaymentRepository(database.DriverMySQL, envDSN("MYSQL_TEST_DSN"))
+})
+
+var _ = Describe("PaymentRepositorySQL on PostgreSQL", func() {
+	describeSQLPaymentRepository(database.DriverPostgres, envDSN("POSTGRES_TEST_DSN"))
+})
+
+var _ = Describe("PaymentRepositorySQL on SQLite", func() {
+	describeSQLPaymentRepository(database.DriverSQLite, sqliteDSN)
+})
+
+// describeSQLPaymentRepository runs the payment repository contract against a
+// migrated, emptied database for each spec.
+func describeSQLPaymentRepository(driver string, dsn func() string) {
+	var db *sql.DB
+
+	BeforeEach(func() {
+		db = openTestDB(driver, dsn)
+	})
+
+	repositorytest.DescribePaymentRepository(func() repository.PaymentRepository {
+		return database.NewPaymentRepositorySQL(db, driver)
+	})
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"sort"
+	"sync"
+)
+
+// RefundRepositoryMock is a mock implementation of the RefundRepository
+// interface. Like OrderRepositoryMock it stores copies of refunds.
+type RefundRepositoryMock struct {
+	mu      sync.Mutex
+	refunds []*entity.Refund
+	ids     map[string]bool
+}
+
+// NewRefundRepositoryMock creates a new RefundRepositoryMock.
+func NewRefundRepositoryMock() *RefundRepositoryMock {
+	return &RefundRepositoryMock{ids: make(map[string]bool)}
+}
+
+// Save records a refund in memory. Saving an ID twice returns domain.ErrConflict.
+func (r *RefundRepositoryMock) Save(ctx context.Context, refund *entity.Refund) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ids[refund.ID] {
+		return domain.ErrConflict
+	}
+	stored := *refund
+	r.refunds = append(r.refunds, &stored)
+	r.ids[refund.ID] = true
+	return nil
+}
+
+// Update stores the status and provider reference of a refund in memory. It
+// returns domain.ErrRefundNotFound when there is no such refund.
+func (r *RefundRepositoryMock) Update(ctx context.Context, refund *entity.Refund) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(refund)
+}
+
+// update stores the status and provider reference of a refund. The caller
+// must hold r.mu.
+func (r *RefundRepositoryMock) update(refund *entity.Refund) error {
+	for _, stored := range r.refunds {
+		if stored.ID == refund.ID {
+			stored.Status = refund.Status
+			stored.ProviderReference = refund.ProviderReference
+			return nil
+		}
+	}
+	return domain.ErrRefundNotFound
+}
+
+// has reports whether a refund with the ID is stored. The caller must hold r.mu.
+func (r *RefundRepositoryMock) has(id string) bool {
+	return r.ids[id]
+}
+
+// ListByOrderID returns the refunds of an order from memory, oldest first.
+func (r *RefundRepositoryMock) ListByOrderID(ctx context.Context, orderID int) ([]*entity.Refund, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var refunds []*entity.Refund
+	for _, refund := range r.refunds {
+		if refund.OrderID == orderID {
+			found := *refund
+			refunds = append(refunds, &found)
+		}
+	}
+	sort.SliceStable(refunds, func(i, j int) bool {
+		if !refunds[i].CreatedAt.Equal(refunds[j].CreatedAt) {
+			return refunds[i].CreatedAt.Before(refunds[j].CreatedAt)
+		}
+		return refunds[i].ID < refunds[j].ID
+	})
+	return refunds, nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"context"
+	"database/sql"
+)
+
+// refundColumns lists the refunds columns in the order scanRefunds reads them.
+const refundColumns = "id, order_id, payment_id, amount, currency, reason, status, provider_reference, created_at"
+
+// RefundRepositorySQL implements the RefundRepository interface for MySQL,
+// PostgreSQL and SQLite. Like OrderRepositorySQL it writes every time in UTC.
+type RefundRepositorySQL struct {
+	DB      *sql.DB
+	dialect sqlDialect
+}
+
+// NewRefundRepositorySQL creates a new refund repository for a database
+// opened with Open for driver.
+func NewRefundRepositorySQL(db *sql.DB, driver string) *RefundRepositorySQL {
+	return &RefundRepositorySQL{DB: db, dialect: sqlDialectFor(driver)}
+}
+
+// Save records a refund in the database. A duplicate ID returns domain.ErrConflict.
+func (r *RefundRepositorySQL) Save(ctx context.Context, refund *entity.Refund) error {
+	_, err := r.DB.ExecContext(ctx, r.dialect.rebind("INSERT INTO refunds ("+refundColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"), refund.ID, refund.OrderID, refund.PaymentID, refund.Amount.Amount, refund.Amount.Currency, refund.Reason, refund.Status, refund.ProviderReference, refund.CreatedAt.UTC())
+	if r.dialect.isUniqueViolation(err) {
+		return domain.ErrConflict
+	}
+	return err
+}
+
+// Update stores the status and provider reference of a refund in the
+// database. It returns domain.ErrRefundNotFound when there is no such refund.
+func (r *RefundRepositorySQL) Update(ctx context.Context, refund *entity.Refund) error {
+	return updateRefund(ctx, r.DB, r.dialect, refund)
+}
+
+// updateRefund stores the status and provider reference of a refund with db,
+// which may be a transaction.
+func updateRefund(ctx context.Context, db execer, dialect sqlDialect, refund *entity.Refund) error {
+	result, err := db.ExecContext(ctx, dialect.rebind("UPDATE refunds SET status = ?, provider_reference = ? WHERE id = ?"), refund.Status, refund.ProviderReference, refund.ID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return domain.ErrRefundNotFound
+	}
+	return nil
+}
+
+// ListByOrderID returns the refunds of an order from the database, oldest first.
+func (r *RefundRepositorySQL) ListByOrderID(ctx context.Context, orderID int) ([]*entity.Refund, error) {
+	rows, err := r.DB.QueryContext(ctx, r.dialect.rebind("SELECT "+refundColumns+" FROM refunds WHERE order_id = ? ORDER BY created_at, id"), orderID)
+	if err != nil {
+		return nil, err
+	}
+	return scanRefunds(rows)
+}
+
+// scanRefunds reads every row of a query for refundColumns and closes the rows.
+func scanRefunds(rows *sql.Rows) ([]*entity.Refund, error) {
+	defer rows.Close()
+
+	var refunds []*entity.Refund
+	for rows.Next() {
+		var refund entity.Refund
+		if err := rows.Scan(&refund.ID, &refund.OrderID, &refund.PaymentID, &refund.Amount.Amount, &refund.Amount.Currency, &refund.Reason, &refund.Status, &refund.ProviderReference, &refund.CreatedAt); err != nil {
+			return nil, err
+		}
+		refunds = append(refunds, &refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return refunds, nil
+}
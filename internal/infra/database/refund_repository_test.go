@@ -0,0 +1,42 @@
+package database_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/domain/repository/repositorytest"
+	"GoCleanArch/internal/infra/database"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+var _ = Describe("RefundRepositoryMock", func() {
+	repositorytest.DescribeRefundRepository(func() repository.RefundRepository {
+		return database.NewRefundRepositoryMock()
+	})
+})
+
+var _ = Describe("RefundRepositorySQL on MySQL", func() {
+	describeSQLRefundRepository(database.DriverMySQL, envDSN("MYSQL_TEST_DSN"))
+})
+
+var _ = Describe("RefundRepositorySQL on PostgreSQL", func() {
+	describeSQLRefundRepository(database.DriverPostgres, envDSN("POSTGRES_TEST_DSN"))
+})
+
+var _ = Describe("RefundRepositorySQL on SQLite", func() {
+	describeSQLRefundRepository(database.DriverSQLite, sqliteDSN)
+})
+
+// describeSQLRefundRepository runs the refund repository contract against a
+// migrated, emptied database for each spec.
+func describeSQLRefundRepository(driver string, dsn func() string) {
+	var db *sql.DB
+
+	BeforeEach(func() {
+		db = openTestDB(driver, dsn)
+	})
+
+	repositorytest.DescribeRefundRepository(func() repository.RefundRepository {
+		return database.NewRefundRepositorySQL(db, driver)
+	})
+}
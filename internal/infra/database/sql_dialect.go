@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// sqlDialect holds what the SQL repositories need to know about a database:
+// how it writes bind parameters, how it reports a unique key violation and how
+// an INSERT reads back the ID the database generated.
+type sqlDialect struct {
+	bind              placeholder
+	isUniqueViolation func(err error) bool
+	// returning is set when generated IDs are read with INSERT ... RETURNING
+	// rather than with sql.Result.LastInsertId.
+	returning bool
+}
+
+// sqlDialectFor returns the dialect of a database driver. Like the db.driver
+// setting, it takes MySQL when the driver is left empty.
+func sqlDialectFor(driver string) sqlDialect {
+	switch driver {
+	case DriverPostgres:
+		return postgresDialect
+	case DriverSQLite:
+		return sqliteDialect
+	default:
+		return mysqlDialect
+	}
+}
+
+// Rebind rewrites the question mark bind parameters of a statement in the
+// style of a database driver, as the SQL repositories do. Statements that other
+// packages run against a database opened with Open go through it.
+func Rebind(driver, statement string) string {
+	return sqlDialectFor(driver).rebind(statement)
+}
+
+// rebind rewrites the question mark bind parameters of a statement in the dialect's style.
+func (d sqlDialect) rebind(statement string) string {
+	parts := strings.Split(statement, "?")
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for n, part := range parts[1:] {
+		b.WriteString(d.bind(n + 1))
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// insertID runs an INSERT statement, written with question marks and without
+// a RETURNING clause, and returns the ID the database generated for the row.
+func (d sqlDialect) insertID(ctx context.Context, db execQueryer, statement string, args ...interface{}) (int64, error) {
+	var id int64
+	if d.returning {
+		err := db.QueryRowContext(ctx, d.rebind(statement)+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	result, err := db.ExecContext(ctx, d.rebind(statement), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// execQueryer is satisfied by both *sql.DB and *sql.Tx.
+type execQueryer interface {
+	execer
+	queryer
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
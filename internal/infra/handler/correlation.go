@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"GoCleanArch/internal/correlation"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// CorrelationIDHeader is the request and response header that carries the correlation ID.
+	CorrelationIDHeader = "X-Correlation-ID"
+	// maxCorrelationIDLength is the longest correlation ID accepted from a client.
+	maxCorrelationIDLength = 128
+)
+
+// CorrelationID is middleware that gives every request a correlation ID. The
+// ID is taken from the X-Correlation-ID header when the client sends a usable
+// one and generated otherwise. It is stored in the request context, where the
+// events the request causes pick it up, and echoed in the response header.
+func CorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationIDHeader)
+		if !validCorrelationID(id) {
+			generated, err := uuid.NewV7()
+			if err != nil {
+				log.Printf("Error generating correlation ID: %v", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			id = generated.String()
+		}
+
+		w.Header().Set(CorrelationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(correlation.WithID(r.Context(), id)))
+	})
+}
+
+// validCorrelationID reports whether id is non-empty, not too long and made of
+// visible ASCII characters, so that it is safe to log and to send on.
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > maxCorrelationIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+package handler_test
+
+import (
+	"GoCleanArch/internal/correlation"
+	"GoCleanArch/internal/infra/handler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CorrelationID", func() {
+	var seen string
+
+	serve := func(header string) *httptest.ResponseRecorder {
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = correlation.ID(r.Context())
+		})
+		req := httptest.NewRequest("GET", "/orders", nil)
+		if header != "" {
+			req.Header.Set(handler.CorrelationIDHeader, header)
+		}
+		rr := httptest.NewRecorder()
+		handler.CorrelationID(next).ServeHTTP(rr, req)
+		return rr
+	}
+
+	BeforeEach(func() {
+		seen = ""
+	})
+
+	It("should keep a correlation ID sent by the client", func() {
+		rr := serve("checkout-42")
+
+		Expect(rr.Header().Get(handler.CorrelationIDHeader)).To(Equal("checkout-42"))
+		Expect(seen).To(Equal("checkout-42"))
+	})
+
+	It("should generate a correlation ID when the client sends none", func() {
+		rr := serve("")
+
+		id := rr.Header().Get(handler.CorrelationIDHeader)
+		Expect(uuid.Parse(id)).Error().NotTo(HaveOccurred())
+		Expect(seen).To(Equal(id))
+	})
+
+	It("should replace a correlation ID that is too long or not printable", func() {
+		for _, header := range []string{strings.Repeat("a", 129), "two words"} {
+			rr := serve(header)
+
+			id := rr.Header().Get(handler.CorrelationIDHeader)
+			Expect(id).NotTo(Equal(header))
+			Expect(uuid.Parse(id)).Error().NotTo(HaveOccurred())
+			Expect(seen).To(Equal(id))
+		}
+	})
+})
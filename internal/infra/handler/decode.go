@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"GoCleanArch/internal/validation"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxRequestBodyBytes caps the size of a JSON request body.
+const maxRequestBodyBytes = 1 << 20
+
+// decodeError explains why a request body could not be decoded.
+type decodeError struct {
+	Detail      string
+	FieldErrors []validation.FieldError
+}
+
+func (e *decodeError) Error() string {
+	return e.Detail
+}
+
+// decodeJSON strictly decodes a request body holding exactly one JSON value:
+// unknown fields, trailing data and oversized bodies are all rejected.
+func decodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return &decodeError{
+				Detail:      "The request body has a field of the wrong type.",
+				FieldErrors: []validation.FieldError{{Field: typeErr.Field, Message: "must be a " + typeErr.Type.String()}},
+			}
+		case errors.As(err, &maxBytesErr):
+			return &decodeError{Detail: fmt.Sprintf("The request body must not be larger than %d bytes.", maxBytesErr.Limit)}
+		case errors.Is(err, io.EOF):
+			return &decodeError{Detail: "The request body must not be empty."}
+		default:
+			return &decodeError{Detail: "The request body is not valid JSON: " + err.Error()}
+		}
+	}
+
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		return &decodeError{Detail: "The request body must contain a single JSON object."}
+	}
+	return nil
+}
+
+// writeDecodeError writes a 400 problem for a body that decodeJSON rejected.
+func writeDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var decodeErr *decodeError
+	if errors.As(err, &decodeErr) {
+		writeProblem(w, r, http.StatusBadRequest, decodeErr.Detail, decodeErr.FieldErrors)
+		return
+	}
+	writeProblem(w, r, http.StatusBadRequest, err.Error(), nil)
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/validation"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header that carries an idempotency key.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from the idempotency store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	// DefaultIdempotencyTTL is how long responses are replayed when no TTL is configured.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// maxIdempotencyKeyLength is the longest idempotency key the store accepts.
+	maxIdempotencyKeyLength = 255
+	// idempotencyReservationTTL is how long a key stays reserved for a request
+	// that never finishes, for example because the server stopped.
+	idempotencyReservationTTL = 5 * time.Minute
+)
+
+// Idempotency returns middleware that makes requests carrying an Idempotency-Key
+// header safe to retry. Before the request is handled its key is reserved with
+// a pending record, so concurrent requests with the same key are handled only
+// once: the others return 409 while the first is in flight. The first
+// successful response for a key is stored for ttl (DefaultIdempotencyTTL if
+// ttl is not positive) and replayed for every retry of the same request; any
+// other response releases the key. Reusing the key for a different request is
+// rejected with 422. Requests without the header pass through.
+func Idempotency(store repository.IdempotencyStore, ttl time.Duration) func(http.Handler) http.Handler {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	reservation := min(ttl, idempotencyReservationTTL)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeProblem(w, r, http.StatusBadRequest, "Invalid Idempotency-Key", []validation.FieldError{{Field: IdempotencyKeyHeader, Message: "must be at most 255 characters"}})
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBodyBytes))
+			if err != nil {
+				writeProblem(w, r, http.StatusBadRequest, "The request body could not be read.", nil)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			hash := requestHash(r, body)
+
+			now := time.Now()
+			record := &repository.IdempotencyRecord{
+				Key:         key,
+				RequestHash: hash,
+				CreatedAt:   now,
+				ExpiresAt:   now.Add(reservation),
+			}
+			if err := store.Save(r.Context(), record); err != nil {
+				if errors.Is(err, domain.ErrConflict) {
+					replay(w, r, store, key, hash)
+					return
+				}
+				log.Printf("Error reserving idempotency key %q: %v", key, err)
+				writeError(w, r, err)
+				return
+			}
+
+			// The outcome is stored even when the client has gone away, so
+			// that its retry finds it.
+			ctx := context.WithoutCancel(r.Context())
+			capture := &responseCapture{ResponseWriter: w}
+			defer func() {
+				if capture.status >= 200 && capture.status < 300 {
+					return
+				}
+				if err := store.Release(ctx, key); err != nil {
+					log.Printf("Error releasing idempotency key %q: %v", key, err)
+				}
+			}()
+			next.ServeHTTP(capture, r)
+			if capture.status < 200 || capture.status >= 300 {
+				return
+			}
+
+			record.StatusCode = capture.status
+			record.ContentType = capture.Header().Get("Content-Type")
+			record.Body = capture.body.Bytes()
+			record.ExpiresAt = time.Now().Add(ttl)
+			if err := store.Complete(ctx, record); err != nil {
+				log.Printf("Error saving idempotency key %q: %v", key, err)
+			}
+		})
+	}
+}
+
+// replay answers a request whose idempotency key is already taken: with the
+// stored response, or with 409 while the first request is still in flight.
+func replay(w http.ResponseWriter, r *http.Request, store repository.IdempotencyStore, key, hash string) {
+	record, err := store.Get(r.Context(), key)
+	switch {
+	case errors.Is(err, repository.ErrIdempotencyRecordNotFound):
+		writeProblem(w, r, http.StatusConflict, "A request with this Idempotency-Key has just finished; retry it.", nil)
+		return
+	case err != nil:
+		log.Printf("Error reading idempotency key %q: %v", key, err)
+		writeError(w, r, err)
+		return
+	case record.RequestHash != hash:
+		log.Printf("Idempotency key %q reused for a different request", key)
+		writeProblem(w, r, http.StatusUnprocessableEntity, "The Idempotency-Key was already used for a different request.", nil)
+		return
+	case record.Pending():
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, r, http.StatusConflict, "A request with this Idempotency-Key is still being processed.", nil)
+		return
+	}
+
+	log.Printf("Replaying stored response for idempotency key %q", key)
+	if record.ContentType != "" {
+		w.Header().Set("Content-Type", record.ContentType)
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(record.StatusCode)
+	w.Write(record.Body)
+}
+
+// requestHash fingerprints the method, path and body of a request.
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCapture passes a response through while keeping a copy of its status and body.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
@@ -0,0 +1,154 @@
+package handler_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/handler"
+	"GoCleanArch/internal/usecase"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency", func() {
+	var (
+		orderRepo *database.OrderRepositoryMock
+		router    *chi.Mux
+	)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set(handler.IdempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	BeforeEach(func() {
+		orderRepo = database.NewOrderRepositoryMock()
+		orderHandler := handler.NewOrderHandler(usecase.NewCreateOrderUseCase(orderRepo), nil, nil, nil, nil, nil, nil)
+
+		router = chi.NewRouter()
+		router.With(handler.Idempotency(database.NewIdempotencyStoreMock(), time.Hour)).Post("/orders", orderHandler.CreateOrder)
+	})
+
+	outboxMessages := func() int {
+		messages, err := orderRepo.FetchPending(context.Background(), 1, 100)
+		Expect(err).NotTo(HaveOccurred())
+		return len(messages)
+	}
+
+	Context("when a request is retried with the same key", func() {
+		It("should replay the stored 201 without creating the order again", func() {
+			body := `{"Data":"27/06/2025","OrderId":600}`
+
+			first := post("key-1", body)
+			second := post("key-1", body)
+
+			Expect(first.Code).To(Equal(http.StatusCreated))
+			Expect(second.Code).To(Equal(http.StatusCreated))
+			Expect(second.Body.String()).To(Equal(first.Body.String()))
+			Expect(second.Header().Get("Content-Type")).To(Equal("application/json"))
+			Expect(second.Header().Get(handler.IdempotentReplayedHeader)).To(Equal("true"))
+			Expect(outboxMessages()).To(Equal(1))
+		})
+	})
+
+	Context("when a key is reused with a different body", func() {
+		It("should return 422 Unprocessable Entity", func() {
+			Expect(post("key-2", `{"Data":"27/06/2025","OrderId":601}`).Code).To(Equal(http.StatusCreated))
+
+			rr := post("key-2", `{"Data":"27/06/2025","OrderId":602}`)
+
+			Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(outboxMessages()).To(Equal(1))
+		})
+	})
+
+	Context("when the first attempt fails", func() {
+		It("should not store the failure", func() {
+			Expect(post("key-3", `{"Data":"","OrderId":603}`).Code).To(Equal(http.StatusUnprocessableEntity))
+
+			rr := post("key-3", `{"Data":"","OrderId":603}`)
+
+			Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+			Expect(rr.Header().Get(handler.IdempotentReplayedHeader)).To(BeEmpty())
+		})
+	})
+
+	Context("when a retry arrives while the first request is in flight", func() {
+		It("should return 409 and replay the response once the first request completes", func() {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			handled := 0
+			slow := chi.NewRouter()
+			slow.With(handler.Idempotency(database.NewIdempotencyStoreMock(), time.Hour)).Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+				handled++
+				close(started)
+				<-release
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"OrderId":605}`))
+			})
+			send := func() *httptest.ResponseRecorder {
+				req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(`{"OrderId":605}`))
+				req.Header.Set(handler.IdempotencyKeyHeader, "key-5")
+				rr := httptest.NewRecorder()
+				slow.ServeHTTP(rr, req)
+				return rr
+			}
+
+			done := make(chan *httptest.ResponseRecorder)
+			go func() { done <- send() }()
+			<-started
+
+			inFlight := send()
+			Expect(inFlight.Code).To(Equal(http.StatusConflict))
+			Expect(inFlight.Header().Get("Retry-After")).NotTo(BeEmpty())
+
+			close(release)
+			Expect((<-done).Code).To(Equal(http.StatusCreated))
+
+			replayed := send()
+			Expect(replayed.Code).To(Equal(http.StatusCreated))
+			Expect(replayed.Body.String()).To(Equal(`{"OrderId":605}`))
+			Expect(replayed.Header().Get(handler.IdempotentReplayedHeader)).To(Equal("true"))
+			Expect(handled).To(Equal(1))
+		})
+	})
+
+	Context("when the handler panics", func() {
+		It("should release the key", func() {
+			store := database.NewIdempotencyStoreMock()
+			panicking := chi.NewRouter()
+			panicking.With(handler.Idempotency(store, time.Hour)).Post("/orders", func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			})
+			req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(`{}`))
+			req.Header.Set(handler.IdempotencyKeyHeader, "key-6")
+
+			Expect(func() { panicking.ServeHTTP(httptest.NewRecorder(), req) }).To(Panic())
+
+			_, err := store.Get(context.Background(), "key-6")
+			Expect(err).To(MatchError(repository.ErrIdempotencyRecordNotFound))
+		})
+	})
+
+	Context("without an Idempotency-Key header", func() {
+		It("should process every request", func() {
+			body := `{"Data":"27/06/2025","OrderId":604}`
+
+			Expect(post("", body).Code).To(Equal(http.StatusCreated))
+			Expect(post("", body).Code).To(Equal(http.StatusConflict))
+			Expect(outboxMessages()).To(Equal(1))
+		})
+	})
+})
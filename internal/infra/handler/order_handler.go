@@ -2,43 +2,57 @@ package handler
 
 import (
 	"GoCleanArch/internal/usecase"
+	"GoCleanArch/internal/validation"
 	"encoding/json"
+	"errors"
 	"log"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // OrderHandler handles HTTP requests for orders.
 type OrderHandler struct {
-	CreateOrderUseCase    *usecase.CreateOrderUseCase
-	GetOrderUseCase       *usecase.GetOrderByIDUseCase
-	GetAllOrdersUseCase   *usecase.GetAllOrdersUseCase
+	CreateOrderUseCase       *usecase.CreateOrderUseCase
+	GetOrderUseCase          *usecase.GetOrderByIDUseCase
+	GetOrderByOrderIDUseCase *usecase.GetOrderByOrderIDUseCase
+	GetAllOrdersUseCase      *usecase.GetAllOrdersUseCase
+	UpdateOrderUseCase       *usecase.UpdateOrderUseCase
+	PatchOrderUseCase        *usecase.PatchOrderUseCase
+	DeleteOrderUseCase       *usecase.DeleteOrderUseCase
 }
 
 // NewOrderHandler creates a new OrderHandler.
-func NewOrderHandler(createOrderUseCase *usecase.CreateOrderUseCase, getOrderUseCase *usecase.GetOrderByIDUseCase, getAllOrdersUseCase *usecase.GetAllOrdersUseCase) *OrderHandler {
+func NewOrderHandler(createOrderUseCase *usecase.CreateOrderUseCase, getOrderUseCase *usecase.GetOrderByIDUseCase, getOrderByOrderIDUseCase *usecase.GetOrderByOrderIDUseCase, getAllOrdersUseCase *usecase.GetAllOrdersUseCase, updateOrderUseCase *usecase.UpdateOrderUseCase, patchOrderUseCase *usecase.PatchOrderUseCase, deleteOrderUseCase *usecase.DeleteOrderUseCase) *OrderHandler {
 	return &OrderHandler{
-		CreateOrderUseCase:  createOrderUseCase,
-		GetOrderUseCase:     getOrderUseCase,
-		GetAllOrdersUseCase: getAllOrdersUseCase,
+		CreateOrderUseCase:       createOrderUseCase,
+		GetOrderUseCase:          getOrderUseCase,
+		GetOrderByOrderIDUseCase: getOrderByOrderIDUseCase,
+		GetAllOrdersUseCase:      getAllOrdersUseCase,
+		UpdateOrderUseCase:       updateOrderUseCase,
+		PatchOrderUseCase:        patchOrderUseCase,
+		DeleteOrderUseCase:       deleteOrderUseCase,
 	}
 }
 
 // CreateOrder handles the creation of a new order.
 func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	var input usecase.CreateOrderInputDTO
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+	if err := decodeJSON(w, r, &input); err != nil {
 		log.Printf("Error decoding request body: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDecodeError(w, r, err)
 		return
 	}
 
-	output, err := h.CreateOrderUseCase.Execute(input)
+	output, err := h.CreateOrderUseCase.Execute(r.Context(), input)
 	if err != nil {
 		log.Printf("Error creating order: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -48,23 +62,46 @@ func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Order created successfully: %d", input.OrderID)
 }
 
-// GetOrder handles the retrieval of an order by its ID.
+// GetOrder handles the retrieval of an order by its internal ID.
 func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Received request to get order by ID")
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		log.Printf("Invalid ID: %s", id)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID", []validation.FieldError{{Field: "id", Message: "must be a UUID"}})
+		return
+	}
+
+	input := usecase.GetOrderByIDInputDTO{ID: id}
+	output, err := h.GetOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		log.Printf("Error getting order %s: %v", id, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Order %s retrieved successfully", id)
+}
+
+// GetOrderByOrderID handles the retrieval of an order by its business OrderID.
+func (h *OrderHandler) GetOrderByOrderID(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Received request to get order by OrderID")
 	orderIDStr := chi.URLParam(r, "orderId")
 	orderID, err := strconv.Atoi(orderIDStr)
 	if err != nil {
 		log.Printf("Invalid Order ID: %s", orderIDStr)
-		http.Error(w, "Invalid Order ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
 		return
 	}
 
-	input := usecase.GetOrderByIDInputDTO{OrderID: orderID}
-	output, err := h.GetOrderUseCase.Execute(input)
+	input := usecase.GetOrderByOrderIDInputDTO{OrderID: orderID}
+	output, err := h.GetOrderByOrderIDUseCase.Execute(r.Context(), input)
 	if err != nil {
 		log.Printf("Error getting order %d: %v", orderID, err)
-		// In a real application, you would check for a 'not found' error and return 404
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -74,25 +111,169 @@ func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Order %d retrieved successfully", orderID)
 }
 
-// GetAllOrders handles the retrieval of all orders.
+// GetAllOrders handles the retrieval of a page of orders.
 // @Summary Get all orders
-// @Description Retrieve all orders
+// @Description Retrieve a filtered, sorted page of orders
 // @Tags orders
 // @Produce json
-// @Success 200 {array} entity.Order
-// @Failure 500 {object} map[string]string
+// @Param status query string false "Only orders with this status"
+// @Param paid query bool false "Only paid or unpaid orders"
+// @Param created_from query string false "Only orders created at or after this RFC 3339 time"
+// @Param created_to query string false "Only orders created before this RFC 3339 time"
+// @Param sort query string false "created_at (default), updated_at or order_id"
+// @Param direction query string false "asc or desc (default)"
+// @Param limit query int false "Page size, 1 to 100 (default 50)"
+// @Param cursor query string false "next_cursor from the previous page"
+// @Success 200 {object} usecase.GetAllOrdersOutputDTO
+// @Failure 400 {object} Problem
+// @Failure 422 {object} Problem
+// @Failure 500 {object} Problem
 // @Router /orders [get]
 func (h *OrderHandler) GetAllOrders(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Received request to get all orders")
-	orders, err := h.GetAllOrdersUseCase.Execute()
+	input, err := parseGetAllOrdersInput(r.URL.Query())
+	if err != nil {
+		log.Printf("Invalid order listing query: %v", err)
+		var fieldErrors validation.Errors
+		errors.As(err, &fieldErrors)
+		writeProblem(w, r, http.StatusBadRequest, "The query has malformed parameters.", fieldErrors)
+		return
+	}
+
+	output, err := h.GetAllOrdersUseCase.Execute(r.Context(), input)
 	if err != nil {
 		log.Printf("Error getting all orders: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Retrieved %d orders successfully", len(output.Orders))
+}
+
+// parseGetAllOrdersInput reads the order listing query parameters. Parameters
+// that cannot be parsed are reported as field errors.
+func parseGetAllOrdersInput(values url.Values) (usecase.GetAllOrdersInputDTO, error) {
+	input := usecase.GetAllOrdersInputDTO{
+		Status:        values.Get("status"),
+		SortBy:        values.Get("sort"),
+		SortDirection: values.Get("direction"),
+		Cursor:        values.Get("cursor"),
+	}
+
+	var v validation.Validator
+	if value := values.Get("paid"); value != "" {
+		paid, err := strconv.ParseBool(value)
+		v.Check(err == nil, "paid", "must be true or false")
+		input.Paid = &paid
+	}
+	if value := values.Get("created_from"); value != "" {
+		createdFrom, err := time.Parse(time.RFC3339, value)
+		v.Check(err == nil, "created_from", "must be an RFC 3339 time")
+		input.CreatedFrom = createdFrom
+	}
+	if value := values.Get("created_to"); value != "" {
+		createdTo, err := time.Parse(time.RFC3339, value)
+		v.Check(err == nil, "created_to", "must be an RFC 3339 time")
+		input.CreatedTo = createdTo
+	}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		v.Check(err == nil, "limit", "must be an integer")
+		input.Limit = limit
+	}
+	return input, v.Err()
+}
+
+// UpdateOrder handles the replacement of an order's data and status.
+func (h *OrderHandler) UpdateOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderId")
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		log.Printf("Invalid Order ID: %s", orderIDStr)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
+		return
+	}
+
+	var input usecase.UpdateOrderInputDTO
+	if err := decodeJSON(w, r, &input); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+	input.OrderID = orderID
+
+	output, err := h.UpdateOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		log.Printf("Error updating order %d: %v", orderID, err)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(orders)
-	log.Printf("All orders retrieved successfully")
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Order %d updated successfully", orderID)
+}
+
+// PatchOrder handles a JSON Merge Patch (RFC 7386) of an order.
+func (h *OrderHandler) PatchOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderId")
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		log.Printf("Invalid Order ID: %s", orderIDStr)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
+		return
+	}
+
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			writeProblem(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json", nil)
+			return
+		}
+	}
+
+	var patch json.RawMessage
+	if err := decodeJSON(w, r, &patch); err != nil {
+		log.Printf("Invalid merge patch for order %d: %v", orderID, err)
+		writeDecodeError(w, r, err)
+		return
+	}
+
+	input := usecase.PatchOrderInputDTO{OrderID: orderID, Patch: patch}
+	output, err := h.PatchOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		log.Printf("Error patching order %d: %v", orderID, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Order %d patched successfully", orderID)
+}
+
+// DeleteOrder handles the deletion of an order.
+func (h *OrderHandler) DeleteOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderId")
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		log.Printf("Invalid Order ID: %s", orderIDStr)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
+		return
+	}
+
+	input := usecase.DeleteOrderInputDTO{OrderID: orderID}
+	if err := h.DeleteOrderUseCase.Execute(r.Context(), input); err != nil {
+		log.Printf("Error deleting order %d: %v", orderID, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	log.Printf("Order %d deleted successfully", orderID)
 }
@@ -4,9 +4,9 @@ import (
 	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/infra/database"
 	"GoCleanArch/internal/infra/handler"
-	"GoCleanArch/internal/infra/messaging"
 	"GoCleanArch/internal/usecase"
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -22,29 +22,41 @@ func TestOrderHandler(t *testing.T) {
 	RunSpecs(t, "OrderHandler Suite")
 }
 
+// prePopulatedOrderID is the internal ID of the order the tests start with.
+const prePopulatedOrderID = "0197a3c0-0000-7000-8000-000000000123"
+
 var _ = Describe("OrderHandler", func() {
 	var (
 		orderHandler *handler.OrderHandler
+		orderRepo    *database.OrderRepositoryMock
 		router       *chi.Mux
 	)
 
 	BeforeEach(func() {
-		orderRepo := database.NewOrderRepositoryMock()
-		messageQueue := messaging.NewOrderMessageQueueMock()
+		orderRepo = database.NewOrderRepositoryMock()
 
-		createOrderUseCase := usecase.NewCreateOrderUseCase(messageQueue)
+		createOrderUseCase := usecase.NewCreateOrderUseCase(orderRepo)
 		getOrderUseCase := usecase.NewGetOrderByIDUseCase(orderRepo)
+		getOrderByOrderIDUseCase := usecase.NewGetOrderByOrderIDUseCase(orderRepo)
 
 		// Pre-populate data for GET tests
-		prePopulatedOrder := &entity.Order{OrderID: 123, Status: "Complete", Paid: true}
-		orderRepo.Save(prePopulatedOrder)
+		prePopulatedOrder := &entity.Order{ID: prePopulatedOrderID, OrderID: 123, Status: entity.OrderStatusDelivered, Paid: true}
+		orderRepo.Save(context.Background(), prePopulatedOrder)
 
 		getAllOrdersUseCase := usecase.NewGetAllOrdersUseCase(orderRepo)
-		orderHandler = handler.NewOrderHandler(createOrderUseCase, getOrderUseCase, getAllOrdersUseCase)
+		updateOrderUseCase := usecase.NewUpdateOrderUseCase(orderRepo, orderRepo)
+		patchOrderUseCase := usecase.NewPatchOrderUseCase(orderRepo, orderRepo)
+		deleteOrderUseCase := usecase.NewDeleteOrderUseCase(orderRepo)
+		orderHandler = handler.NewOrderHandler(createOrderUseCase, getOrderUseCase, getOrderByOrderIDUseCase, getAllOrdersUseCase, updateOrderUseCase, patchOrderUseCase, deleteOrderUseCase)
 
 		router = chi.NewRouter()
 		router.Post("/orders", orderHandler.CreateOrder)
-		router.Get("/orders/{orderId}", orderHandler.GetOrder)
+		router.Get("/orders/{id}", orderHandler.GetOrder)
+		router.Get("/orders/by-order-id/{orderId}", orderHandler.GetOrderByOrderID)
+		router.Get("/orders", orderHandler.GetAllOrders)
+		router.Put("/orders/by-order-id/{orderId}", orderHandler.UpdateOrder)
+		router.Patch("/orders/by-order-id/{orderId}", orderHandler.PatchOrder)
+		router.Delete("/orders/by-order-id/{orderId}", orderHandler.DeleteOrder)
 	})
 
 	Describe("GET /orders", func() {
@@ -56,11 +68,63 @@ var _ = Describe("OrderHandler", func() {
 				router.ServeHTTP(rr, req)
 
 				Expect(rr.Code).To(Equal(http.StatusOK))
-				var response []entity.Order
+				var response usecase.GetAllOrdersOutputDTO
 				err := json.Unmarshal(rr.Body.Bytes(), &response)
 				Expect(err).To(BeNil())
-				Expect(len(response)).To(BeNumerically(">=", 1))
-				Expect(response[0].OrderID).To(Equal(123))
+				Expect(len(response.Orders)).To(BeNumerically(">=", 1))
+				Expect(response.Orders[0].OrderID).To(Equal(123))
+			})
+		})
+
+		Context("with filters and a page size", func() {
+			It("should return 200 OK, the matching orders and a cursor for the next page", func() {
+				orderRepo.Save(context.Background(), &entity.Order{ID: "order-200", OrderID: 200, Status: entity.OrderStatusPending})
+				orderRepo.Save(context.Background(), &entity.Order{ID: "order-201", OrderID: 201, Status: entity.OrderStatusPending})
+
+				req := httptest.NewRequest("GET", "/orders?status=Pending&sort=order_id&direction=asc&limit=1", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				var response usecase.GetAllOrdersOutputDTO
+				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.Orders).To(HaveLen(1))
+				Expect(response.Orders[0].OrderID).To(Equal(200))
+				Expect(response.NextCursor).NotTo(BeEmpty())
+
+				req = httptest.NewRequest("GET", "/orders?status=Pending&sort=order_id&direction=asc&limit=1&cursor="+response.NextCursor, nil)
+				rr = httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				response = usecase.GetAllOrdersOutputDTO{}
+				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.Orders).To(HaveLen(1))
+				Expect(response.Orders[0].OrderID).To(Equal(201))
+				Expect(response.NextCursor).To(BeEmpty())
+			})
+		})
+
+		Context("with a malformed query parameter", func() {
+			It("should return 400 Bad Request", func() {
+				req := httptest.NewRequest("GET", "/orders?limit=ten", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+
+		Context("with an unknown sort field", func() {
+			It("should return 422 Unprocessable Entity", func() {
+				req := httptest.NewRequest("GET", "/orders?sort=data", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
 			})
 		})
 	})
@@ -68,7 +132,7 @@ var _ = Describe("OrderHandler", func() {
 	Describe("POST /orders", func() {
 		Context("with a valid request body", func() {
 			It("should return 201 Created and the created order", func() {
-				orderData := map[string]interface{}{"Data": "23/06/2025", "OrderId": 456, "Status": "New"}
+				orderData := map[string]interface{}{"Data": "23/06/2025", "OrderId": 456, "Status": "Pending"}
 				body, _ := json.Marshal(orderData)
 
 				req := httptest.NewRequest("POST", "/orders", bytes.NewBuffer(body))
@@ -84,6 +148,44 @@ var _ = Describe("OrderHandler", func() {
 			})
 		})
 
+		Context("with line items", func() {
+			It("should return 201 Created with the totals and serve them on GET", func() {
+				body := `{"OrderId":460,"items":[{"sku":"BOOK","description":"Clean Architecture","quantity":2,"unit_price":{"amount":2500,"currency":"USD"}}],"tax_rate_bps":1000}`
+				req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusCreated))
+				var created usecase.CreateOrderOutputDTO
+				Expect(json.Unmarshal(rr.Body.Bytes(), &created)).To(Succeed())
+				Expect(created.Total).To(Equal(entity.Money{Amount: 5500, Currency: "USD"}))
+
+				rr = httptest.NewRecorder()
+				router.ServeHTTP(rr, httptest.NewRequest("GET", "/orders/"+created.ID, nil))
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				var found usecase.GetOrderByIDOutputDTO
+				Expect(json.Unmarshal(rr.Body.Bytes(), &found)).To(Succeed())
+				Expect(found.Items).To(HaveLen(1))
+				Expect(found.Subtotal).To(Equal(entity.Money{Amount: 5000, Currency: "USD"}))
+				Expect(found.Tax).To(Equal(entity.Money{Amount: 500, Currency: "USD"}))
+				Expect(found.Total).To(Equal(entity.Money{Amount: 5500, Currency: "USD"}))
+			})
+		})
+
+		Context("with an unknown status", func() {
+			It("should return 422 Unprocessable Entity", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "23/06/2025", "OrderId": 457, "Status": "Lost"})
+				req := httptest.NewRequest("POST", "/orders", bytes.NewBuffer(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+			})
+		})
+
 		Context("with an invalid request body", func() {
 			It("should return 400 Bad Request", func() {
 				req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString("invalid json"))
@@ -94,12 +196,51 @@ var _ = Describe("OrderHandler", func() {
 				Expect(rr.Code).To(Equal(http.StatusBadRequest))
 			})
 		})
+
+		Context("with unknown fields or trailing data", func() {
+			DescribeTable("should return a 400 problem",
+				func(body string) {
+					req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(body))
+					rr := httptest.NewRecorder()
+
+					router.ServeHTTP(rr, req)
+
+					Expect(rr.Code).To(Equal(http.StatusBadRequest))
+					Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+				},
+				Entry("unknown field", `{"Data":"23/06/2025","OrderId":458,"Paid":true}`),
+				Entry("trailing garbage", `{"Data":"23/06/2025","OrderId":458} {"x":1}`),
+				Entry("wrong type", `{"Data":"23/06/2025","OrderId":"458"}`),
+			)
+		})
+
+		Context("with invalid fields", func() {
+			It("should return a 422 problem listing every field error", func() {
+				req := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(`{"Data":"","OrderId":0,"Status":"Lost"}`))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+
+				var problem handler.Problem
+				Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+				Expect(problem.Status).To(Equal(http.StatusUnprocessableEntity))
+				Expect(problem.Instance).To(Equal("/orders"))
+				fields := make([]string, 0, len(problem.Errors))
+				for _, fieldError := range problem.Errors {
+					fields = append(fields, fieldError.Field)
+				}
+				Expect(fields).To(ConsistOf("OrderId", "Data", "Status"))
+			})
+		})
 	})
 
-	Describe("GET /orders/{orderId}", func() {
+	Describe("GET /orders/{id}", func() {
 		Context("when the order exists", func() {
 			It("should return 200 OK and the order details", func() {
-				req := httptest.NewRequest("GET", "/orders/123", nil)
+				req := httptest.NewRequest("GET", "/orders/"+prePopulatedOrderID, nil)
 				rr := httptest.NewRecorder()
 
 				router.ServeHTTP(rr, req)
@@ -108,21 +249,204 @@ var _ = Describe("OrderHandler", func() {
 
 				var response usecase.GetOrderByIDOutputDTO
 				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.ID).To(Equal(prePopulatedOrderID))
 				Expect(response.OrderID).To(Equal(123))
-				Expect(response.Status).To(Equal("Complete"))
+				Expect(response.Status).To(Equal(entity.OrderStatusDelivered))
 				Expect(response.Paid).To(BeTrue())
 			})
 		})
 
+		Context("when the ID is not a UUID", func() {
+			It("should return a 400 problem", func() {
+				req := httptest.NewRequest("GET", "/orders/123", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+			})
+		})
+
+		Context("when the order does not exist", func() {
+			It("should return 404 Not Found", func() {
+				req := httptest.NewRequest("GET", "/orders/0197a3c0-0000-7000-8000-000000000999", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("GET /orders/by-order-id/{orderId}", func() {
+		Context("when the order exists", func() {
+			It("should return 200 OK and the order details", func() {
+				req := httptest.NewRequest("GET", "/orders/by-order-id/123", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+
+				var response usecase.GetOrderByIDOutputDTO
+				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.ID).To(Equal(prePopulatedOrderID))
+				Expect(response.OrderID).To(Equal(123))
+			})
+		})
+
+		Context("when the order ID is not a number", func() {
+			It("should return a 400 problem", func() {
+				req := httptest.NewRequest("GET", "/orders/by-order-id/abc", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+			})
+		})
+
 		Context("when the order does not exist", func() {
-			It("should return 500 Internal Server Error", func() {
-				req := httptest.NewRequest("GET", "/orders/999", nil)
+			It("should return 404 Not Found", func() {
+				req := httptest.NewRequest("GET", "/orders/by-order-id/999", nil)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+	})
+
+	Describe("PUT /orders/by-order-id/{orderId}", func() {
+		BeforeEach(func() {
+			orderRepo.Save(context.Background(), &entity.Order{ID: "order-124", OrderID: 124, Data: "24/06/2025", Status: entity.OrderStatusPending})
+		})
+
+		Context("with an allowed status change", func() {
+			It("should return 200 OK and the updated order", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "25/06/2025", "Status": "Cancelled"})
+				req := httptest.NewRequest("PUT", "/orders/by-order-id/124", bytes.NewBuffer(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				var response usecase.UpdateOrderOutputDTO
+				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.OrderID).To(Equal(124))
+				Expect(response.Data).To(Equal("25/06/2025"))
+				Expect(response.Status).To(Equal(entity.OrderStatusCancelled))
+			})
+		})
+
+		Context("with a status only a payment can set", func() {
+			It("should return 422 Unprocessable Entity", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "25/06/2025", "Status": "Paid"})
+				req := httptest.NewRequest("PUT", "/orders/by-order-id/124", bytes.NewBuffer(body))
 				rr := httptest.NewRecorder()
 
 				router.ServeHTTP(rr, req)
 
-				Expect(rr.Code).To(Equal(http.StatusInternalServerError))
+				Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
 			})
 		})
+
+		Context("with an illegal status change", func() {
+			It("should return 409 Conflict", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "25/06/2025", "Status": "Delivered"})
+				req := httptest.NewRequest("PUT", "/orders/by-order-id/124", bytes.NewBuffer(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("when the order does not exist", func() {
+			It("should return 404 Not Found", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "25/06/2025", "Status": "Paid"})
+				req := httptest.NewRequest("PUT", "/orders/by-order-id/999", bytes.NewBuffer(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("on the server-generated id", func() {
+			It("should return 405 Method Not Allowed", func() {
+				body, _ := json.Marshal(map[string]interface{}{"Data": "25/06/2025", "Status": "Cancelled"})
+				req := httptest.NewRequest("PUT", "/orders/order-124", bytes.NewBuffer(body))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusMethodNotAllowed))
+			})
+		})
+	})
+
+	Describe("PATCH /orders/by-order-id/{orderId}", func() {
+		BeforeEach(func() {
+			orderRepo.Save(context.Background(), &entity.Order{ID: "order-125", OrderID: 125, Data: "26/06/2025", Status: entity.OrderStatusPending})
+		})
+
+		Context("with a merge patch", func() {
+			It("should return 200 OK and keep the fields it does not mention", func() {
+				req := httptest.NewRequest("PATCH", "/orders/by-order-id/125", bytes.NewBufferString(`{"Status":"Cancelled"}`))
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusOK))
+				var response usecase.UpdateOrderOutputDTO
+				json.Unmarshal(rr.Body.Bytes(), &response)
+				Expect(response.Status).To(Equal(entity.OrderStatusCancelled))
+				Expect(response.Data).To(Equal("26/06/2025"))
+			})
+		})
+
+		Context("with an unsupported content type", func() {
+			It("should return 415 Unsupported Media Type", func() {
+				req := httptest.NewRequest("PATCH", "/orders/by-order-id/125", bytes.NewBufferString(`[]`))
+				req.Header.Set("Content-Type", "application/json-patch+json")
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusUnsupportedMediaType))
+			})
+		})
+	})
+
+	Describe("DELETE /orders/by-order-id/{orderId}", func() {
+		It("should return 204 No Content and remove the order", func() {
+			req := httptest.NewRequest("DELETE", "/orders/by-order-id/123", nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			Expect(rr.Code).To(Equal(http.StatusNoContent))
+
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest("GET", "/orders/by-order-id/123", nil))
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should return 404 Not Found for a missing order", func() {
+			req := httptest.NewRequest("DELETE", "/orders/by-order-id/999", nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			Expect(rr.Code).To(Equal(http.StatusNotFound))
+		})
 	})
 })
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"GoCleanArch/internal/usecase"
+	"GoCleanArch/internal/validation"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PaymentHandler handles HTTP requests for the payments and refunds of an order.
+type PaymentHandler struct {
+	PayOrderUseCase    *usecase.PayOrderUseCase
+	RefundOrderUseCase *usecase.RefundOrderUseCase
+}
+
+// NewPaymentHandler creates a new PaymentHandler.
+func NewPaymentHandler(payOrderUseCase *usecase.PayOrderUseCase, refundOrderUseCase *usecase.RefundOrderUseCase) *PaymentHandler {
+	return &PaymentHandler{PayOrderUseCase: payOrderUseCase, RefundOrderUseCase: refundOrderUseCase}
+}
+
+// PayOrder handles paying for an order. A declined payment is answered with
+// 402 Payment Required.
+func (h *PaymentHandler) PayOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderId")
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		log.Printf("Invalid Order ID: %s", orderIDStr)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
+		return
+	}
+
+	var input usecase.PayOrderInputDTO
+	if err := decodeJSON(w, r, &input); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+	input.OrderID = orderID
+
+	output, err := h.PayOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		log.Printf("Error paying for order %d: %v", orderID, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Order %d paid with payment %s", orderID, output.ID)
+}
+
+// RefundOrder handles refunding part or all of an order's payment.
+func (h *PaymentHandler) RefundOrder(w http.ResponseWriter, r *http.Request) {
+	orderIDStr := chi.URLParam(r, "orderId")
+	orderID, err := strconv.Atoi(orderIDStr)
+	if err != nil {
+		log.Printf("Invalid Order ID: %s", orderIDStr)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid Order ID", []validation.FieldError{{Field: "orderId", Message: "must be an integer"}})
+		return
+	}
+
+	var input usecase.RefundOrderInputDTO
+	if err := decodeJSON(w, r, &input); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		writeDecodeError(w, r, err)
+		return
+	}
+	input.OrderID = orderID
+
+	output, err := h.RefundOrderUseCase.Execute(r.Context(), input)
+	if err != nil {
+		log.Printf("Error refunding order %d: %v", orderID, err)
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(output)
+	log.Printf("Order %d refunded %d %s with refund %s", orderID, output.Amount.Amount, output.Amount.Currency, output.ID)
+}
@@ -0,0 +1,217 @@
+package handler_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/handler"
+	"GoCleanArch/internal/infra/payment"
+	"GoCleanArch/internal/usecase"
+	"GoCleanArch/internal/validation"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// lostPaymentRepository is a payment repository that loses every payment it saves.
+type lostPaymentRepository struct {
+	*database.PaymentRepositoryMock
+}
+
+func (r lostPaymentRepository) Update(ctx context.Context, payment *entity.Payment) error {
+	return domain.ErrPaymentNotFound
+}
+
+var _ = Describe("PaymentHandler", func() {
+	var (
+		router      *chi.Mux
+		orderRepo   *database.OrderRepositoryMock
+		paymentRepo *database.PaymentRepositoryMock
+		gateway     *payment.PaymentGatewayMock
+	)
+
+	BeforeEach(func() {
+		orderRepo = database.NewOrderRepositoryMock()
+		order := &entity.Order{
+			ID:      "order-700",
+			OrderID: 700,
+			Status:  entity.OrderStatusPending,
+			Items:   []entity.OrderItem{{SKU: "MUG", Quantity: 1, UnitPrice: entity.Money{Amount: 900, Currency: "EUR"}}},
+		}
+		Expect(order.CalculateTotals()).To(Succeed())
+		Expect(orderRepo.Save(context.Background(), order)).To(Succeed())
+
+		gateway = payment.NewPaymentGatewayMock(map[string]string{"tok_declined": "card_declined"})
+		paymentRepo = database.NewPaymentRepositoryMock()
+		payOrderUseCase := usecase.NewPayOrderUseCase(orderRepo, paymentRepo, gateway, orderRepo)
+		refundRepo := database.NewRefundRepositoryMock()
+		orderRepo.Refunds = refundRepo
+		refundOrderUseCase := usecase.NewRefundOrderUseCase(orderRepo, paymentRepo, refundRepo, gateway, orderRepo)
+		paymentHandler := handler.NewPaymentHandler(payOrderUseCase, refundOrderUseCase)
+
+		router = chi.NewRouter()
+		router.Post("/orders/by-order-id/{orderId}/payments", paymentHandler.PayOrder)
+		router.Post("/orders/by-order-id/{orderId}/refunds", paymentHandler.RefundOrder)
+	})
+
+	Describe("POST /orders/by-order-id/{orderId}/payments", func() {
+		Context("when the payment is approved", func() {
+			It("should return 201 Created and the captured payment", func() {
+				req := httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusCreated))
+				var response usecase.PaymentOutputDTO
+				Expect(json.Unmarshal(rr.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Status).To(Equal(entity.PaymentStatusCaptured))
+				Expect(response.Amount).To(Equal(entity.Money{Amount: 900, Currency: "EUR"}))
+			})
+		})
+
+		Context("when the payment is declined", func() {
+			It("should return a 402 problem", func() {
+				req := httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_declined"}`))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusPaymentRequired))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+				var problem handler.Problem
+				Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+				Expect(problem.Detail).To(ContainSubstring("card_declined"))
+			})
+		})
+
+		Context("when the order is already paid", func() {
+			It("should return 409 Conflict", func() {
+				router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("with an invalid order ID or body", func() {
+			DescribeTable("should return a 400 problem",
+				func(path, body string) {
+					rr := httptest.NewRecorder()
+
+					router.ServeHTTP(rr, httptest.NewRequest("POST", path, bytes.NewBufferString(body)))
+
+					Expect(rr.Code).To(Equal(http.StatusBadRequest))
+				},
+				Entry("order ID that is not a number", "/orders/by-order-id/abc/payments", `{"payment_method":"tok_visa"}`),
+				Entry("unknown field", "/orders/by-order-id/700/payments", `{"payment_method":"tok_visa","amount":1}`),
+			)
+		})
+
+		Context("for a missing order", func() {
+			It("should return 404 Not Found", func() {
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/999/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+			})
+		})
+
+		Context("when the payment is no longer stored", func() {
+			It("should return a 404 problem", func() {
+				payOrderUseCase := usecase.NewPayOrderUseCase(orderRepo, lostPaymentRepository{paymentRepo}, gateway, orderRepo)
+				router.Post("/lost/{orderId}/payments", handler.NewPaymentHandler(payOrderUseCase, nil).PayOrder)
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/lost/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+				Expect(rr.Header().Get("Content-Type")).To(Equal("application/problem+json"))
+				var problem handler.Problem
+				Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+				Expect(problem.Detail).To(Equal("payment not found"))
+			})
+		})
+	})
+
+	Describe("POST /orders/by-order-id/{orderId}/refunds", func() {
+		Context("when the order has been paid", func() {
+			BeforeEach(func() {
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+				Expect(rr.Code).To(Equal(http.StatusCreated))
+			})
+
+			It("should return 201 Created and the partial refund", func() {
+				req := httptest.NewRequest("POST", "/orders/by-order-id/700/refunds", bytes.NewBufferString(`{"amount":400,"reason":"chipped"}`))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusCreated))
+				var response usecase.RefundOutputDTO
+				Expect(json.Unmarshal(rr.Body.Bytes(), &response)).To(Succeed())
+				Expect(response.Amount).To(Equal(entity.Money{Amount: 400, Currency: "EUR"}))
+				Expect(response.OrderStatus).To(Equal(entity.OrderStatusPartiallyRefunded))
+			})
+
+			It("should return a 422 problem for more than was captured", func() {
+				req := httptest.NewRequest("POST", "/orders/by-order-id/700/refunds", bytes.NewBufferString(`{"amount":901}`))
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, req)
+
+				Expect(rr.Code).To(Equal(http.StatusUnprocessableEntity))
+				var problem handler.Problem
+				Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+				Expect(problem.Errors).To(ConsistOf(validation.FieldError{Field: "amount", Message: "must not exceed the refundable amount of 900"}))
+			})
+		})
+
+		Context("when the refund is no longer stored", func() {
+			It("should return a 404 problem", func() {
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/700/payments", bytes.NewBufferString(`{"payment_method":"tok_visa"}`)))
+				Expect(rr.Code).To(Equal(http.StatusCreated))
+				orderRepo.Refunds = database.NewRefundRepositoryMock()
+				rr = httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/700/refunds", bytes.NewBufferString(`{}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusNotFound))
+				var problem handler.Problem
+				Expect(json.Unmarshal(rr.Body.Bytes(), &problem)).To(Succeed())
+				Expect(problem.Detail).To(Equal("refund not found"))
+			})
+		})
+
+		Context("when the order has not been paid", func() {
+			It("should return 409 Conflict", func() {
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/700/refunds", bytes.NewBufferString(`{}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusConflict))
+			})
+		})
+
+		Context("with an invalid order ID", func() {
+			It("should return a 400 problem", func() {
+				rr := httptest.NewRecorder()
+
+				router.ServeHTTP(rr, httptest.NewRequest("POST", "/orders/by-order-id/abc/refunds", bytes.NewBufferString(`{}`)))
+
+				Expect(rr.Code).To(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+})
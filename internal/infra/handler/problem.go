@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/validation"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// problemContentType is the media type of RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Errors is an extension
+// member that lists every invalid field of a rejected request.
+type Problem struct {
+	Type     string                  `json:"type"`
+	Title    string                  `json:"title"`
+	Status   int                     `json:"status"`
+	Detail   string                  `json:"detail,omitempty"`
+	Instance string                  `json:"instance,omitempty"`
+	Errors   []validation.FieldError `json:"errors,omitempty"`
+}
+
+// writeProblem writes a problem details response for the request.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string, fieldErrors []validation.FieldError) {
+	problem := Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Errors:   fieldErrors,
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+// statusForError maps a domain error to the HTTP status code that describes it.
+func statusForError(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrOrderNotFound), errors.Is(err, domain.ErrPaymentNotFound), errors.Is(err, domain.ErrRefundNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return http.StatusPaymentRequired
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError writes err as a problem with the status code that matches it.
+// Field errors are listed individually and unexpected errors are reported
+// without their details.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := statusForError(err)
+	if status == http.StatusInternalServerError {
+		writeProblem(w, r, status, "", nil)
+		return
+	}
+
+	var fieldErrors validation.Errors
+	if errors.As(err, &fieldErrors) {
+		writeProblem(w, r, status, "The request has invalid fields.", fieldErrors)
+		return
+	}
+	writeProblem(w, r, status, err.Error(), nil)
+}
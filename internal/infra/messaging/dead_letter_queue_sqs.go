@@ -0,0 +1,264 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// sqsScanVisibilityTimeout is how long a scan hides the dead letters it has
+// received, so that it doesn't receive them twice while it reads the rest of
+// the queue.
+const sqsScanVisibilityTimeout = time.Minute
+
+// DeadLetter is a message on a dead-letter queue, with the body and attributes
+// it was published with. ReceiveCount is how many times it has been delivered.
+type DeadLetter struct {
+	ID           string
+	Type         string
+	SentAt       time.Time
+	ReceiveCount int
+	Body         []byte
+	Attributes   map[string]string
+}
+
+// DeadLetterQueueSQS is the dead-letter queue of an SQS order queue, the
+// source queue. Once Attach has set the source queue's redrive policy, SQS
+// moves a message there when it has been delivered too many times.
+//
+// SQS has no way to read a queue without receiving from it, so List, Redrive
+// and Purge receive every dead letter, hiding them for VisibilityTimeout, and
+// make the ones they leave on the queue visible again when they are done. A
+// dead letter that becomes visible again before a scan is done is received
+// twice but listed once.
+type DeadLetterQueueSQS struct {
+	Client         *sqs.Client
+	QueueURL       string
+	SourceQueueURL string
+	// VisibilityTimeout is how long a scan hides the dead letters it receives, in whole seconds.
+	VisibilityTimeout time.Duration
+}
+
+// NewDeadLetterQueueSQS creates a new SQS dead-letter queue for the queue at sourceQueueURL.
+func NewDeadLetterQueueSQS(client *sqs.Client, queueURL, sourceQueueURL string) *DeadLetterQueueSQS {
+	return &DeadLetterQueueSQS{Client: client, QueueURL: queueURL, SourceQueueURL: sourceQueueURL, VisibilityTimeout: sqsScanVisibilityTimeout}
+}
+
+// Attach sets the redrive policy of the source queue so that SQS moves a
+// message to the dead-letter queue once it has been delivered maxReceives
+// times. It leaves a policy that already does so alone and reports whether it
+// changed the policy.
+func (d *DeadLetterQueueSQS) Attach(ctx context.Context, maxReceives int) (bool, error) {
+	if maxReceives < 1 {
+		return false, errors.New("the max receive count must be at least 1")
+	}
+	out, err := d.Client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       &d.QueueURL,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return false, err
+	}
+	arn := out.Attributes[string(types.QueueAttributeNameQueueArn)]
+
+	source, err := d.Client.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       &d.SourceQueueURL,
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameRedrivePolicy},
+	})
+	if err != nil {
+		return false, err
+	}
+	if current := source.Attributes[string(types.QueueAttributeNameRedrivePolicy)]; current != "" {
+		// SQS returns maxReceiveCount as a number, though it takes a string.
+		var policy map[string]interface{}
+		if err := json.Unmarshal([]byte(current), &policy); err == nil &&
+			policy["deadLetterTargetArn"] == arn && fmt.Sprint(policy["maxReceiveCount"]) == strconv.Itoa(maxReceives) {
+			return false, nil
+		}
+	}
+
+	policy, err := json.Marshal(map[string]string{
+		"deadLetterTargetArn": arn,
+		"maxReceiveCount":     strconv.Itoa(maxReceives),
+	})
+	if err != nil {
+		return false, err
+	}
+	_, err = d.Client.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl:   &d.SourceQueueURL,
+		Attributes: map[string]string{string(types.QueueAttributeNameRedrivePolicy): string(policy)},
+	})
+	return err == nil, err
+}
+
+// List returns up to limit dead letters, or all of them when limit is 0. It
+// fails when the dead letters cannot be made visible again.
+func (d *DeadLetterQueueSQS) List(ctx context.Context, limit int) ([]*DeadLetter, error) {
+	messages, err := d.scan(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.release(context.WithoutCancel(ctx), messages); err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]*DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		deadLetters = append(deadLetters, sqsDeadLetter(m))
+	}
+	return deadLetters, nil
+}
+
+// Redrive sends the dead letters with the given IDs, or every dead letter
+// when no ID is given, back to the source queue with their original body and
+// attributes, and deletes them from the dead-letter queue. It returns how many
+// messages it moved. SQS gives a redriven message a new ID.
+func (d *DeadLetterQueueSQS) Redrive(ctx context.Context, ids []string) (int, error) {
+	return d.each(ctx, ids, func(m types.Message) error {
+		_, err := d.Client.SendMessage(ctx, &sqs.SendMessageInput{
+			QueueUrl:          &d.SourceQueueURL,
+			MessageBody:       m.Body,
+			MessageAttributes: m.MessageAttributes,
+		})
+		if err != nil {
+			return err
+		}
+		return d.delete(ctx, m)
+	})
+}
+
+// Purge deletes the dead letters with the given IDs, or every dead letter
+// when no ID is given. It returns how many messages it deleted.
+func (d *DeadLetterQueueSQS) Purge(ctx context.Context, ids []string) (int, error) {
+	return d.each(ctx, ids, func(m types.Message) error {
+		return d.delete(ctx, m)
+	})
+}
+
+// each calls fn for the dead letters with the given IDs, or for every dead
+// letter when no ID is given, until it fails. It returns how many calls
+// succeeded, and an error also when the dead letters it leaves cannot be made
+// visible again.
+func (d *DeadLetterQueueSQS) each(ctx context.Context, ids []string, fn func(types.Message) error) (done int, err error) {
+	messages, err := d.scan(ctx, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	var untouched []types.Message
+	defer func() { err = errors.Join(err, d.release(context.WithoutCancel(ctx), untouched)) }()
+
+	for i, m := range messages {
+		if len(ids) > 0 && !slices.Contains(ids, aws.ToString(m.MessageId)) {
+			untouched = append(untouched, m)
+			continue
+		}
+		if err := fn(m); err != nil {
+			untouched = append(untouched, messages[i:]...)
+			return done, err
+		}
+		done++
+	}
+	return done, nil
+}
+
+// scan receives up to limit dead letters, or all of them when limit is 0,
+// and leaves them hidden. A dead letter received again keeps its place and
+// takes the new receipt handle, as the old one no longer works; the scan ends
+// when a receive returns no dead letter it has not seen.
+func (d *DeadLetterQueueSQS) scan(ctx context.Context, limit int) ([]types.Message, error) {
+	var messages []types.Message
+	seen := make(map[string]int)
+	for limit == 0 || len(messages) < limit {
+		maxMessages := int32(sqsMaxMessages)
+		if limit > 0 {
+			maxMessages = int32(min(sqsMaxMessages, limit-len(messages)))
+		}
+		out, err := d.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:              &d.QueueURL,
+			MaxNumberOfMessages:   maxMessages,
+			VisibilityTimeout:     int32(d.VisibilityTimeout / time.Second),
+			WaitTimeSeconds:       1,
+			MessageAttributeNames: []string{sqsAllAttributes},
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameApproximateReceiveCount,
+				types.MessageSystemAttributeNameSentTimestamp,
+			},
+		})
+		if err != nil {
+			return nil, errors.Join(err, d.release(context.WithoutCancel(ctx), messages))
+		}
+		received := 0
+		for _, m := range out.Messages {
+			if i, ok := seen[aws.ToString(m.MessageId)]; ok {
+				messages[i] = m
+				continue
+			}
+			seen[aws.ToString(m.MessageId)] = len(messages)
+			messages = append(messages, m)
+			received++
+		}
+		if received == 0 {
+			break
+		}
+	}
+	return messages, nil
+}
+
+// release makes received dead letters visible again. A dead letter it fails
+// to release is visible again after VisibilityTimeout anyway.
+func (d *DeadLetterQueueSQS) release(ctx context.Context, messages []types.Message) error {
+	var errs []error
+	for batch := range slices.Chunk(messages, sqsMaxMessages) {
+		entries := make([]types.ChangeMessageVisibilityBatchRequestEntry, 0, len(batch))
+		for i, m := range batch {
+			entries = append(entries, types.ChangeMessageVisibilityBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(i)),
+				ReceiptHandle: m.ReceiptHandle,
+			})
+		}
+		out, err := d.Client.ChangeMessageVisibilityBatch(ctx, &sqs.ChangeMessageVisibilityBatchInput{QueueUrl: &d.QueueURL, Entries: entries})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("releasing dead letters: %w", err))
+			continue
+		}
+		for _, failed := range out.Failed {
+			id := aws.ToString(failed.Id)
+			if i, err := strconv.Atoi(id); err == nil && i < len(batch) {
+				id = aws.ToString(batch[i].MessageId)
+			}
+			errs = append(errs, fmt.Errorf("releasing dead letter %s: %s: %s", id, aws.ToString(failed.Code), aws.ToString(failed.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// delete deletes a received dead letter.
+func (d *DeadLetterQueueSQS) delete(ctx context.Context, m types.Message) error {
+	_, err := d.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{QueueUrl: &d.QueueURL, ReceiptHandle: m.ReceiptHandle})
+	return err
+}
+
+// sqsDeadLetter converts a received SQS message to a DeadLetter.
+func sqsDeadLetter(m types.Message) *DeadLetter {
+	attributes := sqsStringAttributes(m.MessageAttributes)
+	deadLetter := &DeadLetter{
+		ID:         aws.ToString(m.MessageId),
+		Type:       attributes[messageTypeAttribute],
+		Body:       []byte(aws.ToString(m.Body)),
+		Attributes: attributes,
+	}
+	deadLetter.ReceiveCount, _ = strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if millis, err := strconv.ParseInt(m.Attributes[string(types.MessageSystemAttributeNameSentTimestamp)], 10, 64); err == nil {
+		deadLetter.SentAt = time.UnixMilli(millis)
+	}
+	return deadLetter
+}
@@ -0,0 +1,198 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/infra/messaging"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sqsDeadLetterArn is the ARN of the dead-letter queue fakeDeadLetterSQS serves.
+const sqsDeadLetterArn = "arn:aws:sqs:us-east-1:123456789012:orders-dlq"
+
+// sqsReceivedMessage is a message of a ReceiveMessage response.
+type sqsReceivedMessage struct {
+	MessageID     string            `json:"MessageId"`
+	ReceiptHandle string            `json:"ReceiptHandle"`
+	Body          string            `json:"Body"`
+	Attributes    map[string]string `json:"Attributes"`
+}
+
+// fakeDeadLetterSQS answers the requests of DeadLetterQueueSQS in the SQS JSON
+// protocol. Each ReceiveMessage returns the next of receives, then nothing.
+// ChangeMessageVisibilityBatch fails every entry when failRelease is set.
+type fakeDeadLetterSQS struct {
+	mu            sync.Mutex
+	receives      [][]sqsReceivedMessage
+	redrivePolicy string
+	failRelease   bool
+
+	policiesSet []string
+	released    []string
+	deleted     []string
+}
+
+func (f *fakeDeadLetterSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	var input struct {
+		QueueURL      string `json:"QueueUrl"`
+		Attributes    map[string]string
+		ReceiptHandle string
+		Entries       []struct {
+			ID            string `json:"Id"`
+			ReceiptHandle string
+		}
+	}
+	Expect(json.NewDecoder(r.Body).Decode(&input)).To(Succeed())
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var output interface{} = struct{}{}
+	switch r.Header.Get("X-Amz-Target") {
+	case "AmazonSQS.GetQueueAttributes":
+		attributes := map[string]string{}
+		if strings.HasSuffix(input.QueueURL, "/orders-dlq") {
+			attributes["QueueArn"] = sqsDeadLetterArn
+		} else if f.redrivePolicy != "" {
+			attributes["RedrivePolicy"] = f.redrivePolicy
+		}
+		output = map[string]interface{}{"Attributes": attributes}
+	case "AmazonSQS.SetQueueAttributes":
+		f.redrivePolicy = input.Attributes["RedrivePolicy"]
+		f.policiesSet = append(f.policiesSet, f.redrivePolicy)
+	case "AmazonSQS.ReceiveMessage":
+		messages := []sqsReceivedMessage{}
+		if len(f.receives) > 0 {
+			messages, f.receives = f.receives[0], f.receives[1:]
+		}
+		output = map[string]interface{}{"Messages": messages}
+	case "AmazonSQS.ChangeMessageVisibilityBatch":
+		successful, failed := []map[string]interface{}{}, []map[string]interface{}{}
+		for _, entry := range input.Entries {
+			if f.failRelease {
+				failed = append(failed, map[string]interface{}{"Id": entry.ID, "Code": "ReceiptHandleIsInvalid", "Message": "expired", "SenderFault": true})
+				continue
+			}
+			f.released = append(f.released, entry.ReceiptHandle)
+			successful = append(successful, map[string]interface{}{"Id": entry.ID})
+		}
+		output = map[string]interface{}{"Successful": successful, "Failed": failed}
+	case "AmazonSQS.DeleteMessage":
+		f.deleted = append(f.deleted, input.ReceiptHandle)
+	default:
+		Fail("unexpected request " + r.Header.Get("X-Amz-Target"))
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	Expect(json.NewEncoder(w).Encode(output)).To(Succeed())
+}
+
+// deadLetterMessage returns a received dead letter with the given ID and receipt handle.
+func deadLetterMessage(id, receiptHandle string) sqsReceivedMessage {
+	return sqsReceivedMessage{
+		MessageID:     id,
+		ReceiptHandle: receiptHandle,
+		Body:          `{"OrderId":600}`,
+		Attributes:    map[string]string{"ApproximateReceiveCount": "5", "SentTimestamp": "1751032991000"},
+	}
+}
+
+var _ = Describe("DeadLetterQueueSQS", func() {
+	var (
+		fake            *fakeDeadLetterSQS
+		deadLetterQueue *messaging.DeadLetterQueueSQS
+	)
+
+	BeforeEach(func() {
+		fake = &fakeDeadLetterSQS{}
+		server := httptest.NewServer(fake)
+		DeferCleanup(server.Close)
+
+		client := sqs.New(sqs.Options{
+			Region:           "us-east-1",
+			BaseEndpoint:     aws.String(server.URL),
+			Credentials:      aws.AnonymousCredentials{},
+			RetryMaxAttempts: 1,
+		})
+		deadLetterQueue = messaging.NewDeadLetterQueueSQS(client, server.URL+"/123456789012/orders-dlq", server.URL+"/123456789012/orders")
+	})
+
+	Describe("Attach", func() {
+		It("should set the redrive policy of the source queue", func() {
+			changed, err := deadLetterQueue.Attach(context.Background(), 5)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(fake.policiesSet).To(HaveLen(1))
+			Expect(fake.policiesSet[0]).To(MatchJSON(`{"deadLetterTargetArn":"` + sqsDeadLetterArn + `","maxReceiveCount":"5"}`))
+		})
+
+		It("should leave a redrive policy that already matches alone", func() {
+			fake.redrivePolicy = `{"deadLetterTargetArn":"` + sqsDeadLetterArn + `","maxReceiveCount":5}`
+
+			changed, err := deadLetterQueue.Attach(context.Background(), 5)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeFalse())
+			Expect(fake.policiesSet).To(BeEmpty())
+		})
+
+		It("should replace a redrive policy with another receive count", func() {
+			fake.redrivePolicy = `{"deadLetterTargetArn":"` + sqsDeadLetterArn + `","maxReceiveCount":3}`
+
+			changed, err := deadLetterQueue.Attach(context.Background(), 5)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changed).To(BeTrue())
+			Expect(fake.policiesSet).To(HaveLen(1))
+		})
+	})
+
+	Describe("List", func() {
+		It("should list a dead letter received twice once, with its latest receipt handle", func() {
+			fake.receives = [][]sqsReceivedMessage{
+				{deadLetterMessage("m1", "h1"), deadLetterMessage("m2", "h2")},
+				{deadLetterMessage("m1", "h1-again")},
+			}
+
+			deadLetters, err := deadLetterQueue.List(context.Background(), 0)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(deadLetters).To(HaveLen(2))
+			Expect(deadLetters[0].ID).To(Equal("m1"))
+			Expect(deadLetters[0].ReceiveCount).To(Equal(5))
+			Expect(deadLetters[1].ID).To(Equal("m2"))
+			Expect(fake.released).To(ConsistOf("h1-again", "h2"))
+		})
+
+		It("should fail when the dead letters cannot be made visible again", func() {
+			fake.receives = [][]sqsReceivedMessage{{deadLetterMessage("m1", "h1")}}
+			fake.failRelease = true
+
+			_, err := deadLetterQueue.List(context.Background(), 0)
+
+			Expect(err).To(MatchError("releasing dead letter m1: ReceiptHandleIsInvalid: expired"))
+		})
+	})
+
+	Describe("Purge", func() {
+		It("should report the dead letters it leaves that cannot be made visible again", func() {
+			fake.receives = [][]sqsReceivedMessage{{deadLetterMessage("m1", "h1"), deadLetterMessage("m2", "h2")}}
+			fake.failRelease = true
+
+			count, err := deadLetterQueue.Purge(context.Background(), []string{"m2"})
+
+			Expect(count).To(Equal(1))
+			Expect(fake.deleted).To(Equal([]string{"h2"}))
+			Expect(err).To(MatchError("releasing dead letter m1: ReceiptHandleIsInvalid: expired"))
+		})
+	})
+})
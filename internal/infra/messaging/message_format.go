@@ -0,0 +1,219 @@
+// Package messaging holds the adapters that publish and consume order events
+// on message queues.
+package messaging
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MessageFormat is how an event envelope is laid out in a queue message.
+type MessageFormat string
+
+// Supported message formats. The zero value is MessageFormatEnvelope.
+const (
+	// MessageFormatEnvelope sends the EventEnvelope as the message body.
+	MessageFormatEnvelope MessageFormat = "envelope"
+	// MessageFormatCloudEventsStructured sends a CloudEvents 1.0 event in the
+	// JSON event format as the message body.
+	MessageFormatCloudEventsStructured MessageFormat = "cloudevents-structured"
+	// MessageFormatCloudEventsBinary sends the event data as the message body
+	// and the CloudEvents 1.0 context attributes as message attributes.
+	MessageFormatCloudEventsBinary MessageFormat = "cloudevents-binary"
+)
+
+const (
+	// messageTypeAttribute is the message attribute that carries the event
+	// type in every format, so that subscriptions can filter on it.
+	messageTypeAttribute = "type"
+	// contentTypeAttribute is the message attribute that carries the media
+	// type of the body in the CloudEvents formats.
+	contentTypeAttribute = "content-type"
+	// cloudEventsAttributePrefix prefixes the CloudEvents context attributes
+	// in binary mode, as in the CloudEvents Kafka binding.
+	cloudEventsAttributePrefix = "ce_"
+
+	// CloudEventsSpecVersion is the CloudEvents version the formats follow.
+	CloudEventsSpecVersion = "1.0"
+	// CloudEventsSource is the source of every event this service publishes.
+	CloudEventsSource = "/orders"
+	// cloudEventsContentType is the media type of a structured CloudEvent.
+	cloudEventsContentType = "application/cloudevents+json"
+	// eventDataContentType is the media type of event data.
+	eventDataContentType = "application/json"
+)
+
+// Message is an event as it travels on a queue: a body and string attributes.
+type Message struct {
+	Body       []byte
+	Attributes map[string]string
+}
+
+// CloudEvent is a CloudEvents 1.0 event in the JSON event format. Subject is
+// the OrderId of the order the event happened to. The correlationid and
+// schemaversion extensions carry the envelope fields CloudEvents has no
+// attribute for.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	SchemaVersion   int             `json:"schemaversion"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// ParseMessageFormat checks a message format setting. An empty setting is
+// MessageFormatEnvelope.
+func ParseMessageFormat(value string) (MessageFormat, error) {
+	switch format := MessageFormat(value); format {
+	case "":
+		return MessageFormatEnvelope, nil
+	case MessageFormatEnvelope, MessageFormatCloudEventsStructured, MessageFormatCloudEventsBinary:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported message format %q", value)
+	}
+}
+
+// Encode lays out an event envelope as a queue message in format f.
+func (f MessageFormat) Encode(envelope *repository.EventEnvelope) (*Message, error) {
+	attributes := map[string]string{messageTypeAttribute: envelope.Type}
+
+	switch f {
+	case "", MessageFormatEnvelope:
+		body, err := json.Marshal(envelope)
+		if err != nil {
+			return nil, err
+		}
+		return &Message{Body: body, Attributes: attributes}, nil
+
+	case MessageFormatCloudEventsStructured:
+		body, err := json.Marshal(newCloudEvent(envelope))
+		if err != nil {
+			return nil, err
+		}
+		attributes[contentTypeAttribute] = cloudEventsContentType
+		return &Message{Body: body, Attributes: attributes}, nil
+
+	case MessageFormatCloudEventsBinary:
+		event := newCloudEvent(envelope)
+		attributes[contentTypeAttribute] = event.DataContentType
+		attributes[cloudEventsAttributePrefix+"specversion"] = event.SpecVersion
+		attributes[cloudEventsAttributePrefix+"id"] = event.ID
+		attributes[cloudEventsAttributePrefix+"source"] = event.Source
+		attributes[cloudEventsAttributePrefix+"type"] = event.Type
+		attributes[cloudEventsAttributePrefix+"subject"] = event.Subject
+		attributes[cloudEventsAttributePrefix+"time"] = event.Time.Format(time.RFC3339Nano)
+		attributes[cloudEventsAttributePrefix+"schemaversion"] = strconv.Itoa(event.SchemaVersion)
+		if event.CorrelationID != "" {
+			attributes[cloudEventsAttributePrefix+"correlationid"] = event.CorrelationID
+		}
+		return &Message{Body: event.Data, Attributes: attributes}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported message format %q", f)
+	}
+}
+
+// DecodeMessage turns a queue message in any of the formats back into an
+// order message body and type, so that consumers don't depend on the format
+// the publisher chose. CloudEvents are converted to an EventEnvelope. Other
+// messages are returned as they are, with the type from their attribute.
+func DecodeMessage(message *Message) (messageType string, body []byte, err error) {
+	if _, ok := message.Attributes[cloudEventsAttributePrefix+"specversion"]; ok {
+		event, err := binaryCloudEvent(message)
+		if err != nil {
+			return "", nil, err
+		}
+		return event.envelope()
+	}
+	if message.Attributes[contentTypeAttribute] == cloudEventsContentType {
+		var event CloudEvent
+		if err := json.Unmarshal(message.Body, &event); err != nil {
+			return "", nil, err
+		}
+		return event.envelope()
+	}
+	return message.Attributes[messageTypeAttribute], message.Body, nil
+}
+
+// newCloudEvent converts an event envelope to a CloudEvent.
+func newCloudEvent(envelope *repository.EventEnvelope) *CloudEvent {
+	return &CloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              envelope.ID,
+		Source:          CloudEventsSource,
+		Type:            envelope.Type,
+		Subject:         strconv.Itoa(envelope.OrderID),
+		Time:            envelope.OccurredAt,
+		DataContentType: eventDataContentType,
+		CorrelationID:   envelope.CorrelationID,
+		SchemaVersion:   envelope.SchemaVersion,
+		Data:            envelope.Data,
+	}
+}
+
+// binaryCloudEvent reads a CloudEvent from the attributes and body of a binary mode message.
+func binaryCloudEvent(message *Message) (*CloudEvent, error) {
+	attribute := func(name string) string {
+		return message.Attributes[cloudEventsAttributePrefix+name]
+	}
+
+	event := &CloudEvent{
+		SpecVersion:     attribute("specversion"),
+		ID:              attribute("id"),
+		Source:          attribute("source"),
+		Type:            attribute("type"),
+		Subject:         attribute("subject"),
+		DataContentType: message.Attributes[contentTypeAttribute],
+		CorrelationID:   attribute("correlationid"),
+		Data:            message.Body,
+	}
+	if value := attribute("time"); value != "" {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudEvents time %q: %w", value, err)
+		}
+		event.Time = t
+	}
+	if value := attribute("schemaversion"); value != "" {
+		version, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CloudEvents schemaversion %q: %w", value, err)
+		}
+		event.SchemaVersion = version
+	}
+	return event, nil
+}
+
+// envelope converts a CloudEvent back to the type and JSON of an event envelope.
+func (e *CloudEvent) envelope() (string, []byte, error) {
+	if e.SpecVersion != CloudEventsSpecVersion {
+		return "", nil, fmt.Errorf("unsupported CloudEvents specversion %q", e.SpecVersion)
+	}
+	orderID, err := strconv.Atoi(e.Subject)
+	if err != nil {
+		return "", nil, fmt.Errorf("CloudEvents subject %q is not an OrderId", e.Subject)
+	}
+
+	body, err := json.Marshal(&repository.EventEnvelope{
+		ID:            e.ID,
+		Type:          e.Type,
+		SchemaVersion: e.SchemaVersion,
+		OccurredAt:    e.Time,
+		CorrelationID: e.CorrelationID,
+		OrderID:       orderID,
+		Data:          e.Data,
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return e.Type, body, nil
+}
@@ -0,0 +1,147 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/messaging"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// newEnvelope returns the envelope of an OrderPaid event for order 600.
+func newEnvelope() *repository.EventEnvelope {
+	return &repository.EventEnvelope{
+		ID:            "0197b6a2-5c1e-7d4a-9f3b-2a6c8e1d4f70",
+		Type:          "OrderPaid",
+		SchemaVersion: 1,
+		OccurredAt:    time.Date(2025, 6, 27, 14, 3, 11, 500, time.UTC),
+		CorrelationID: "checkout-42",
+		OrderID:       600,
+		Data:          json.RawMessage(`{"OrderId":600,"amount":{"amount":5412,"currency":"USD"}}`),
+	}
+}
+
+// decodeEnvelope decodes a message and the envelope in its body.
+func decodeEnvelope(message *messaging.Message) (string, *repository.EventEnvelope) {
+	messageType, body, err := messaging.DecodeMessage(message)
+	Expect(err).NotTo(HaveOccurred())
+	var envelope repository.EventEnvelope
+	Expect(json.Unmarshal(body, &envelope)).To(Succeed())
+	return messageType, &envelope
+}
+
+var _ = Describe("MessageFormat", func() {
+	Describe("ParseMessageFormat", func() {
+		It("should default to the envelope format", func() {
+			Expect(messaging.ParseMessageFormat("")).To(Equal(messaging.MessageFormatEnvelope))
+		})
+
+		It("should accept the CloudEvents formats", func() {
+			Expect(messaging.ParseMessageFormat("cloudevents-structured")).To(Equal(messaging.MessageFormatCloudEventsStructured))
+			Expect(messaging.ParseMessageFormat("cloudevents-binary")).To(Equal(messaging.MessageFormatCloudEventsBinary))
+		})
+
+		It("should reject unknown formats", func() {
+			_, err := messaging.ParseMessageFormat("avro")
+
+			Expect(err).To(MatchError(ContainSubstring(`unsupported message format "avro"`)))
+		})
+	})
+
+	DescribeTable("should decode what it encodes",
+		func(format messaging.MessageFormat) {
+			envelope := newEnvelope()
+			message, err := format.Encode(envelope)
+			Expect(err).NotTo(HaveOccurred())
+
+			messageType, decoded := decodeEnvelope(message)
+
+			Expect(messageType).To(Equal("OrderPaid"))
+			Expect(decoded.OccurredAt.Equal(envelope.OccurredAt)).To(BeTrue())
+			decoded.OccurredAt = envelope.OccurredAt
+			Expect(decoded.Data).To(MatchJSON(envelope.Data))
+			decoded.Data = envelope.Data
+			Expect(decoded).To(Equal(envelope))
+			Expect(message.Attributes).To(HaveKeyWithValue("type", "OrderPaid"))
+		},
+		Entry("envelope", messaging.MessageFormatEnvelope),
+		Entry("CloudEvents structured", messaging.MessageFormatCloudEventsStructured),
+		Entry("CloudEvents binary", messaging.MessageFormatCloudEventsBinary),
+	)
+
+	Context("in the CloudEvents structured format", func() {
+		It("should send the event in the CloudEvents JSON format", func() {
+			message, err := messaging.MessageFormatCloudEventsStructured.Encode(newEnvelope())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(message.Attributes).To(HaveKeyWithValue("content-type", "application/cloudevents+json"))
+			Expect(message.Body).To(MatchJSON(`{
+				"specversion": "1.0",
+				"id": "0197b6a2-5c1e-7d4a-9f3b-2a6c8e1d4f70",
+				"source": "/orders",
+				"type": "OrderPaid",
+				"subject": "600",
+				"time": "2025-06-27T14:03:11.0000005Z",
+				"datacontenttype": "application/json",
+				"correlationid": "checkout-42",
+				"schemaversion": 1,
+				"data": {"OrderId": 600, "amount": {"amount": 5412, "currency": "USD"}}
+			}`))
+		})
+	})
+
+	Context("in the CloudEvents binary format", func() {
+		It("should send the data as the body and the context as attributes", func() {
+			message, err := messaging.MessageFormatCloudEventsBinary.Encode(newEnvelope())
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(message.Body).To(MatchJSON(newEnvelope().Data))
+			Expect(message.Attributes).To(Equal(map[string]string{
+				"type":             "OrderPaid",
+				"content-type":     "application/json",
+				"ce_specversion":   "1.0",
+				"ce_id":            "0197b6a2-5c1e-7d4a-9f3b-2a6c8e1d4f70",
+				"ce_source":        "/orders",
+				"ce_type":          "OrderPaid",
+				"ce_subject":       "600",
+				"ce_time":          "2025-06-27T14:03:11.0000005Z",
+				"ce_schemaversion": "1",
+				"ce_correlationid": "checkout-42",
+			}))
+		})
+
+		It("should leave out the correlation ID when there is none", func() {
+			envelope := newEnvelope()
+			envelope.CorrelationID = ""
+
+			message, err := messaging.MessageFormatCloudEventsBinary.Encode(envelope)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(message.Attributes).NotTo(HaveKey("ce_correlationid"))
+		})
+
+		It("should reject another CloudEvents version", func() {
+			message, err := messaging.MessageFormatCloudEventsBinary.Encode(newEnvelope())
+			Expect(err).NotTo(HaveOccurred())
+			message.Attributes["ce_specversion"] = "0.3"
+
+			_, _, err = messaging.DecodeMessage(message)
+
+			Expect(err).To(MatchError(ContainSubstring(`unsupported CloudEvents specversion "0.3"`)))
+		})
+	})
+
+	Context("when a message has no attributes", func() {
+		It("should return the body as it is, with no type", func() {
+			body := []byte(`{"OrderId":600,"Data":"legacy"}`)
+
+			messageType, decoded, err := messaging.DecodeMessage(&messaging.Message{Body: body})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messageType).To(BeEmpty())
+			Expect(decoded).To(Equal(body))
+		})
+	})
+})
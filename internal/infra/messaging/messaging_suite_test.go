@@ -0,0 +1,13 @@
+package messaging_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMessaging(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Messaging Suite")
+}
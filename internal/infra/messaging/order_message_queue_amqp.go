@@ -0,0 +1,518 @@
+package messaging
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// DriverAMQP is the messaging.driver setting for RabbitMQ and other AMQP 0.9.1 brokers.
+const DriverAMQP = "amqp"
+
+const (
+	// amqpExchangeKind is the kind of the exchange events are published to,
+	// so that queues can bind to a subset of the routing keys.
+	amqpExchangeKind = "topic"
+	// amqpDefaultBindingKey binds a queue to every order event.
+	amqpDefaultBindingKey = "order.#"
+	// amqpMaxMessages is the most deliveries a single receive returns.
+	amqpMaxMessages = 100
+	// amqpPollTimeout is how long a receive waits for a delivery, like SQS long polling.
+	amqpPollTimeout = 20 * time.Second
+	// amqpDeliveryCountHeader is the header in which a quorum queue counts the
+	// earlier deliveries of a message that were not acknowledged.
+	amqpDeliveryCountHeader = "x-delivery-count"
+	// amqpReceiveCountHeader is the header in which a message moved to a retry
+	// queue carries how many times it had been received before.
+	amqpReceiveCountHeader = "x-receive-count"
+	// amqpRetryQueueExpiry is how long a retry queue stays unused after its
+	// messages have expired before the broker deletes it.
+	amqpRetryQueueExpiry = time.Hour
+)
+
+// errAMQPNotConfirmed is returned when the broker nacks a published message.
+var errAMQPNotConfirmed = errors.New("the AMQP broker did not confirm the message")
+
+// OrderMessageQueueAMQP implements the EventPublisher and OrderMessageConsumer
+// interfaces for RabbitMQ and other AMQP 0.9.1 brokers. Events are published
+// to a durable topic exchange as persistent messages, with a routing key made
+// from the event type, and Publish waits for the broker's publisher confirm.
+// Message attributes travel as message headers. Events are published in
+// Format; messages in any format are received.
+//
+// Receive consumes Queue, which is declared and bound to the exchange with
+// BindingKeys the first time it is needed. When the connection or a channel
+// closes, the next Publish or Receive reconnects and declares the topology
+// again. Messages that were in flight on the old channel are redelivered by
+// the broker and can no longer be acknowledged. A message that failed is handed
+// back to the broker with Retry or Release and delivered again.
+//
+// AMQP cannot delay a redelivery, so Retry moves a message to a retry queue
+// named after Queue and the delay, such as "order-worker.retry.30000" for 30
+// seconds, where it expires after the delay and is dead-lettered back to Queue.
+// Each retry queue holds messages of a single delay, so that none waits behind
+// a message with a longer one. A retry queue that has been unused for an hour
+// after its messages expired is deleted by the broker.
+//
+// With a DeadLetterQueue, Queue is declared as a quorum queue that dead-letters
+// to it, and the receive count of a message comes from the queue's
+// x-delivery-count header and the x-receive-count header Retry adds. Once a message has been received MaxReceives times,
+// Retry and Release reject it and the broker moves it to DeadLetterQueue. A
+// classic queue cannot be turned into a quorum queue, so a queue declared
+// without a DeadLetterQueue has to be deleted first.
+type OrderMessageQueueAMQP struct {
+	URL         string
+	Exchange    string
+	Queue       string
+	BindingKeys []string
+	Format      MessageFormat
+	// Prefetch is how many unacknowledged deliveries the broker sends ahead; 0 is unlimited.
+	Prefetch int
+	// PollTimeout is how long Receive waits for a delivery.
+	PollTimeout time.Duration
+	// DeadLetterQueue is the queue rejected messages are moved to; empty discards them.
+	DeadLetterQueue string
+	// MaxReceives is how many times a message is received before it is dead-lettered; 0 is unlimited.
+	MaxReceives int
+
+	mu             sync.Mutex
+	connection     *amqp.Connection
+	publishChannel *amqp.Channel
+	consumeChannel *amqp.Channel
+	deliveries     <-chan amqp.Delivery
+	// generation counts consume channels, so that receipt handles from a
+	// closed channel are not mistaken for ones on the current channel.
+	generation int
+	inFlight   map[string]amqp.Delivery
+}
+
+// NewOrderMessageQueueAMQP creates a new AMQP message queue. No connection is
+// made until the first Publish or Receive. Without binding keys the queue
+// receives every order event.
+func NewOrderMessageQueueAMQP(url, exchange, queue string, bindingKeys []string, prefetch int, format MessageFormat) *OrderMessageQueueAMQP {
+	if len(bindingKeys) == 0 {
+		bindingKeys = []string{amqpDefaultBindingKey}
+	}
+	return &OrderMessageQueueAMQP{
+		URL:         url,
+		Exchange:    exchange,
+		Queue:       queue,
+		BindingKeys: bindingKeys,
+		Format:      format,
+		Prefetch:    prefetch,
+		PollTimeout: amqpPollTimeout,
+		inFlight:    make(map[string]amqp.Delivery),
+	}
+}
+
+// AMQPRoutingKey returns the routing key of an event type: "order." followed
+// by the rest of the type in snake case, such as "order.status_changed" for
+// OrderStatusChanged.
+func AMQPRoutingKey(eventType string) string {
+	var words []string
+	start := 0
+	for i, r := range eventType {
+		if i > 0 && unicode.IsUpper(r) {
+			words = append(words, strings.ToLower(eventType[start:i]))
+			start = i
+		}
+	}
+	words = append(words, strings.ToLower(eventType[start:]))
+	if len(words) > 1 && words[0] == "order" {
+		return "order." + strings.Join(words[1:], "_")
+	}
+	return strings.Join(words, "_")
+}
+
+// Publish publishes an event to the exchange and waits for the broker to
+// confirm it. A publish on a connection that has closed reconnects and is
+// tried once more.
+func (q *OrderMessageQueueAMQP) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	message, err := q.Format.Encode(envelope)
+	if err != nil {
+		return err
+	}
+
+	headers := make(amqp.Table, len(message.Attributes))
+	for name, value := range message.Attributes {
+		headers[name] = value
+	}
+	contentType := message.Attributes[contentTypeAttribute]
+	if contentType == "" {
+		contentType = eventDataContentType
+	}
+	publishing := amqp.Publishing{
+		Headers:      headers,
+		ContentType:  contentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    envelope.ID,
+		Type:         envelope.Type,
+		Timestamp:    envelope.OccurredAt,
+		Body:         message.Body,
+	}
+
+	err = q.publish(ctx, q.Exchange, AMQPRoutingKey(envelope.Type), publishing)
+	if errors.Is(err, amqp.ErrClosed) {
+		err = q.publish(ctx, q.Exchange, AMQPRoutingKey(envelope.Type), publishing)
+	}
+	return err
+}
+
+// publish publishes one message to exchange on the publish channel and waits
+// for its confirm.
+func (q *OrderMessageQueueAMQP) publish(ctx context.Context, exchange, routingKey string, publishing amqp.Publishing) error {
+	q.mu.Lock()
+	channel, err := q.openPublishChannel()
+	if err != nil {
+		q.mu.Unlock()
+		return err
+	}
+	confirmation, err := channel.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, publishing)
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return err
+	}
+	if !acked {
+		return errAMQPNotConfirmed
+	}
+	return nil
+}
+
+// Receive waits up to PollTimeout for a delivery from the queue and returns it
+// with any others that have already arrived. A message that cannot be decoded
+// is logged and rejected without requeueing, which moves it to DeadLetterQueue,
+// as it could never be processed.
+func (q *OrderMessageQueueAMQP) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	q.mu.Lock()
+	deliveries, err := q.openConsumeChannel()
+	generation := q.generation
+	q.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(q.PollTimeout)
+	defer timer.Stop()
+
+	var received []amqp.Delivery
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-timer.C:
+		return nil, nil
+	case delivery, ok := <-deliveries:
+		if !ok {
+			return nil, amqp.ErrClosed
+		}
+		received = append(received, delivery)
+	}
+drain:
+	for len(received) < amqpMaxMessages {
+		select {
+		case delivery, ok := <-deliveries:
+			if !ok {
+				break drain
+			}
+			received = append(received, delivery)
+		default:
+			break drain
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := make([]*repository.OrderMessage, 0, len(received))
+	for _, delivery := range received {
+		handle := fmt.Sprintf("%d/%d", generation, delivery.DeliveryTag)
+		attributes := make(map[string]string, len(delivery.Headers)+1)
+		for name, value := range delivery.Headers {
+			if s, ok := value.(string); ok {
+				attributes[name] = s
+			}
+		}
+		if _, ok := attributes[contentTypeAttribute]; !ok && delivery.ContentType == cloudEventsContentType {
+			attributes[contentTypeAttribute] = delivery.ContentType
+		}
+		if _, ok := attributes[messageTypeAttribute]; !ok && delivery.Type != "" {
+			attributes[messageTypeAttribute] = delivery.Type
+		}
+
+		messageType, body, err := DecodeMessage(&Message{Body: delivery.Body, Attributes: attributes})
+		if err != nil {
+			log.Printf("Error decoding message %s: %v", delivery.MessageId, err)
+			if err := delivery.Reject(false); err != nil {
+				log.Printf("Error rejecting message %s: %v", delivery.MessageId, err)
+			}
+			continue
+		}
+		q.inFlight[handle] = delivery
+		messages = append(messages, &repository.OrderMessage{ID: delivery.MessageId, Type: messageType, Body: body, ReceiptHandle: handle, ReceiveCount: amqpReceiveCount(delivery)})
+	}
+	return messages, nil
+}
+
+// Ack acknowledges a received message, removing it from the queue.
+func (q *OrderMessageQueueAMQP) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	delivery, err := q.takeInFlight(message)
+	if err != nil {
+		return err
+	}
+	return delivery.Ack(false)
+}
+
+// Retry hands a received message back to the broker, which delivers it again
+// after delay. A copy of the message is published to the retry queue of the
+// delay and the message is acknowledged once the broker has confirmed the
+// copy, so that nothing is held unacknowledged while it waits. When the copy
+// cannot be published, the message is handed back for delivery straight away
+// and the error is returned. A message received MaxReceives times is rejected
+// instead, and moved to DeadLetterQueue.
+func (q *OrderMessageQueueAMQP) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	delivery, err := q.takeInFlight(message)
+	if err != nil {
+		return err
+	}
+	if q.DeadLetterQueue != "" && q.MaxReceives > 0 && message.ReceiveCount >= q.MaxReceives {
+		log.Printf("Message %s was received %d times; moving it to %s", message.ID, message.ReceiveCount, q.DeadLetterQueue)
+		return delivery.Reject(false)
+	}
+	if delay <= 0 {
+		return delivery.Nack(false, true)
+	}
+
+	if err := q.publishRetry(ctx, delivery, message.ReceiveCount, delay); err != nil {
+		if err := delivery.Nack(false, true); err != nil {
+			log.Printf("Error requeueing message %s: %v", message.ID, err)
+		}
+		return fmt.Errorf("moving message %s to a retry queue: %w", message.ID, err)
+	}
+	return delivery.Ack(false)
+}
+
+// publishRetry declares the retry queue of delay and publishes a copy of a
+// delivery to it, which records that the message has been received
+// receiveCount times.
+func (q *OrderMessageQueueAMQP) publishRetry(ctx context.Context, delivery amqp.Delivery, receiveCount int, delay time.Duration) error {
+	q.mu.Lock()
+	channel, err := q.openPublishChannel()
+	if err == nil {
+		err = q.declareRetryQueue(channel, delay)
+	}
+	q.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	headers := make(amqp.Table, len(delivery.Headers)+1)
+	for name, value := range delivery.Headers {
+		headers[name] = value
+	}
+	delete(headers, amqpDeliveryCountHeader)
+	headers[amqpReceiveCountHeader] = int64(receiveCount)
+	return q.publish(ctx, "", q.retryQueue(delay), amqp.Publishing{
+		Headers:      headers,
+		ContentType:  delivery.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    delivery.MessageId,
+		Type:         delivery.Type,
+		Timestamp:    delivery.Timestamp,
+		Body:         delivery.Body,
+	})
+}
+
+// Release hands a received message back to the broker, which delivers it again.
+func (q *OrderMessageQueueAMQP) Release(ctx context.Context, message *repository.OrderMessage) error {
+	return q.Retry(ctx, message, 0)
+}
+
+// takeInFlight removes a received message from the deliveries in flight and
+// returns its delivery.
+func (q *OrderMessageQueueAMQP) takeInFlight(message *repository.OrderMessage) (amqp.Delivery, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delivery, ok := q.inFlight[message.ReceiptHandle]
+	if !ok {
+		return amqp.Delivery{}, errors.New("message not in flight")
+	}
+	delete(q.inFlight, message.ReceiptHandle)
+	return delivery, nil
+}
+
+// amqpReceiveCount returns how many times a delivery has been received: one
+// more than its x-delivery-count, which only quorum queues set, plus the
+// receives before it last went through a retry queue.
+func amqpReceiveCount(delivery amqp.Delivery) int {
+	return amqpHeaderCount(delivery.Headers[amqpDeliveryCountHeader]) + 1 + amqpHeaderCount(delivery.Headers[amqpReceiveCountHeader])
+}
+
+// amqpHeaderCount returns the value of a count header, or 0 when it is not set.
+func amqpHeaderCount(value interface{}) int {
+	switch count := value.(type) {
+	case int64:
+		return int(count)
+	case int32:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
+
+// Close closes the connection to the broker.
+func (q *OrderMessageQueueAMQP) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.connection == nil || q.connection.IsClosed() {
+		return nil
+	}
+	return q.connection.Close()
+}
+
+// openConnection returns the open connection, dialing the broker when there
+// is none. The caller holds q.mu.
+func (q *OrderMessageQueueAMQP) openConnection() (*amqp.Connection, error) {
+	if q.connection != nil && !q.connection.IsClosed() {
+		return q.connection, nil
+	}
+	connection, err := amqp.Dial(q.URL)
+	if err != nil {
+		return nil, err
+	}
+	q.connection = connection
+	return connection, nil
+}
+
+// openPublishChannel returns the open publish channel, opening one in confirm
+// mode and declaring the exchange when there is none. The caller holds q.mu.
+func (q *OrderMessageQueueAMQP) openPublishChannel() (*amqp.Channel, error) {
+	if q.publishChannel != nil && !q.publishChannel.IsClosed() {
+		return q.publishChannel, nil
+	}
+	connection, err := q.openConnection()
+	if err != nil {
+		return nil, err
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.declareExchange(channel); err != nil {
+		channel.Close()
+		return nil, err
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, err
+	}
+	q.publishChannel = channel
+	return channel, nil
+}
+
+// openConsumeChannel returns the deliveries of the open consume channel,
+// opening one, declaring and binding the queue and starting to consume it
+// when there is none. The caller holds q.mu.
+func (q *OrderMessageQueueAMQP) openConsumeChannel() (<-chan amqp.Delivery, error) {
+	if q.consumeChannel != nil && !q.consumeChannel.IsClosed() {
+		return q.deliveries, nil
+	}
+	if q.Queue == "" {
+		return nil, errors.New("no AMQP queue to consume is configured")
+	}
+	connection, err := q.openConnection()
+	if err != nil {
+		return nil, err
+	}
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := q.consume(channel)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	// Deliveries from the previous channel can no longer be acknowledged.
+	clear(q.inFlight)
+	q.generation++
+	q.consumeChannel = channel
+	q.deliveries = deliveries
+	return deliveries, nil
+}
+
+// consume declares the exchange, the queue and its dead-letter queue, binds
+// them and starts consuming the queue.
+func (q *OrderMessageQueueAMQP) consume(channel *amqp.Channel) (<-chan amqp.Delivery, error) {
+	if err := q.declareExchange(channel); err != nil {
+		return nil, err
+	}
+	var arguments amqp.Table
+	if q.DeadLetterQueue != "" {
+		if _, err := channel.QueueDeclare(q.DeadLetterQueue, true, false, false, false, nil); err != nil {
+			return nil, err
+		}
+		// The default exchange routes dead letters to the queue named by their routing key.
+		arguments = amqp.Table{
+			amqp.QueueTypeArg:           amqp.QueueTypeQuorum,
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": q.DeadLetterQueue,
+		}
+	}
+	if _, err := channel.QueueDeclare(q.Queue, true, false, false, false, arguments); err != nil {
+		return nil, err
+	}
+	for _, key := range q.BindingKeys {
+		if err := channel.QueueBind(q.Queue, key, q.Exchange, false, nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := channel.Qos(q.Prefetch, 0, false); err != nil {
+		return nil, err
+	}
+	return channel.Consume(q.Queue, "", false, false, false, false, nil)
+}
+
+// retryQueue returns the name of the retry queue of delay.
+func (q *OrderMessageQueueAMQP) retryQueue(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%d", q.Queue, amqpDelayMillis(delay))
+}
+
+// declareRetryQueue declares the retry queue of delay, whose messages expire
+// after delay and are dead-lettered back to Queue.
+func (q *OrderMessageQueueAMQP) declareRetryQueue(channel *amqp.Channel, delay time.Duration) error {
+	ttl := amqpDelayMillis(delay)
+	_, err := channel.QueueDeclare(q.retryQueue(delay), true, false, false, false, amqp.Table{
+		"x-message-ttl": ttl,
+		"x-expires":     ttl + amqpRetryQueueExpiry.Milliseconds(),
+		// The default exchange routes dead letters to the queue named by their routing key.
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": q.Queue,
+	})
+	return err
+}
+
+// amqpDelayMillis returns a delay in whole milliseconds, rounded up.
+func amqpDelayMillis(delay time.Duration) int64 {
+	return int64((delay + time.Millisecond - 1) / time.Millisecond)
+}
+
+// declareExchange declares the durable topic exchange events are published to.
+func (q *OrderMessageQueueAMQP) declareExchange(channel *amqp.Channel) error {
+	return channel.ExchangeDeclare(q.Exchange, amqpExchangeKind, true, false, false, false, nil)
+}
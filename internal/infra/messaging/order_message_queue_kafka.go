@@ -0,0 +1,439 @@
+package messaging
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Supported values of the messaging.driver setting.
+const (
+	DriverSQS   = "sqs"
+	DriverKafka = "kafka"
+)
+
+// Supported values of the Kafka acks setting.
+const (
+	KafkaAcksAll    = "all"
+	KafkaAcksLeader = "leader"
+	KafkaAcksNone   = "none"
+)
+
+const (
+	// kafkaMaxMessages is the most records a single receive returns.
+	kafkaMaxMessages = 100
+	// kafkaPollTimeout is how long a receive waits for records, like SQS long polling.
+	kafkaPollTimeout = 20 * time.Second
+	// kafkaMaxReceives is how many times a record is delivered when no
+	// maximum is configured.
+	kafkaMaxReceives = 5
+	// kafkaMaxInFlight is how many received records are held, unacknowledged
+	// or waiting on an earlier record of their partition, before Receive stops
+	// fetching more.
+	kafkaMaxInFlight = 1000
+	// kafkaSourceHeader is the header of a dead-lettered record that holds the
+	// topic, partition and offset it was consumed from.
+	kafkaSourceHeader = "dead-letter-source"
+)
+
+// NewKafkaClient creates a Kafka client that produces to topic. acks is how
+// many replicas must acknowledge a produced record: "all" (the default),
+// "leader" or "none". idempotent enables the idempotent producer, which lets
+// the brokers drop records a retry sends twice; it needs acks "all". When
+// group is set the client also consumes topic as a member of that consumer
+// group, committing only the offsets of acknowledged records. Any further
+// options are added to those.
+func NewKafkaClient(brokers []string, topic, group, acks string, idempotent bool, extra ...kgo.Opt) (*kgo.Client, error) {
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(brokers...),
+		kgo.DefaultProduceTopic(topic),
+	}
+
+	switch acks {
+	case "", KafkaAcksAll:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case KafkaAcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case KafkaAcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	default:
+		return nil, fmt.Errorf("unsupported Kafka acks %q", acks)
+	}
+	if !idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	} else if acks != "" && acks != KafkaAcksAll {
+		return nil, fmt.Errorf("the idempotent Kafka producer needs acks %q, not %q", KafkaAcksAll, acks)
+	}
+
+	if group != "" {
+		opts = append(opts,
+			kgo.ConsumerGroup(group),
+			kgo.ConsumeTopics(topic),
+			kgo.AutoCommitMarks(),
+		)
+	}
+	return kgo.NewClient(append(opts, extra...)...)
+}
+
+// NewKafkaConsumer creates a Kafka client that consumes topic as a member of
+// group, as NewKafkaClient does, and a queue on it that forgets the records
+// of the partitions the client is revoked or loses in a rebalance.
+func NewKafkaConsumer(brokers []string, topic, group, acks string, idempotent bool, format MessageFormat) (*OrderMessageQueueKafka, error) {
+	queue := NewOrderMessageQueueKafka(nil, topic, format)
+	client, err := NewKafkaClient(brokers, topic, group, acks, idempotent,
+		kgo.OnPartitionsRevoked(queue.dropPartitions),
+		kgo.OnPartitionsLost(queue.dropPartitions),
+	)
+	if err != nil {
+		return nil, err
+	}
+	queue.Client = client
+	return queue, nil
+}
+
+// OrderMessageQueueKafka implements the EventPublisher and OrderMessageConsumer
+// interfaces for Kafka. Records are keyed by the OrderId, so that the events of
+// one order stay in one partition and in order, and message attributes travel
+// as record headers. Events are published in Format; records in any format
+// are received.
+//
+// Kafka tracks one offset per partition rather than deleting messages, so only
+// the offsets up to the first unacknowledged record of each partition are
+// committed. Records after it that were acknowledged are delivered again, with
+// it, after the worker restarts or the group rebalances.
+//
+// A record that failed is delivered again by Receive itself, after the delay
+// given to Retry or straight away after Release. Once it has been delivered
+// MaxReceives times it is produced to DeadLetterTopic, or dropped with a log
+// line when there is none, and acknowledged, so that it no longer holds back
+// the commits of its partition. Receive stops fetching while MaxInFlight
+// records are held.
+type OrderMessageQueueKafka struct {
+	Client *kgo.Client
+	Topic  string
+	Format MessageFormat
+	// PollTimeout is how long Receive waits for records.
+	PollTimeout time.Duration
+	// MaxReceives is how many times a record is delivered before it is dead-lettered.
+	MaxReceives int
+	// DeadLetterTopic is the topic records that keep failing are produced to.
+	DeadLetterTopic string
+	// MaxInFlight is how many received records are held before Receive stops fetching.
+	MaxInFlight int
+
+	mu       sync.Mutex
+	inFlight map[topicPartition][]*kafkaDelivery
+	held     int
+	retries  []*kafkaDelivery
+}
+
+// topicPartition identifies a Kafka partition.
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// kafkaDelivery is a received record, what it decoded to, how many times it
+// has been delivered, and whether it has been acknowledged or is waiting to be
+// delivered again.
+type kafkaDelivery struct {
+	record      *kgo.Record
+	decoded     bool
+	messageType string
+	body        []byte
+	receives    int
+	acked       bool
+	retryAt     time.Time
+}
+
+// NewOrderMessageQueueKafka creates a new Kafka message queue on a client made by
+// NewKafkaClient. Use NewKafkaConsumer for a queue that is received from.
+func NewOrderMessageQueueKafka(client *kgo.Client, topic string, format MessageFormat) *OrderMessageQueueKafka {
+	return &OrderMessageQueueKafka{
+		Client:      client,
+		Topic:       topic,
+		Format:      format,
+		PollTimeout: kafkaPollTimeout,
+		MaxReceives: kafkaMaxReceives,
+		MaxInFlight: kafkaMaxInFlight,
+		inFlight:    make(map[topicPartition][]*kafkaDelivery),
+	}
+}
+
+// Publish produces an event to the topic, keyed by its OrderId, and waits for
+// the brokers to acknowledge it.
+func (q *OrderMessageQueueKafka) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	message, err := q.Format.Encode(envelope)
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{
+		Topic: q.Topic,
+		Key:   []byte(strconv.Itoa(envelope.OrderID)),
+		Value: message.Body,
+	}
+	for name, value := range message.Attributes {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: name, Value: []byte(value)})
+	}
+	return q.Client.ProduceSync(ctx, record).FirstErr()
+}
+
+// Receive returns the records that are due to be delivered again or, when
+// there are none, waits up to PollTimeout for new records from the topic. A
+// record that cannot be decoded is logged and dead-lettered, as it could never
+// be processed.
+func (q *OrderMessageQueueKafka) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	var messages []*repository.OrderMessage
+	for _, delivery := range q.dueRetries(time.Now()) {
+		if !delivery.decoded {
+			q.deadLetterUndecodable(ctx, delivery)
+			continue
+		}
+		messages = append(messages, delivery.message())
+	}
+	if len(messages) > 0 {
+		return messages, nil
+	}
+
+	q.mu.Lock()
+	room := min(kafkaMaxMessages, q.MaxInFlight-q.held)
+	pollTimeout := q.PollTimeout
+	for _, delivery := range q.retries {
+		pollTimeout = min(pollTimeout, time.Until(delivery.retryAt))
+	}
+	q.mu.Unlock()
+	if room <= 0 {
+		return nil, nil
+	}
+
+	pollCtx, cancel := context.WithTimeout(ctx, max(pollTimeout, 0))
+	defer cancel()
+
+	fetches := q.Client.PollRecords(pollCtx, room)
+	if fetches.IsClientClosed() {
+		return nil, kgo.ErrClientClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	for _, fetchErr := range fetches.Errors() {
+		if !errors.Is(fetchErr.Err, context.DeadlineExceeded) {
+			return nil, fetchErr.Err
+		}
+	}
+
+	var undecodable []*kafkaDelivery
+	q.mu.Lock()
+	for _, record := range fetches.Records() {
+		partition := topicPartition{topic: record.Topic, partition: record.Partition}
+		delivery := &kafkaDelivery{record: record, receives: 1}
+		q.inFlight[partition] = append(q.inFlight[partition], delivery)
+		q.held++
+
+		attributes := make(map[string]string, len(record.Headers))
+		for _, header := range record.Headers {
+			attributes[header.Key] = string(header.Value)
+		}
+		messageType, body, err := DecodeMessage(&Message{Body: record.Value, Attributes: attributes})
+		if err != nil {
+			log.Printf("Error decoding message %s: %v", kafkaReceiptHandle(record), err)
+			undecodable = append(undecodable, delivery)
+			continue
+		}
+		delivery.messageType, delivery.body, delivery.decoded = messageType, body, true
+		messages = append(messages, delivery.message())
+	}
+	q.mu.Unlock()
+
+	for _, delivery := range undecodable {
+		q.deadLetterUndecodable(ctx, delivery)
+	}
+	return messages, nil
+}
+
+// deadLetterUndecodable dead-letters a record that cannot be decoded, trying
+// again after PollTimeout when that fails.
+func (q *OrderMessageQueueKafka) deadLetterUndecodable(ctx context.Context, delivery *kafkaDelivery) {
+	if err := q.deadLetter(ctx, delivery); err != nil {
+		log.Printf("Error dead-lettering message %s: %v", kafkaReceiptHandle(delivery.record), err)
+		q.scheduleRetry(delivery, q.PollTimeout)
+	}
+}
+
+// Ack acknowledges a received record and marks for commit every record of its
+// partition up to the first one still unacknowledged.
+func (q *OrderMessageQueueKafka) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	partition, delivery, ok := q.find(message.ReceiptHandle)
+	if !ok {
+		return errors.New("message not in flight")
+	}
+	q.ack(partition, delivery)
+	return nil
+}
+
+// Retry delivers a received record again from Receive after delay, or
+// dead-letters it once it has been delivered MaxReceives times.
+func (q *OrderMessageQueueKafka) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	q.mu.Lock()
+	_, delivery, ok := q.find(message.ReceiptHandle)
+	exhausted := ok && q.MaxReceives > 0 && delivery.receives >= q.MaxReceives
+	q.mu.Unlock()
+	if !ok {
+		return errors.New("message not in flight")
+	}
+
+	if exhausted {
+		if err := q.deadLetter(ctx, delivery); err != nil {
+			q.scheduleRetry(delivery, delay)
+			return err
+		}
+		return nil
+	}
+	q.scheduleRetry(delivery, delay)
+	return nil
+}
+
+// Release delivers a received record again from the next Receive, or
+// dead-letters it once it has been delivered MaxReceives times.
+func (q *OrderMessageQueueKafka) Release(ctx context.Context, message *repository.OrderMessage) error {
+	return q.Retry(ctx, message, 0)
+}
+
+// dueRetries takes the deliveries waiting to be delivered again whose time
+// has come off the retry list, counting the new delivery.
+func (q *OrderMessageQueueKafka) dueRetries(now time.Time) []*kafkaDelivery {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var due []*kafkaDelivery
+	waiting := q.retries[:0]
+	for _, delivery := range q.retries {
+		switch {
+		case delivery.acked:
+		case delivery.retryAt.After(now) || len(due) == kafkaMaxMessages:
+			waiting = append(waiting, delivery)
+		default:
+			delivery.receives++
+			delivery.retryAt = time.Time{}
+			due = append(due, delivery)
+		}
+	}
+	clear(q.retries[len(waiting):])
+	q.retries = waiting
+	return due
+}
+
+// scheduleRetry puts a delivery on the list of records to deliver again after delay.
+func (q *OrderMessageQueueKafka) scheduleRetry(delivery *kafkaDelivery, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if delivery.acked || !delivery.retryAt.IsZero() {
+		return
+	}
+	delivery.retryAt = time.Now().Add(delay)
+	q.retries = append(q.retries, delivery)
+}
+
+// deadLetter produces a record to DeadLetterTopic, or logs that it is dropped
+// when there is none, and acknowledges it.
+func (q *OrderMessageQueueKafka) deadLetter(ctx context.Context, delivery *kafkaDelivery) error {
+	handle := kafkaReceiptHandle(delivery.record)
+	if q.DeadLetterTopic == "" {
+		log.Printf("Dropping message %s after %d deliveries; no dead-letter topic is configured", handle, delivery.receives)
+	} else {
+		record := &kgo.Record{
+			Topic:   q.DeadLetterTopic,
+			Key:     delivery.record.Key,
+			Value:   delivery.record.Value,
+			Headers: append(slices.Clone(delivery.record.Headers), kgo.RecordHeader{Key: kafkaSourceHeader, Value: []byte(handle)}),
+		}
+		if err := q.Client.ProduceSync(ctx, record).FirstErr(); err != nil {
+			return err
+		}
+		log.Printf("Moved message %s to dead-letter topic %s after %d deliveries", handle, q.DeadLetterTopic, delivery.receives)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if partition, found, ok := q.find(handle); ok && found == delivery {
+		q.ack(partition, delivery)
+	}
+	return nil
+}
+
+// dropPartitions forgets the records of partitions the client no longer
+// consumes. Their offsets are committed by whoever consumes them next.
+func (q *OrderMessageQueueKafka) dropPartitions(ctx context.Context, client *kgo.Client, partitions map[string][]int32) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for topic, numbers := range partitions {
+		for _, number := range numbers {
+			partition := topicPartition{topic: topic, partition: number}
+			q.held -= len(q.inFlight[partition])
+			delete(q.inFlight, partition)
+		}
+	}
+	q.retries = slices.DeleteFunc(q.retries, func(delivery *kafkaDelivery) bool {
+		return slices.Contains(partitions[delivery.record.Topic], delivery.record.Partition)
+	})
+}
+
+// find returns the unacknowledged delivery with a receipt handle and its
+// partition. The caller holds q.mu.
+func (q *OrderMessageQueueKafka) find(handle string) (topicPartition, *kafkaDelivery, bool) {
+	for partition, deliveries := range q.inFlight {
+		for _, delivery := range deliveries {
+			if !delivery.acked && kafkaReceiptHandle(delivery.record) == handle {
+				return partition, delivery, true
+			}
+		}
+	}
+	return topicPartition{}, nil, false
+}
+
+// ack acknowledges a delivery and marks the acknowledged records at the head
+// of its partition for commit. The caller holds q.mu.
+func (q *OrderMessageQueueKafka) ack(partition topicPartition, delivery *kafkaDelivery) {
+	delivery.acked = true
+
+	deliveries := q.inFlight[partition]
+	done := 0
+	for done < len(deliveries) && deliveries[done].acked {
+		done++
+	}
+	if done == 0 {
+		return
+	}
+	q.Client.MarkCommitRecords(deliveries[done-1].record)
+	q.held -= done
+	if done == len(deliveries) {
+		delete(q.inFlight, partition)
+	} else {
+		q.inFlight[partition] = deliveries[done:]
+	}
+}
+
+// message returns the order message of a delivery.
+func (d *kafkaDelivery) message() *repository.OrderMessage {
+	handle := kafkaReceiptHandle(d.record)
+	return &repository.OrderMessage{ID: handle, Type: d.messageType, Body: d.body, ReceiptHandle: handle, ReceiveCount: d.receives}
+}
+
+// kafkaReceiptHandle identifies a record by its topic, partition and offset.
+func kafkaReceiptHandle(record *kgo.Record) string {
+	return fmt.Sprintf("%s/%d/%d", record.Topic, record.Partition, record.Offset)
+}
@@ -0,0 +1,236 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/messaging"
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+var _ = Describe("OrderMessageQueueKafka", func() {
+	const (
+		topic           = "orders"
+		deadLetterTopic = "orders-dlq"
+	)
+
+	var cluster *kfake.Cluster
+
+	// newQueue returns a queue on a new client, which consumes in group when it is set.
+	newQueue := func(group string, format messaging.MessageFormat) *messaging.OrderMessageQueueKafka {
+		var queue *messaging.OrderMessageQueueKafka
+		if group == "" {
+			client, err := messaging.NewKafkaClient(cluster.ListenAddrs(), topic, "", messaging.KafkaAcksAll, true)
+			Expect(err).NotTo(HaveOccurred())
+			queue = messaging.NewOrderMessageQueueKafka(client, topic, format)
+		} else {
+			var err error
+			queue, err = messaging.NewKafkaConsumer(cluster.ListenAddrs(), topic, group, messaging.KafkaAcksAll, true, format)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		DeferCleanup(queue.Client.Close)
+		queue.PollTimeout = 500 * time.Millisecond
+		return queue
+	}
+
+	// publish publishes an event for an order and returns its envelope.
+	publish := func(queue *messaging.OrderMessageQueueKafka, orderID int) *repository.EventEnvelope {
+		envelope := newEnvelope()
+		envelope.ID = time.Now().Format(time.RFC3339Nano)
+		envelope.OrderID = orderID
+		Expect(queue.Publish(context.Background(), envelope)).To(Succeed())
+		return envelope
+	}
+
+	// receive receives from a queue until it has n messages.
+	receive := func(queue *messaging.OrderMessageQueueKafka, n int) []*repository.OrderMessage {
+		var messages []*repository.OrderMessage
+		Eventually(func() []*repository.OrderMessage {
+			received, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			messages = append(messages, received...)
+			return messages
+		}).WithTimeout(10 * time.Second).Should(HaveLen(n))
+		return messages
+	}
+
+	BeforeEach(func() {
+		var err error
+		cluster, err = kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, topic, deadLetterTopic))
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(cluster.Close)
+	})
+
+	It("should key records by OrderId so that each order stays in one partition, in order", func() {
+		queue := newQueue("", messaging.MessageFormatEnvelope)
+		var published []string
+		for i := 0; i < 3; i++ {
+			published = append(published, publish(queue, 600).ID, publish(queue, 601).ID)
+		}
+
+		reader, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics(topic))
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+		var records []*kgo.Record
+		Eventually(func() []*kgo.Record {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			records = append(records, reader.PollFetches(ctx).Records()...)
+			return records
+		}).WithTimeout(10 * time.Second).Should(HaveLen(6))
+
+		partitions := map[string]int32{}
+		ids := map[string][]string{}
+		for _, record := range records {
+			key := string(record.Key)
+			if partition, ok := partitions[key]; ok {
+				Expect(record.Partition).To(Equal(partition))
+			}
+			partitions[key] = record.Partition
+			Expect(record.Headers).To(ContainElement(kgo.RecordHeader{Key: "type", Value: []byte("OrderPaid")}))
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(record.Value, &envelope)).To(Succeed())
+			ids[key] = append(ids[key], envelope.ID)
+		}
+		Expect(partitions).To(HaveKey("600"))
+		Expect(partitions).To(HaveKey("601"))
+		Expect(ids["600"]).To(Equal([]string{published[0], published[2], published[4]}))
+		Expect(ids["601"]).To(Equal([]string{published[1], published[3], published[5]}))
+	})
+
+	It("should receive CloudEvents from record headers", func() {
+		producer := newQueue("", messaging.MessageFormatCloudEventsBinary)
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		envelope := publish(producer, 600)
+
+		messages := receive(consumer, 1)
+
+		Expect(messages[0].Type).To(Equal("OrderPaid"))
+		var received repository.EventEnvelope
+		Expect(json.Unmarshal(messages[0].Body, &received)).To(Succeed())
+		Expect(received.ID).To(Equal(envelope.ID))
+		Expect(received.OrderID).To(Equal(600))
+		Expect(received.CorrelationID).To(Equal("checkout-42"))
+		Expect(consumer.Ack(context.Background(), messages[0])).To(Succeed())
+	})
+
+	It("should commit offsets only up to the first unacknowledged record", func() {
+		producer := newQueue("", messaging.MessageFormatEnvelope)
+		for i := 0; i < 3; i++ {
+			publish(producer, 600)
+		}
+
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		messages := receive(consumer, 3)
+		Expect(consumer.Ack(context.Background(), messages[0])).To(Succeed())
+		Expect(consumer.Ack(context.Background(), messages[2])).To(Succeed())
+		Expect(consumer.Client.CommitMarkedOffsets(context.Background())).To(Succeed())
+		consumer.Client.Close()
+
+		redelivered := receive(newQueue("order-worker", messaging.MessageFormatEnvelope), 2)
+		Expect(redelivered[0].ID).To(Equal(messages[1].ID))
+		Expect(redelivered[1].ID).To(Equal(messages[2].ID))
+	})
+
+	It("should deliver a retried record again after the delay, counting its deliveries", func() {
+		producer := newQueue("", messaging.MessageFormatEnvelope)
+		publish(producer, 600)
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		messages := receive(consumer, 1)
+		Expect(messages[0].ReceiveCount).To(Equal(1))
+
+		Expect(consumer.Retry(context.Background(), messages[0], 200*time.Millisecond)).To(Succeed())
+
+		redelivered := receive(consumer, 1)
+		Expect(redelivered[0].ID).To(Equal(messages[0].ID))
+		Expect(redelivered[0].ReceiveCount).To(Equal(2))
+		Expect(consumer.Ack(context.Background(), redelivered[0])).To(Succeed())
+	})
+
+	It("should move a record to the dead-letter topic after MaxReceives deliveries and commit past it", func() {
+		producer := newQueue("", messaging.MessageFormatEnvelope)
+		publish(producer, 600)
+		publish(producer, 600)
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		consumer.MaxReceives = 2
+		consumer.DeadLetterTopic = deadLetterTopic
+		messages := receive(consumer, 2)
+
+		Expect(consumer.Release(context.Background(), messages[0])).To(Succeed())
+		Expect(consumer.Ack(context.Background(), messages[1])).To(Succeed())
+		redelivered := receive(consumer, 1)
+		Expect(redelivered[0].ReceiveCount).To(Equal(2))
+		Expect(consumer.Release(context.Background(), redelivered[0])).To(Succeed())
+		Expect(consumer.Client.CommitMarkedOffsets(context.Background())).To(Succeed())
+
+		reader, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...), kgo.ConsumeTopics(deadLetterTopic))
+		Expect(err).NotTo(HaveOccurred())
+		defer reader.Close()
+		var deadLetters []*kgo.Record
+		Eventually(func() []*kgo.Record {
+			ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+			defer cancel()
+			deadLetters = append(deadLetters, reader.PollFetches(ctx).Records()...)
+			return deadLetters
+		}).WithTimeout(10 * time.Second).Should(HaveLen(1))
+		Expect(deadLetters[0].Key).To(Equal([]byte("600")))
+		Expect(deadLetters[0].Headers).To(ContainElement(kgo.RecordHeader{Key: "dead-letter-source", Value: []byte(messages[0].ID)}))
+
+		consumer.Client.Close()
+		publish(producer, 600)
+		received := receive(newQueue("order-worker", messaging.MessageFormatEnvelope), 1)
+		Expect(received[0].ID).NotTo(BeElementOf(messages[0].ID, messages[1].ID))
+	})
+
+	It("should stop fetching while MaxInFlight records are held", func() {
+		producer := newQueue("", messaging.MessageFormatEnvelope)
+		for i := 0; i < 3; i++ {
+			publish(producer, 600)
+		}
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		consumer.MaxInFlight = 2
+
+		messages := receive(consumer, 2)
+		received, err := consumer.Receive(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(received).To(BeEmpty())
+
+		Expect(consumer.Ack(context.Background(), messages[0])).To(Succeed())
+		Expect(receive(consumer, 1)[0].ID).NotTo(Equal(messages[1].ID))
+	})
+
+	It("should reject acknowledging a message that is not in flight", func() {
+		queue := newQueue("order-worker", messaging.MessageFormatEnvelope)
+
+		err := queue.Ack(context.Background(), &repository.OrderMessage{ReceiptHandle: "orders/0/42"})
+
+		Expect(err).To(MatchError("message not in flight"))
+	})
+
+	Describe("NewKafkaClient", func() {
+		It("should reject an idempotent producer without acks from all replicas", func() {
+			_, err := messaging.NewKafkaClient(cluster.ListenAddrs(), topic, "", messaging.KafkaAcksLeader, true)
+
+			Expect(err).To(MatchError(ContainSubstring(`needs acks "all"`)))
+		})
+
+		It("should reject unknown acks", func() {
+			_, err := messaging.NewKafkaClient(cluster.ListenAddrs(), topic, "", "some", false)
+
+			Expect(err).To(MatchError(`unsupported Kafka acks "some"`))
+		})
+
+		It("should produce with fewer acks when idempotence is off", func() {
+			client, err := messaging.NewKafkaClient(cluster.ListenAddrs(), topic, "", messaging.KafkaAcksLeader, false)
+			Expect(err).NotTo(HaveOccurred())
+			defer client.Close()
+
+			Expect(messaging.NewOrderMessageQueueKafka(client, topic, messaging.MessageFormatEnvelope).Publish(context.Background(), newEnvelope())).To(Succeed())
+		})
+	})
+})
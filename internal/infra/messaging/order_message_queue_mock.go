@@ -1,26 +1,422 @@
 package messaging
 
 import (
-	"GoCleanArch/internal/domain/entity"
-	"encoding/json"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
 )
 
-// OrderMessageQueueMock is a mock implementation of the OrderMessageQueue interface.
-type OrderMessageQueueMock struct{}
+const (
+	// mockVisibilityTimeout is how long a received message stays hidden, as on SQS.
+	mockVisibilityTimeout = 30 * time.Second
+	// mockWaitTimeout is how long WaitFor waits.
+	mockWaitTimeout = 5 * time.Second
+	// mockMaxPublished is how many published messages Messages and Published keep.
+	mockMaxPublished = 1000
+	// mockMaxDeadLetters is how many dead-lettered messages the dead-letter queue keeps.
+	mockMaxDeadLetters = 1000
+)
+
+// MessageHandler processes a message delivered to a subscriber.
+type MessageHandler func(ctx context.Context, message *repository.OrderMessage) error
+
+// OrderMessageQueueMock is an in-memory implementation of the
+// BatchEventPublisher and OrderMessageConsumer interfaces that behaves like an
+// SQS queue. Published messages wait on the queue until they are received,
+// with Receive or by a subscriber, and stay on it, hidden, until they are
+// acknowledged. A message that is not acknowledged within VisibilityTimeout is
+// delivered again with a new receipt handle, and the old handle can no longer
+// acknowledge it. Retry changes how long a received message stays hidden.
+//
+// Like an SQS queue with a redrive policy, a message that has been delivered
+// MaxReceives times is moved to the queue's dead-letter queue instead of being
+// delivered again; a zero MaxReceives delivers messages forever. Messages that
+// cannot be decoded are dead-lettered straight away. DeadLetters lists the
+// dead-lettered messages and Redrive puts them back on the queue. Only the last
+// MaxDeadLetters of them are kept.
+//
+// Format is the format messages are encoded in, as they would be on SQS; the
+// zero value is MessageFormatEnvelope. Messages, Drain and WaitFor let tests
+// see what was published. Only the last MaxPublished messages are kept for
+// Messages and Published, so that a long-running development server does not
+// hold on to every message it ever published.
+type OrderMessageQueueMock struct {
+	Format            MessageFormat
+	VisibilityTimeout time.Duration
+	MaxReceives       int
+	// WaitTimeout is how long WaitFor waits before giving up.
+	WaitTimeout time.Duration
+	// MaxPublished is how many published messages are kept; zero keeps them all.
+	MaxPublished int
+	// MaxDeadLetters is how many dead-lettered messages are kept; zero keeps them all.
+	MaxDeadLetters int
+
+	mu sync.Mutex
+	// nextID counts the messages published so far.
+	nextID    int
+	published []*queuedMessage
+	pending   []*queuedMessage
+	inFlight  map[string]*queuedMessage
+	dead      []*queuedMessage
+	// changed is closed and replaced whenever a message is published or
+	// acknowledged, to wake up subscribers and WaitFor.
+	changed chan struct{}
+}
+
+// queuedMessage is a message on the in-memory queue.
+type queuedMessage struct {
+	id string
+	// seq is the message's place in the order messages were published.
+	seq     int
+	message *Message
+	sentAt  time.Time
+	// receives counts deliveries; it makes each receipt handle unique.
+	receives int
+	// hiddenUntil is when an unacknowledged delivery becomes visible again.
+	hiddenUntil time.Time
+}
 
 // NewOrderMessageQueueMock creates a new OrderMessageQueueMock.
 func NewOrderMessageQueueMock() *OrderMessageQueueMock {
-	return &OrderMessageQueueMock{}
+	return &OrderMessageQueueMock{
+		VisibilityTimeout: mockVisibilityTimeout,
+		WaitTimeout:       mockWaitTimeout,
+		MaxPublished:      mockMaxPublished,
+		MaxDeadLetters:    mockMaxDeadLetters,
+		inFlight:          make(map[string]*queuedMessage),
+		changed:           make(chan struct{}),
+	}
 }
 
-// Send simulates sending an order message to a queue.
-func (m *OrderMessageQueueMock) Send(order *entity.Order) error {
-	jsonData, err := json.Marshal(order)
+// Publish encodes an event in the queue's format and puts it on the queue.
+func (m *OrderMessageQueueMock) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	message, err := m.Format.Encode(envelope)
 	if err != nil {
-		log.Printf("Error marshalling order for message queue: %v", err)
+		log.Printf("Error encoding %s event for message queue: %v", envelope.Type, err)
 		return err
 	}
-	log.Printf("Simulating sending message to SQS: %s", string(jsonData))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	queued := &queuedMessage{id: strconv.Itoa(m.nextID), seq: m.nextID, message: message, sentAt: time.Now()}
+	m.published = append(m.published, queued)
+	if m.MaxPublished > 0 && len(m.published) > m.MaxPublished {
+		m.published = slices.Delete(m.published, 0, len(m.published)-m.MaxPublished)
+	}
+	m.pending = append(m.pending, queued)
+	m.notify()
+	log.Printf("Simulating sending %s event to SQS: %s", envelope.Type, string(message.Body))
 	return nil
 }
+
+// PublishBatch publishes each event in turn.
+func (m *OrderMessageQueueMock) PublishBatch(ctx context.Context, envelopes []*repository.EventEnvelope) []error {
+	errs := make([]error, len(envelopes))
+	for i, envelope := range envelopes {
+		errs[i] = m.Publish(ctx, envelope)
+	}
+	return errs
+}
+
+// Receive returns every visible message and hides them for VisibilityTimeout.
+func (m *OrderMessageQueueMock) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.restoreExpired(time.Now())
+	messages := make([]*repository.OrderMessage, 0, len(m.pending))
+	for len(m.pending) > 0 {
+		if message := m.deliverNext(); message != nil {
+			messages = append(messages, message)
+		}
+	}
+	return messages, nil
+}
+
+// Ack removes a received message from the queue. It fails once the message's
+// visibility timeout has passed, as the message may have been delivered again.
+func (m *OrderMessageQueueMock) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued, ok := m.inFlight[message.ReceiptHandle]
+	if !ok || !time.Now().Before(queued.hiddenUntil) {
+		return errors.New("message not in flight")
+	}
+	delete(m.inFlight, message.ReceiptHandle)
+	m.notify()
+	return nil
+}
+
+// Retry hides a received message for delay instead of VisibilityTimeout, after
+// which it is delivered again.
+func (m *OrderMessageQueueMock) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued, ok := m.inFlight[message.ReceiptHandle]
+	if !ok || !time.Now().Before(queued.hiddenUntil) {
+		return errors.New("message not in flight")
+	}
+	queued.hiddenUntil = time.Now().Add(delay)
+	m.notify()
+	return nil
+}
+
+// Subscribe delivers each message on the queue to handler, one at a time, as
+// soon as it is visible. A message is acknowledged when handler returns nil
+// and delivered again after VisibilityTimeout when it returns an error.
+// Subscribers compete with each other and with Receive for messages. The
+// returned function stops the subscription and waits for handler to return.
+func (m *OrderMessageQueueMock) Subscribe(handler MessageHandler) (unsubscribe func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for {
+			message, changed, wait := m.next()
+			if message == nil {
+				timer := time.NewTimer(wait)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-changed:
+				case <-timer.C:
+				}
+				timer.Stop()
+				continue
+			}
+
+			if err := handler(ctx, message); err != nil {
+				log.Printf("Error handling message %s: %v", message.ID, err)
+				continue
+			}
+			if err := m.Ack(ctx, message); err != nil {
+				log.Printf("Error acknowledging message %s: %v", message.ID, err)
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Messages returns the last MaxPublished messages published so far, in order,
+// decoded as a consumer would receive them. Receiving or acknowledging
+// messages does not change what it returns.
+func (m *OrderMessageQueueMock) Messages() []*repository.OrderMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return decodeQueued(m.published)
+}
+
+// Drain removes every message from the queue, whether it is waiting or being
+// processed, and returns them in the order they were published.
+func (m *OrderMessageQueueMock) Drain() []*repository.OrderMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	drained := slices.Clone(m.pending)
+	for _, queued := range m.inFlight {
+		if !slices.Contains(drained, queued) {
+			drained = append(drained, queued)
+		}
+	}
+	slices.SortFunc(drained, func(a, b *queuedMessage) int { return a.seq - b.seq })
+	m.pending = nil
+	clear(m.inFlight)
+	m.notify()
+	return decodeQueued(drained)
+}
+
+// WaitFor waits until at least n messages have been published and returns
+// Messages, which holds at most MaxPublished of them. It gives up with an
+// error after WaitTimeout.
+func (m *OrderMessageQueueMock) WaitFor(n int) ([]*repository.OrderMessage, error) {
+	timeout := time.NewTimer(m.WaitTimeout)
+	defer timeout.Stop()
+
+	for {
+		m.mu.Lock()
+		count, changed := m.nextID, m.changed
+		m.mu.Unlock()
+		if count >= n {
+			return m.Messages(), nil
+		}
+
+		select {
+		case <-changed:
+		case <-timeout.C:
+			return nil, fmt.Errorf("%d of %d messages published after %s", count, n, m.WaitTimeout)
+		}
+	}
+}
+
+// DeadLetters returns the messages on the dead-letter queue, in the order they
+// were dead-lettered.
+func (m *OrderMessageQueueMock) DeadLetters() []*DeadLetter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	deadLetters := make([]*DeadLetter, 0, len(m.dead))
+	for _, queued := range m.dead {
+		deadLetters = append(deadLetters, &DeadLetter{
+			ID:           queued.id,
+			Type:         queued.message.Attributes[messageTypeAttribute],
+			SentAt:       queued.sentAt,
+			ReceiveCount: queued.receives,
+			Body:         append([]byte(nil), queued.message.Body...),
+			Attributes:   maps.Clone(queued.message.Attributes),
+		})
+	}
+	return deadLetters
+}
+
+// Redrive moves the dead-lettered messages with the given IDs, or every
+// dead-lettered message when no ID is given, back to the queue with their
+// receive counts reset. It returns how many messages it moved.
+func (m *OrderMessageQueueMock) Redrive(ids ...string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var kept []*queuedMessage
+	moved := 0
+	for _, queued := range m.dead {
+		if len(ids) > 0 && !slices.Contains(ids, queued.id) {
+			kept = append(kept, queued)
+			continue
+		}
+		queued.receives = 0
+		m.pending = append(m.pending, queued)
+		moved++
+	}
+	m.dead = kept
+	m.notify()
+	return moved
+}
+
+// Published returns a copy of the last MaxPublished messages published so far,
+// in order and as they would appear on the queue. Consumer contract tests use it to check the
+// wire format.
+func (m *OrderMessageQueueMock) Published() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	published := make([]*Message, 0, len(m.published))
+	for _, queued := range m.published {
+		published = append(published, &Message{
+			Body:       append([]byte(nil), queued.message.Body...),
+			Attributes: maps.Clone(queued.message.Attributes),
+		})
+	}
+	return published
+}
+
+// next delivers the next visible message to a subscriber. When there is none
+// it returns a channel that is closed on the next change and how long until
+// the next hidden message becomes visible again.
+func (m *OrderMessageQueueMock) next() (*repository.OrderMessage, <-chan struct{}, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.restoreExpired(now)
+	for len(m.pending) > 0 {
+		if message := m.deliverNext(); message != nil {
+			return message, nil, 0
+		}
+	}
+
+	wait := m.VisibilityTimeout
+	for _, queued := range m.inFlight {
+		wait = min(wait, queued.hiddenUntil.Sub(now))
+	}
+	return nil, m.changed, wait
+}
+
+// deliverNext takes the first waiting message off the queue, hides it and
+// returns it decoded. It returns nil when it moves the message to the
+// dead-letter queue instead, because the message has been delivered
+// MaxReceives times or cannot be decoded. The caller holds m.mu and has
+// checked that a message is waiting.
+func (m *OrderMessageQueueMock) deliverNext() *repository.OrderMessage {
+	queued := m.pending[0]
+	m.pending = m.pending[1:]
+
+	if m.MaxReceives > 0 && queued.receives >= m.MaxReceives {
+		log.Printf("Moving message %s to the dead-letter queue after %d deliveries", queued.id, queued.receives)
+		m.deadLetter(queued)
+		return nil
+	}
+	messageType, body, err := DecodeMessage(queued.message)
+	if err != nil {
+		log.Printf("Moving message %s to the dead-letter queue: %v", queued.id, err)
+		m.deadLetter(queued)
+		return nil
+	}
+	queued.receives++
+	queued.hiddenUntil = time.Now().Add(m.VisibilityTimeout)
+	handle := queued.id + "-" + strconv.Itoa(queued.receives)
+	m.inFlight[handle] = queued
+	return &repository.OrderMessage{ID: queued.id, Type: messageType, Body: body, ReceiptHandle: handle, ReceiveCount: queued.receives}
+}
+
+// deadLetter moves a message to the dead-letter queue, dropping the oldest
+// dead letter when it holds MaxDeadLetters already. The caller holds m.mu.
+func (m *OrderMessageQueueMock) deadLetter(queued *queuedMessage) {
+	m.dead = append(m.dead, queued)
+	if m.MaxDeadLetters > 0 && len(m.dead) > m.MaxDeadLetters {
+		m.dead = slices.Delete(m.dead, 0, len(m.dead)-m.MaxDeadLetters)
+	}
+}
+
+// restoreExpired puts the messages whose visibility timeout has passed back on
+// the queue, in the order they were published. The caller holds m.mu.
+func (m *OrderMessageQueueMock) restoreExpired(now time.Time) {
+	var expired []*queuedMessage
+	for handle, queued := range m.inFlight {
+		if !now.Before(queued.hiddenUntil) {
+			delete(m.inFlight, handle)
+			expired = append(expired, queued)
+		}
+	}
+	slices.SortFunc(expired, func(a, b *queuedMessage) int { return a.seq - b.seq })
+	m.pending = append(m.pending, expired...)
+}
+
+// notify wakes up everything waiting for the queue to change. The caller holds m.mu.
+func (m *OrderMessageQueueMock) notify() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// decodeQueued decodes queued messages. Messages that cannot be decoded are left out.
+func decodeQueued(queued []*queuedMessage) []*repository.OrderMessage {
+	messages := make([]*repository.OrderMessage, 0, len(queued))
+	for _, q := range queued {
+		messageType, body, err := DecodeMessage(q.message)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, &repository.OrderMessage{ID: q.id, Type: messageType, Body: body})
+	}
+	return messages
+}
@@ -0,0 +1,268 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/messaging"
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrderMessageQueueMock", func() {
+	DescribeTable("should publish in its format and receive the envelope",
+		func(format messaging.MessageFormat, contentType string) {
+			queue := messaging.NewOrderMessageQueueMock()
+			queue.Format = format
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+
+			published := queue.Published()
+			Expect(published).To(HaveLen(1))
+			Expect(published[0].Attributes["content-type"]).To(Equal(contentType))
+
+			messages, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].Type).To(Equal("OrderPaid"))
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(messages[0].Body, &envelope)).To(Succeed())
+			Expect(envelope.ID).To(Equal(newEnvelope().ID))
+			Expect(envelope.CorrelationID).To(Equal("checkout-42"))
+			Expect(queue.Ack(context.Background(), messages[0])).To(Succeed())
+		},
+		Entry("envelope", messaging.MessageFormatEnvelope, ""),
+		Entry("CloudEvents structured", messaging.MessageFormatCloudEventsStructured, "application/cloudevents+json"),
+		Entry("CloudEvents binary", messaging.MessageFormatCloudEventsBinary, "application/json"),
+	)
+})
+
+var _ = Describe("OrderMessageQueueMock queue", func() {
+	var queue *messaging.OrderMessageQueueMock
+
+	BeforeEach(func() {
+		queue = messaging.NewOrderMessageQueueMock()
+		queue.VisibilityTimeout = 50 * time.Millisecond
+		queue.WaitTimeout = time.Second
+	})
+
+	Context("when a received message is not acknowledged", func() {
+		It("should deliver it again after the visibility timeout with a new receipt handle", func() {
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+
+			first, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(HaveLen(1))
+			Expect(queue.Receive(context.Background())).To(BeEmpty())
+
+			var second []*repository.OrderMessage
+			Eventually(func() []*repository.OrderMessage {
+				second, _ = queue.Receive(context.Background())
+				return second
+			}).Should(HaveLen(1))
+			Expect(second[0].ID).To(Equal(first[0].ID))
+			Expect(second[0].ReceiptHandle).NotTo(Equal(first[0].ReceiptHandle))
+
+			Expect(queue.Ack(context.Background(), first[0])).To(MatchError("message not in flight"))
+			Expect(queue.Ack(context.Background(), second[0])).To(Succeed())
+		})
+
+		It("should deliver the messages again in the order they were published", func() {
+			for range 20 {
+				Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			}
+			first, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first).To(HaveLen(20))
+
+			var second []*repository.OrderMessage
+			Eventually(func() []*repository.OrderMessage {
+				second, _ = queue.Receive(context.Background())
+				return second
+			}).Should(HaveLen(20))
+			for i, message := range second {
+				Expect(message.ID).To(Equal(strconv.Itoa(i + 1)))
+			}
+		})
+	})
+
+	Context("with a subscriber", func() {
+		It("should acknowledge the messages it handles and deliver the ones it fails again", func() {
+			var mu sync.Mutex
+			deliveries := map[string]int{}
+			unsubscribe := queue.Subscribe(func(ctx context.Context, message *repository.OrderMessage) error {
+				mu.Lock()
+				defer mu.Unlock()
+				deliveries[message.Type]++
+				if message.Type == "OrderRefunded" && deliveries[message.Type] == 1 {
+					return errors.New("refunds are down")
+				}
+				return nil
+			})
+			DeferCleanup(unsubscribe)
+
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			refunded := newEnvelope()
+			refunded.Type = "OrderRefunded"
+			Expect(queue.Publish(context.Background(), refunded)).To(Succeed())
+
+			Eventually(func() map[string]int {
+				mu.Lock()
+				defer mu.Unlock()
+				return maps.Clone(deliveries)
+			}).Should(Equal(map[string]int{"OrderPaid": 1, "OrderRefunded": 2}))
+			unsubscribe()
+			Expect(queue.Drain()).To(BeEmpty())
+			Expect(queue.Messages()).To(HaveLen(2))
+		})
+	})
+
+	Describe("Retry", func() {
+		It("should hide the message for the given delay instead of the visibility timeout", func() {
+			queue.VisibilityTimeout = time.Hour
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			received, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(received[0].ReceiveCount).To(Equal(1))
+
+			Expect(queue.Retry(context.Background(), received[0], 20*time.Millisecond)).To(Succeed())
+
+			var again []*repository.OrderMessage
+			Eventually(func() []*repository.OrderMessage {
+				again, _ = queue.Receive(context.Background())
+				return again
+			}).Should(HaveLen(1))
+			Expect(again[0].ReceiveCount).To(Equal(2))
+			Expect(queue.Retry(context.Background(), received[0], time.Millisecond)).To(MatchError("message not in flight"))
+		})
+	})
+
+	Context("with a maximum receive count", func() {
+		BeforeEach(func() {
+			queue.MaxReceives = 2
+		})
+
+		It("should move a message to the dead-letter queue instead of delivering it too often", func() {
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			unsubscribe := queue.Subscribe(func(ctx context.Context, message *repository.OrderMessage) error {
+				return errors.New("payments are down")
+			})
+			DeferCleanup(unsubscribe)
+
+			Eventually(queue.DeadLetters).Should(HaveLen(1))
+			deadLetter := queue.DeadLetters()[0]
+			Expect(deadLetter.ID).To(Equal("1"))
+			Expect(deadLetter.Type).To(Equal("OrderPaid"))
+			Expect(deadLetter.ReceiveCount).To(Equal(2))
+			Expect(deadLetter.SentAt).To(BeTemporally("~", time.Now(), time.Second))
+			_, envelope := decodeEnvelope(&messaging.Message{Body: deadLetter.Body, Attributes: deadLetter.Attributes})
+			Expect(envelope.ID).To(Equal(newEnvelope().ID))
+		})
+
+		It("should redrive dead letters back to the queue with a new receive count", func() {
+			for range 2 {
+				Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			}
+			Eventually(func() []*messaging.DeadLetter {
+				Expect(queue.Receive(context.Background())).Error().NotTo(HaveOccurred())
+				return queue.DeadLetters()
+			}).Should(HaveLen(2))
+
+			Expect(queue.Redrive("2", "unknown")).To(Equal(1))
+			Expect(queue.DeadLetters()).To(HaveLen(1))
+			redriven, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(redriven).To(HaveLen(1))
+			Expect(redriven[0].ID).To(Equal("2"))
+			Expect(redriven[0].ReceiveCount).To(Equal(1))
+
+			Expect(queue.Redrive()).To(Equal(1))
+			Expect(queue.DeadLetters()).To(BeEmpty())
+		})
+
+		It("should keep only the last MaxDeadLetters dead letters", func() {
+			queue.MaxDeadLetters = 2
+			for range 3 {
+				Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			}
+			Eventually(func() []string {
+				Expect(queue.Receive(context.Background())).Error().NotTo(HaveOccurred())
+				var ids []string
+				for _, deadLetter := range queue.DeadLetters() {
+					ids = append(ids, deadLetter.ID)
+				}
+				return ids
+			}).Should(Equal([]string{"2", "3"}))
+		})
+	})
+
+	Describe("Drain", func() {
+		It("should remove the waiting and received messages and leave the published ones", func() {
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			received, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			Expect(queue.Ack(context.Background(), received[0])).To(Succeed())
+
+			drained := queue.Drain()
+			Expect(drained).To(HaveLen(2))
+			Expect(drained[0].ID).To(Equal("2"))
+			Expect(drained[1].ID).To(Equal("3"))
+
+			Expect(queue.Receive(context.Background())).To(BeEmpty())
+			Expect(queue.Ack(context.Background(), received[0])).To(MatchError("message not in flight"))
+			Expect(queue.Messages()).To(HaveLen(3))
+		})
+	})
+
+	Describe("Messages", func() {
+		It("should keep only the last MaxPublished messages", func() {
+			queue.MaxPublished = 2
+			for range 3 {
+				Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+			}
+
+			messages, err := queue.WaitFor(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(2))
+			Expect(messages[0].ID).To(Equal("2"))
+			Expect(messages[1].ID).To(Equal("3"))
+			Expect(queue.Published()).To(HaveLen(2))
+
+			drained := queue.Drain()
+			Expect(drained).To(HaveLen(3))
+			Expect(drained[0].ID).To(Equal("1"))
+		})
+	})
+
+	Describe("WaitFor", func() {
+		It("should return once enough messages are published", func() {
+			go func() {
+				defer GinkgoRecover()
+				for range 3 {
+					time.Sleep(10 * time.Millisecond)
+					Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+				}
+			}()
+
+			messages, err := queue.WaitFor(3)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(3))
+			Expect(messages[2].Type).To(Equal("OrderPaid"))
+		})
+
+		It("should fail after the wait timeout", func() {
+			queue.WaitTimeout = 20 * time.Millisecond
+			Expect(queue.Publish(context.Background(), newEnvelope())).To(Succeed())
+
+			_, err := queue.WaitFor(2)
+			Expect(err).To(MatchError("1 of 2 messages published after 20ms"))
+		})
+	})
+})
@@ -0,0 +1,239 @@
+package messaging
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// DriverNATS is the messaging.driver setting for NATS JetStream.
+const DriverNATS = "nats"
+
+const (
+	// natsMaxMessages is the most messages a single receive returns.
+	natsMaxMessages = 100
+	// natsPollTimeout is how long a receive waits for messages, like SQS long polling.
+	natsPollTimeout = 20 * time.Second
+	// natsAckWait is how long a delivered message waits for an ack before it is delivered again.
+	natsAckWait = 30 * time.Second
+)
+
+// ConnectNATS connects to a NATS server and creates or updates the JetStream
+// stream that stores the events published under subject. Messages published
+// twice with the same Nats-Msg-Id within duplicateWindow are stored once; a
+// zero window keeps the server's default of two minutes.
+func ConnectNATS(ctx context.Context, url, stream, subject string, duplicateWindow time.Duration) (*nats.Conn, jetstream.JetStream, error) {
+	conn, err := nats.Connect(url, nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       stream,
+		Subjects:   []string{subject + ".>"},
+		Storage:    jetstream.FileStorage,
+		Duplicates: duplicateWindow,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return conn, js, nil
+}
+
+// NATSMsgID returns the Nats-Msg-Id of an event: its OrderId followed by the
+// envelope ID. The OrderId alone would let the stream drop every event of an
+// order after the first, while the envelope ID is the same each time the
+// outbox relay retries an event, so the stream stores the event once.
+func NATSMsgID(envelope *repository.EventEnvelope) string {
+	return strconv.Itoa(envelope.OrderID) + "-" + envelope.ID
+}
+
+// OrderMessageQueueNATS implements the EventPublisher and OrderMessageConsumer
+// interfaces for NATS JetStream. Events are published to Subject followed by
+// their type, such as "orders.OrderCreated", with a Nats-Msg-Id the stream
+// deduplicates on. Message attributes travel as message headers. Events are
+// published in Format; messages in any format are received.
+//
+// Receive pulls from the durable consumer Consumer, which is created on the
+// stream the first time it is needed. A message that is not acknowledged
+// within AckWait is delivered again, at most MaxDeliver times; a message
+// delivered that often stays in the stream but is not delivered any more.
+type OrderMessageQueueNATS struct {
+	JetStream jetstream.JetStream
+	Stream    string
+	Subject   string
+	Consumer  string
+	Format    MessageFormat
+	// AckWait is how long a received message waits for Ack before it is delivered again.
+	AckWait time.Duration
+	// PollTimeout is how long Receive waits for messages.
+	PollTimeout time.Duration
+	// MaxDeliver is how many times a message is delivered; zero delivers it until it is acknowledged.
+	MaxDeliver int
+
+	mu       sync.Mutex
+	consumer jetstream.Consumer
+	inFlight map[string]jetstream.Msg
+}
+
+// NewOrderMessageQueueNATS creates a new NATS JetStream message queue on a stream made by ConnectNATS.
+func NewOrderMessageQueueNATS(js jetstream.JetStream, stream, subject, consumer string, format MessageFormat) *OrderMessageQueueNATS {
+	return &OrderMessageQueueNATS{
+		JetStream:   js,
+		Stream:      stream,
+		Subject:     subject,
+		Consumer:    consumer,
+		Format:      format,
+		AckWait:     natsAckWait,
+		PollTimeout: natsPollTimeout,
+		inFlight:    make(map[string]jetstream.Msg),
+	}
+}
+
+// Publish publishes an event to the stream and waits for the server to store
+// it. An event the stream has already stored is not stored again.
+func (q *OrderMessageQueueNATS) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	message, err := q.Format.Encode(envelope)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(q.Subject + "." + envelope.Type)
+	msg.Data = message.Body
+	for name, value := range message.Attributes {
+		msg.Header.Set(name, value)
+	}
+	_, err = q.JetStream.PublishMsg(ctx, msg, jetstream.WithMsgID(NATSMsgID(envelope)))
+	return err
+}
+
+// Receive waits up to PollTimeout for messages from the durable consumer. A
+// message that cannot be decoded is logged and terminated, as it could never
+// be processed.
+func (q *OrderMessageQueueNATS) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	consumer, err := q.durableConsumer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := consumer.Fetch(natsMaxMessages, jetstream.FetchMaxWait(q.PollTimeout))
+	if err != nil {
+		return nil, err
+	}
+
+	var received []jetstream.Msg
+	for msg := range batch.Messages() {
+		received = append(received, msg)
+	}
+	if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	messages := make([]*repository.OrderMessage, 0, len(received))
+	for _, msg := range received {
+		metadata, err := msg.Metadata()
+		if err != nil {
+			log.Printf("Error reading metadata of message on %s: %v", msg.Subject(), err)
+			continue
+		}
+		id := msg.Headers().Get(jetstream.MsgIDHeader)
+		handle := strconv.FormatUint(metadata.Sequence.Stream, 10)
+
+		attributes := make(map[string]string, len(msg.Headers()))
+		for name := range msg.Headers() {
+			attributes[name] = msg.Headers().Get(name)
+		}
+		messageType, body, err := DecodeMessage(&Message{Body: msg.Data(), Attributes: attributes})
+		if err != nil {
+			log.Printf("Error decoding message %s: %v", id, err)
+			if err := msg.Term(); err != nil {
+				log.Printf("Error terminating message %s: %v", id, err)
+			}
+			continue
+		}
+		q.inFlight[handle] = msg
+		messages = append(messages, &repository.OrderMessage{ID: id, Type: messageType, Body: body, ReceiptHandle: handle, ReceiveCount: int(metadata.NumDelivered)})
+	}
+	return messages, nil
+}
+
+// Ack acknowledges a received message, so that it is not delivered again.
+func (q *OrderMessageQueueNATS) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	msg, err := q.takeInFlight(message)
+	if err != nil {
+		return err
+	}
+	return msg.DoubleAck(ctx)
+}
+
+// Retry hands a received message back to the consumer, which delivers it
+// again after delay instead of waiting for AckWait.
+func (q *OrderMessageQueueNATS) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	msg, err := q.takeInFlight(message)
+	if err != nil {
+		return err
+	}
+	if delay <= 0 {
+		return msg.Nak()
+	}
+	return msg.NakWithDelay(delay)
+}
+
+// Release hands a received message back to the consumer, which delivers it again right away.
+func (q *OrderMessageQueueNATS) Release(ctx context.Context, message *repository.OrderMessage) error {
+	return q.Retry(ctx, message, 0)
+}
+
+// takeInFlight removes a received message from the messages in flight.
+func (q *OrderMessageQueueNATS) takeInFlight(message *repository.OrderMessage) (jetstream.Msg, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msg, ok := q.inFlight[message.ReceiptHandle]
+	if !ok {
+		return nil, errors.New("message not in flight")
+	}
+	delete(q.inFlight, message.ReceiptHandle)
+	return msg, nil
+}
+
+// durableConsumer returns the durable pull consumer, creating or updating it on
+// the stream the first time.
+func (q *OrderMessageQueueNATS) durableConsumer(ctx context.Context) (jetstream.Consumer, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.consumer != nil {
+		return q.consumer, nil
+	}
+	if q.Consumer == "" {
+		return nil, errors.New("no NATS consumer is configured")
+	}
+	consumer, err := q.JetStream.CreateOrUpdateConsumer(ctx, q.Stream, jetstream.ConsumerConfig{
+		Durable:       q.Consumer,
+		FilterSubject: q.Subject + ".>",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       q.AckWait,
+		MaxDeliver:    q.MaxDeliver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating NATS consumer %q: %w", q.Consumer, err)
+	}
+	q.consumer = consumer
+	return consumer, nil
+}
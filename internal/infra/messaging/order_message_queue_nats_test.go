@@ -0,0 +1,162 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/messaging"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OrderMessageQueueNATS", func() {
+	const (
+		stream  = "ORDERS"
+		subject = "orders"
+	)
+
+	var (
+		url string
+		js  jetstream.JetStream
+	)
+
+	newQueue := func(consumer string, format messaging.MessageFormat) *messaging.OrderMessageQueueNATS {
+		queue := messaging.NewOrderMessageQueueNATS(js, stream, subject, consumer, format)
+		queue.PollTimeout = 200 * time.Millisecond
+		return queue
+	}
+
+	// receive receives from a queue until it has n messages.
+	receive := func(queue *messaging.OrderMessageQueueNATS, n int) []*repository.OrderMessage {
+		var messages []*repository.OrderMessage
+		Eventually(func() []*repository.OrderMessage {
+			received, err := queue.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			messages = append(messages, received...)
+			return messages
+		}).WithTimeout(10 * time.Second).Should(HaveLen(n))
+		return messages
+	}
+
+	// storedMessages returns how many messages the stream holds.
+	storedMessages := func() uint64 {
+		s, err := js.Stream(context.Background(), stream)
+		Expect(err).NotTo(HaveOccurred())
+		info, err := s.Info(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		return info.State.Msgs
+	}
+
+	BeforeEach(func() {
+		srv, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: GinkgoT().TempDir(), NoLog: true, NoSigs: true})
+		Expect(err).NotTo(HaveOccurred())
+		go srv.Start()
+		Expect(srv.ReadyForConnections(5 * time.Second)).To(BeTrue())
+		DeferCleanup(srv.Shutdown)
+		url = srv.ClientURL()
+
+		var conn *nats.Conn
+		conn, js, err = messaging.ConnectNATS(context.Background(), url, stream, subject, time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(conn.Close)
+	})
+
+	It("should publish to a subject per event type with a Nats-Msg-Id from the order", func() {
+		envelope := newEnvelope()
+		Expect(newQueue("", messaging.MessageFormatEnvelope).Publish(context.Background(), envelope)).To(Succeed())
+
+		s, err := js.Stream(context.Background(), stream)
+		Expect(err).NotTo(HaveOccurred())
+		msg, err := s.GetMsg(context.Background(), 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(msg.Subject).To(Equal("orders.OrderPaid"))
+		Expect(msg.Header.Get(jetstream.MsgIDHeader)).To(Equal("600-" + envelope.ID))
+		Expect(msg.Header.Get("type")).To(Equal("OrderPaid"))
+	})
+
+	It("should store an event published twice once, and every event of an order", func() {
+		queue := newQueue("", messaging.MessageFormatEnvelope)
+		envelope := newEnvelope()
+		Expect(queue.Publish(context.Background(), envelope)).To(Succeed())
+		Expect(queue.Publish(context.Background(), envelope)).To(Succeed())
+
+		next := newEnvelope()
+		next.ID = "0197b6a2-5c1e-7d4a-9f3b-2a6c8e1d4f71"
+		Expect(queue.Publish(context.Background(), next)).To(Succeed())
+
+		Expect(storedMessages()).To(Equal(uint64(2)))
+	})
+
+	It("should receive CloudEvents and acknowledge them on a durable consumer", func() {
+		producer := newQueue("", messaging.MessageFormatCloudEventsBinary)
+		Expect(producer.Publish(context.Background(), newEnvelope())).To(Succeed())
+
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		messages := receive(consumer, 1)
+		Expect(messages[0].ID).To(Equal("600-" + newEnvelope().ID))
+		Expect(messages[0].Type).To(Equal("OrderPaid"))
+		var received repository.EventEnvelope
+		Expect(json.Unmarshal(messages[0].Body, &received)).To(Succeed())
+		Expect(received.CorrelationID).To(Equal("checkout-42"))
+		Expect(consumer.Ack(context.Background(), messages[0])).To(Succeed())
+
+		again, err := newQueue("order-worker", messaging.MessageFormatEnvelope).Receive(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again).To(BeEmpty())
+	})
+
+	It("should deliver a message again when it is not acknowledged in time", func() {
+		Expect(newQueue("", messaging.MessageFormatEnvelope).Publish(context.Background(), newEnvelope())).To(Succeed())
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		consumer.AckWait = 500 * time.Millisecond
+
+		first := receive(consumer, 1)
+		redelivered := receive(consumer, 1)
+
+		Expect(redelivered[0].ID).To(Equal(first[0].ID))
+		Expect(first[0].ReceiveCount).To(Equal(1))
+		Expect(redelivered[0].ReceiveCount).To(Equal(2))
+		Expect(consumer.Ack(context.Background(), redelivered[0])).To(Succeed())
+	})
+
+	It("should deliver a retried message again after the delay", func() {
+		Expect(newQueue("", messaging.MessageFormatEnvelope).Publish(context.Background(), newEnvelope())).To(Succeed())
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		first := receive(consumer, 1)
+
+		Expect(consumer.Retry(context.Background(), first[0], 300*time.Millisecond)).To(Succeed())
+
+		redelivered := receive(consumer, 1)
+		Expect(redelivered[0].ID).To(Equal(first[0].ID))
+		Expect(redelivered[0].ReceiveCount).To(Equal(2))
+		Expect(consumer.Ack(context.Background(), redelivered[0])).To(Succeed())
+	})
+
+	It("should stop delivering a message after MaxDeliver deliveries", func() {
+		Expect(newQueue("", messaging.MessageFormatEnvelope).Publish(context.Background(), newEnvelope())).To(Succeed())
+		consumer := newQueue("order-worker", messaging.MessageFormatEnvelope)
+		consumer.MaxDeliver = 2
+
+		Expect(consumer.Release(context.Background(), receive(consumer, 1)[0])).To(Succeed())
+		last := receive(consumer, 1)
+		Expect(last[0].ReceiveCount).To(Equal(2))
+		Expect(consumer.Release(context.Background(), last[0])).To(Succeed())
+
+		Consistently(func() []*repository.OrderMessage {
+			received, err := consumer.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			return received
+		}).WithTimeout(time.Second).Should(BeEmpty())
+	})
+
+	It("should reject acknowledging a message that is not in flight", func() {
+		err := newQueue("order-worker", messaging.MessageFormatEnvelope).Ack(context.Background(), &repository.OrderMessage{ReceiptHandle: "42"})
+
+		Expect(err).To(MatchError("message not in flight"))
+	})
+})
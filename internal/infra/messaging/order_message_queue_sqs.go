@@ -1,36 +1,263 @@
 package messaging
 
 import (
-	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 )
 
-// OrderMessageQueueSQS implements the OrderMessageQueue interface for AWS SQS.
+const (
+	// sqsMaxMessages is the largest batch SQS returns from a single receive.
+	sqsMaxMessages = 10
+	// sqsWaitTimeSeconds enables long polling so idle workers don't spin.
+	sqsWaitTimeSeconds = 20
+	// sqsAllAttributes asks SQS to return every message attribute.
+	sqsAllAttributes = "All"
+	// sqsMaxVisibilityTimeout is the longest SQS can hide a received message.
+	sqsMaxVisibilityTimeout = 12 * time.Hour
+
+	// SQSMaxBatchEntries is the most messages SQS takes in one SendMessageBatch request.
+	SQSMaxBatchEntries = 10
+	// sqsMaxBatchBytes is the most bytes of messages SQS takes in one SendMessageBatch request.
+	sqsMaxBatchBytes = 256 * 1024
+	// sqsBatchAttempts is how many times PublishBatch sends a message SQS failed
+	// to send through no fault of the request.
+	sqsBatchAttempts = 3
+	// sqsBatchRetryDelay is the wait before the first retry of failed batch entries, doubled for each retry.
+	sqsBatchRetryDelay = 100 * time.Millisecond
+)
+
+// sqsBatchEntry is a message waiting to be sent in a SendMessageBatch request.
+type sqsBatchEntry struct {
+	// index is the position of the message's envelope in the batch, and its
+	// entry ID in requests.
+	index      int
+	body       string
+	attributes map[string]types.MessageAttributeValue
+	size       int
+}
+
+// OrderMessageQueueSQS implements the BatchEventPublisher and
+// OrderMessageConsumer interfaces for AWS SQS. Events are published in Format;
+// messages in any format are received.
 type OrderMessageQueueSQS struct {
 	Client   *sqs.Client
 	QueueURL string
+	Format   MessageFormat
 }
 
 // NewOrderMessageQueueSQS creates a new SQS message queue.
-func NewOrderMessageQueueSQS(client *sqs.Client, queueURL string) *OrderMessageQueueSQS {
-	return &OrderMessageQueueSQS{Client: client, QueueURL: queueURL}
+func NewOrderMessageQueueSQS(client *sqs.Client, queueURL string, format MessageFormat) *OrderMessageQueueSQS {
+	return &OrderMessageQueueSQS{Client: client, QueueURL: queueURL, Format: format}
 }
 
-// Send sends an order message to the SQS queue.
-func (q *OrderMessageQueueSQS) Send(order *entity.Order) error {
-	body, err := json.Marshal(order)
+// Publish sends an event to the SQS queue, encoded in the queue's format.
+func (q *OrderMessageQueueSQS) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	message, err := q.Format.Encode(envelope)
 	if err != nil {
 		return err
 	}
 
-	_, err = q.Client.SendMessage(context.TODO(), &sqs.SendMessageInput{
-		QueueUrl:    &q.QueueURL,
-		MessageBody: aws.String(string(body)),
+	_, err = q.Client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:          &q.QueueURL,
+		MessageBody:       aws.String(string(message.Body)),
+		MessageAttributes: sqsMessageAttributes(message.Attributes),
 	})
 
 	return err
 }
+
+// PublishBatch publishes events with SendMessageBatch requests of up to 10
+// messages and 256KB. SQS reports whether each message was sent; the ones it
+// failed to send through no fault of the request are gathered from every
+// request and sent again together, in as few requests as they fit in, up to
+// three times in all. It returns one error for each envelope, in order, which
+// is nil when that envelope was published.
+func (q *OrderMessageQueueSQS) PublishBatch(ctx context.Context, envelopes []*repository.EventEnvelope) []error {
+	errs := make([]error, len(envelopes))
+	var entries []*sqsBatchEntry
+	for i, envelope := range envelopes {
+		message, err := q.Format.Encode(envelope)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		entry := &sqsBatchEntry{index: i, body: string(message.Body), attributes: sqsMessageAttributes(message.Attributes), size: len(message.Body)}
+		for name, value := range message.Attributes {
+			entry.size += len(name) + len("String") + len(value)
+		}
+		if entry.size > sqsMaxBatchBytes {
+			errs[i] = fmt.Errorf("the %s message is %d bytes, more than SQS takes", envelope.Type, entry.size)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	delay := sqsBatchRetryDelay
+	for attempt := 1; len(entries) > 0; attempt++ {
+		var failed []*sqsBatchEntry
+		for _, batch := range sqsBatches(entries) {
+			failed = append(failed, q.sendBatch(ctx, batch, errs)...)
+		}
+		if len(failed) == 0 || attempt == sqsBatchAttempts {
+			break
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errs
+		case <-timer.C:
+		}
+		delay *= 2
+		entries = failed
+	}
+	return errs
+}
+
+// sendBatch sends a SendMessageBatch request and records in errs whether each
+// message was sent. It returns the messages SQS failed to send through no
+// fault of the request.
+func (q *OrderMessageQueueSQS) sendBatch(ctx context.Context, batch []*sqsBatchEntry, errs []error) []*sqsBatchEntry {
+	requestEntries := make([]types.SendMessageBatchRequestEntry, 0, len(batch))
+	entries := make(map[string]*sqsBatchEntry, len(batch))
+	for _, entry := range batch {
+		id := strconv.Itoa(entry.index)
+		entries[id] = entry
+		requestEntries = append(requestEntries, types.SendMessageBatchRequestEntry{
+			Id:                aws.String(id),
+			MessageBody:       aws.String(entry.body),
+			MessageAttributes: entry.attributes,
+		})
+		errs[entry.index] = errors.New("SQS did not report whether the message was sent")
+	}
+
+	out, err := q.Client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{QueueUrl: &q.QueueURL, Entries: requestEntries})
+	if err != nil {
+		for _, entry := range batch {
+			errs[entry.index] = err
+		}
+		return nil
+	}
+
+	for _, sent := range out.Successful {
+		if entry, ok := entries[aws.ToString(sent.Id)]; ok {
+			errs[entry.index] = nil
+		}
+	}
+	var failed []*sqsBatchEntry
+	for _, result := range out.Failed {
+		entry, ok := entries[aws.ToString(result.Id)]
+		if !ok {
+			continue
+		}
+		errs[entry.index] = fmt.Errorf("SQS did not send the message: %s: %s", aws.ToString(result.Code), aws.ToString(result.Message))
+		if !result.SenderFault {
+			failed = append(failed, entry)
+		}
+	}
+	return failed
+}
+
+// Receive long-polls the SQS queue for order messages. A message that cannot
+// be decoded is logged and left on the queue, for its redrive policy to move
+// to the dead-letter queue.
+func (q *OrderMessageQueueSQS) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	out, err := q.Client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:              &q.QueueURL,
+		MaxNumberOfMessages:   sqsMaxMessages,
+		WaitTimeSeconds:       sqsWaitTimeSeconds,
+		MessageAttributeNames: []string{sqsAllAttributes},
+		MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+			types.MessageSystemAttributeNameApproximateReceiveCount,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]*repository.OrderMessage, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		messageType, body, err := DecodeMessage(&Message{Body: []byte(aws.ToString(m.Body)), Attributes: sqsStringAttributes(m.MessageAttributes)})
+		if err != nil {
+			log.Printf("Error decoding message %s: %v", aws.ToString(m.MessageId), err)
+			continue
+		}
+		receiveCount, _ := strconv.Atoi(m.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+		messages = append(messages, &repository.OrderMessage{
+			ID:            aws.ToString(m.MessageId),
+			Type:          messageType,
+			Body:          body,
+			ReceiptHandle: aws.ToString(m.ReceiptHandle),
+			ReceiveCount:  receiveCount,
+		})
+	}
+	return messages, nil
+}
+
+// Ack deletes a processed message from the SQS queue.
+func (q *OrderMessageQueueSQS) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	_, err := q.Client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      &q.QueueURL,
+		ReceiptHandle: aws.String(message.ReceiptHandle),
+	})
+	return err
+}
+
+// Retry changes the visibility timeout of a received message, so that SQS
+// delivers it again after delay, rounded up to a second and at most 12 hours.
+func (q *OrderMessageQueueSQS) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	delay = min(delay, sqsMaxVisibilityTimeout)
+	_, err := q.Client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          &q.QueueURL,
+		ReceiptHandle:     aws.String(message.ReceiptHandle),
+		VisibilityTimeout: int32((delay + time.Second - 1) / time.Second),
+	})
+	return err
+}
+
+// sqsBatches groups messages into SendMessageBatch requests, in order.
+func sqsBatches(entries []*sqsBatchEntry) [][]*sqsBatchEntry {
+	var batches [][]*sqsBatchEntry
+	var batch []*sqsBatchEntry
+	size := 0
+	for _, entry := range entries {
+		if len(batch) == SQSMaxBatchEntries || size+entry.size > sqsMaxBatchBytes {
+			batches = append(batches, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, entry)
+		size += entry.size
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// sqsMessageAttributes converts message attributes to SQS String attributes.
+func sqsMessageAttributes(attributes map[string]string) map[string]types.MessageAttributeValue {
+	values := make(map[string]types.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		values[name] = types.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(value)}
+	}
+	return values
+}
+
+// sqsStringAttributes converts SQS message attributes back to strings.
+func sqsStringAttributes(values map[string]types.MessageAttributeValue) map[string]string {
+	attributes := make(map[string]string, len(values))
+	for name, value := range values {
+		attributes[name] = aws.ToString(value.StringValue)
+	}
+	return attributes
+}
@@ -0,0 +1,192 @@
+package messaging_test
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/messaging"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// sqsBatchEntry is an entry of a SendMessageBatch request.
+type sqsBatchEntry struct {
+	ID          string `json:"Id"`
+	MessageBody string `json:"MessageBody"`
+}
+
+// sqsBatchFailure is a failed entry of a SendMessageBatch response.
+type sqsBatchFailure struct {
+	ID          string `json:"Id"`
+	Code        string `json:"Code"`
+	Message     string `json:"Message"`
+	SenderFault bool   `json:"SenderFault"`
+}
+
+// fakeSQS answers SendMessageBatch requests in the SQS JSON protocol. It
+// records the entry IDs of each request and fails the entries fail returns a
+// failure for.
+type fakeSQS struct {
+	mu       sync.Mutex
+	requests [][]string
+	fail     func(request int, entry sqsBatchEntry) *sqsBatchFailure
+}
+
+func (f *fakeSQS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer GinkgoRecover()
+	Expect(r.Header.Get("X-Amz-Target")).To(Equal("AmazonSQS.SendMessageBatch"))
+	var input struct{ Entries []sqsBatchEntry }
+	Expect(json.NewDecoder(r.Body).Decode(&input)).To(Succeed())
+
+	f.mu.Lock()
+	request := len(f.requests)
+	var ids []string
+	for _, entry := range input.Entries {
+		ids = append(ids, entry.ID)
+	}
+	f.requests = append(f.requests, ids)
+	f.mu.Unlock()
+
+	output := struct {
+		Successful []map[string]string
+		Failed     []*sqsBatchFailure
+	}{Successful: []map[string]string{}, Failed: []*sqsBatchFailure{}}
+	for _, entry := range input.Entries {
+		if f.fail != nil {
+			if failure := f.fail(request, entry); failure != nil {
+				failure.ID = entry.ID
+				output.Failed = append(output.Failed, failure)
+				continue
+			}
+		}
+		output.Successful = append(output.Successful, map[string]string{"Id": entry.ID, "MessageId": "message-" + entry.ID})
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	Expect(json.NewEncoder(w).Encode(output)).To(Succeed())
+}
+
+// envelopes returns n OrderPaid envelopes for orders 1 to n, each with data of about dataSize bytes.
+func envelopes(n, dataSize int) []*repository.EventEnvelope {
+	var envelopes []*repository.EventEnvelope
+	for i := 1; i <= n; i++ {
+		envelope := newEnvelope()
+		envelope.OrderID = i
+		if dataSize > 0 {
+			data, err := json.Marshal(strings.Repeat("x", dataSize))
+			Expect(err).NotTo(HaveOccurred())
+			envelope.Data = data
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes
+}
+
+var _ = Describe("OrderMessageQueueSQS", func() {
+	var (
+		fake  *fakeSQS
+		queue *messaging.OrderMessageQueueSQS
+	)
+
+	BeforeEach(func() {
+		fake = &fakeSQS{}
+		server := httptest.NewServer(fake)
+		DeferCleanup(server.Close)
+
+		client := sqs.New(sqs.Options{
+			Region:           "us-east-1",
+			BaseEndpoint:     aws.String(server.URL),
+			Credentials:      aws.AnonymousCredentials{},
+			RetryMaxAttempts: 1,
+		})
+		queue = messaging.NewOrderMessageQueueSQS(client, server.URL+"/123456789012/orders", messaging.MessageFormatEnvelope)
+	})
+
+	Describe("PublishBatch", func() {
+		It("should send at most 10 messages in a request", func() {
+			errs := queue.PublishBatch(context.Background(), envelopes(25, 0))
+
+			Expect(errs).To(HaveLen(25))
+			Expect(errs).To(HaveEach(BeNil()))
+			Expect(fake.requests).To(HaveLen(3))
+			Expect(fake.requests[0]).To(HaveLen(10))
+			Expect(fake.requests[1]).To(HaveLen(10))
+			Expect(fake.requests[2]).To(Equal([]string{"20", "21", "22", "23", "24"}))
+		})
+
+		It("should send at most 256KB of messages in a request", func() {
+			errs := queue.PublishBatch(context.Background(), envelopes(3, 100*1024))
+
+			Expect(errs).To(HaveEach(BeNil()))
+			Expect(fake.requests).To(Equal([][]string{{"0", "1"}, {"2"}}))
+		})
+
+		It("should fail a message larger than SQS takes without sending it", func() {
+			batch := append(envelopes(1, 0), envelopes(1, 300*1024)...)
+
+			errs := queue.PublishBatch(context.Background(), batch)
+
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).To(MatchError(ContainSubstring("more than SQS takes")))
+			Expect(fake.requests).To(Equal([][]string{{"0"}}))
+		})
+
+		It("should send again only the entries SQS failed through no fault of the request", func() {
+			fake.fail = func(request int, entry sqsBatchEntry) *sqsBatchFailure {
+				switch {
+				case entry.ID == "1" && request == 0:
+					return &sqsBatchFailure{Code: "InternalError", Message: "try again"}
+				case entry.ID == "2":
+					return &sqsBatchFailure{Code: "InvalidMessageContents", Message: "bad characters", SenderFault: true}
+				}
+				return nil
+			}
+
+			errs := queue.PublishBatch(context.Background(), envelopes(3, 0))
+
+			Expect(errs[0]).NotTo(HaveOccurred())
+			Expect(errs[1]).NotTo(HaveOccurred())
+			Expect(errs[2]).To(MatchError("SQS did not send the message: InvalidMessageContents: bad characters"))
+			Expect(fake.requests).To(Equal([][]string{{"0", "1", "2"}, {"1"}}))
+		})
+
+		It("should send the entries failed in different requests again together", func() {
+			fake.fail = func(request int, entry sqsBatchEntry) *sqsBatchFailure {
+				if request < 2 && (entry.ID == "3" || entry.ID == "7" || entry.ID == "11") {
+					return &sqsBatchFailure{Code: "ServiceUnavailable", Message: "try again"}
+				}
+				return nil
+			}
+
+			errs := queue.PublishBatch(context.Background(), envelopes(12, 0))
+
+			Expect(errs).To(HaveEach(BeNil()))
+			Expect(fake.requests).To(HaveLen(3))
+			Expect(fake.requests[0]).To(HaveLen(10))
+			Expect(fake.requests[1]).To(Equal([]string{"10", "11"}))
+			Expect(fake.requests[2]).To(Equal([]string{"3", "7", "11"}))
+		})
+
+		It("should give up on an entry after three attempts", func() {
+			fake.fail = func(request int, entry sqsBatchEntry) *sqsBatchFailure {
+				if entry.ID == "0" {
+					return &sqsBatchFailure{Code: "InternalError", Message: "attempt " + strconv.Itoa(request+1)}
+				}
+				return nil
+			}
+
+			errs := queue.PublishBatch(context.Background(), envelopes(2, 0))
+
+			Expect(errs[0]).To(MatchError("SQS did not send the message: InternalError: attempt 3"))
+			Expect(errs[1]).NotTo(HaveOccurred())
+			Expect(fake.requests).To(Equal([][]string{{"0", "1"}, {"0"}, {"0"}}))
+		})
+	})
+})
@@ -0,0 +1,152 @@
+// Package payment holds the adapters for payment providers.
+package payment
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Decline reasons the mock gateway reports on its own.
+const (
+	DeclineReasonUnknownAuthorization = "unknown_authorization"
+	DeclineReasonAlreadyCaptured      = "already_captured"
+	DeclineReasonAmountTooHigh        = "amount_exceeds_authorization"
+	DeclineReasonNotCaptured          = "not_captured"
+	DeclineReasonRefundTooHigh        = "amount_exceeds_capture"
+)
+
+// authorization is an amount the mock gateway has reserved.
+type authorization struct {
+	request  repository.PaymentRequest
+	captured int64
+	refunded int64
+	// refunds maps the ID of each refund to its reference.
+	refunds map[string]string
+}
+
+// PaymentGatewayMock is an in-process fake payment provider for development
+// and tests. It approves every payment except those whose payment method is a
+// key of Declines, which it declines with the mapped reason.
+type PaymentGatewayMock struct {
+	Declines map[string]string
+
+	mu             sync.Mutex
+	authorizations map[string]*authorization
+	// references maps the PaymentID of each authorization to its reference.
+	references    map[string]string
+	nextReference int
+}
+
+// NewPaymentGatewayMock creates a new PaymentGatewayMock that declines the payment
+// methods in declines.
+func NewPaymentGatewayMock(declines map[string]string) *PaymentGatewayMock {
+	return &PaymentGatewayMock{
+		Declines:       declines,
+		authorizations: make(map[string]*authorization),
+		references:     make(map[string]string),
+	}
+}
+
+// Authorize reserves the amount unless the payment method is declined.
+// Repeating a PaymentID returns the reference of the first authorization.
+func (g *PaymentGatewayMock) Authorize(ctx context.Context, request repository.PaymentRequest) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if reason, ok := g.Declines[request.PaymentMethod]; ok {
+		return "", &entity.PaymentDeclinedError{Reason: reason}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if reference, ok := g.references[request.PaymentID]; ok {
+		return reference, nil
+	}
+	g.nextReference++
+	reference := fmt.Sprintf("fake_%06d", g.nextReference)
+	g.authorizations[reference] = &authorization{request: request}
+	g.references[request.PaymentID] = reference
+	return reference, nil
+}
+
+// Capture collects an authorized amount once; capturing the same amount again
+// succeeds without collecting it twice. Capturing more than was authorized, a
+// different amount after the first capture, or an unknown authorization, is
+// declined.
+func (g *PaymentGatewayMock) Capture(ctx context.Context, reference string, amount entity.Money) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, ok := g.authorizations[reference]
+	switch {
+	case !ok:
+		return &entity.PaymentDeclinedError{Reason: DeclineReasonUnknownAuthorization}
+	case auth.captured > 0 && auth.captured == amount.Amount && amount.Currency == auth.request.Amount.Currency:
+		return nil
+	case auth.captured > 0:
+		return &entity.PaymentDeclinedError{Reason: DeclineReasonAlreadyCaptured}
+	case amount.Currency != auth.request.Amount.Currency || amount.Amount > auth.request.Amount.Amount:
+		return &entity.PaymentDeclinedError{Reason: DeclineReasonAmountTooHigh}
+	}
+	auth.captured = amount.Amount
+	return nil
+}
+
+// Refund returns part of a captured amount. Refunds that together exceed the
+// captured amount are declined. Repeating a refundID returns the reference of
+// the first refund without refunding again.
+func (g *PaymentGatewayMock) Refund(ctx context.Context, reference, refundID string, amount entity.Money) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, ok := g.authorizations[reference]
+	switch {
+	case !ok:
+		return "", &entity.PaymentDeclinedError{Reason: DeclineReasonUnknownAuthorization}
+	case auth.captured == 0:
+		return "", &entity.PaymentDeclinedError{Reason: DeclineReasonNotCaptured}
+	}
+	if refundReference, ok := auth.refunds[refundID]; ok {
+		return refundReference, nil
+	}
+	if amount.Currency != auth.request.Amount.Currency || amount.Amount > auth.captured-auth.refunded {
+		return "", &entity.PaymentDeclinedError{Reason: DeclineReasonRefundTooHigh}
+	}
+
+	g.nextReference++
+	refundReference := fmt.Sprintf("fake_refund_%06d", g.nextReference)
+	if auth.refunds == nil {
+		auth.refunds = make(map[string]string)
+	}
+	auth.refunds[refundID] = refundReference
+	auth.refunded += amount.Amount
+	return refundReference, nil
+}
+
+// Captured reports whether the payment with reference has been captured.
+func (g *PaymentGatewayMock) Captured(reference string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	auth, ok := g.authorizations[reference]
+	return ok && auth.captured > 0
+}
+
+// Refunded returns the amount refunded from the payment with reference, in
+// the payment's minor unit.
+func (g *PaymentGatewayMock) Refunded(reference string) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if auth, ok := g.authorizations[reference]; ok {
+		return auth.refunded
+	}
+	return 0
+}
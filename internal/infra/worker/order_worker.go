@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"GoCleanArch/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// OrderWorker repeatedly drains the order queue into the order repository.
+type OrderWorker struct {
+	ConsumeOrdersUseCase *usecase.ConsumeOrdersUseCase
+	PollInterval         time.Duration
+}
+
+// NewOrderWorker creates a new OrderWorker.
+func NewOrderWorker(consumeOrdersUseCase *usecase.ConsumeOrdersUseCase, pollInterval time.Duration) *OrderWorker {
+	return &OrderWorker{ConsumeOrdersUseCase: consumeOrdersUseCase, PollInterval: pollInterval}
+}
+
+// Run consumes order messages until the context is cancelled. It waits for
+// PollInterval whenever the queue is empty or receiving fails.
+func (w *OrderWorker) Run(ctx context.Context) {
+	log.Printf("Order worker started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Order worker stopped")
+			return
+		default:
+		}
+
+		output, err := w.ConsumeOrdersUseCase.Execute(ctx)
+		if err != nil {
+			log.Printf("Error receiving order messages: %v", err)
+		} else if output.Received > 0 {
+			log.Printf("Processed %d of %d order messages", output.Processed, output.Received)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Order worker stopped")
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package worker
+
+import (
+	"GoCleanArch/internal/usecase"
+	"context"
+	"log"
+	"time"
+)
+
+// OutboxRelay repeatedly publishes pending outbox messages to the message queue.
+type OutboxRelay struct {
+	RelayOutboxUseCase *usecase.RelayOutboxUseCase
+	PollInterval       time.Duration
+}
+
+// NewOutboxRelay creates a new OutboxRelay.
+func NewOutboxRelay(relayOutboxUseCase *usecase.RelayOutboxUseCase, pollInterval time.Duration) *OutboxRelay {
+	return &OutboxRelay{RelayOutboxUseCase: relayOutboxUseCase, PollInterval: pollInterval}
+}
+
+// Run relays outbox messages until the context is cancelled. It waits for
+// PollInterval whenever the outbox has nothing due or reading it fails.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	log.Printf("Outbox relay started")
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Outbox relay stopped")
+			return
+		default:
+		}
+
+		output, err := r.RelayOutboxUseCase.Execute(ctx)
+		if err != nil {
+			log.Printf("Error relaying outbox messages: %v", err)
+		} else if output.Published > 0 {
+			log.Printf("Published %d outbox messages (%d failed)", output.Published, output.Failed)
+			continue
+		} else if output.Failed > 0 {
+			log.Printf("Failed to publish %d outbox messages", output.Failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("Outbox relay stopped")
+			return
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
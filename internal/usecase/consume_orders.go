@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ConsumeOrdersOutputDTO is the data transfer object for the result of consuming a batch of order messages.
+type ConsumeOrdersOutputDTO struct {
+	Received  int
+	Processed int
+}
+
+// ConsumeOrdersUseCase is the use case for persisting orders received from the message queue.
+// When the consumer is an OrderMessageRetrier, a message that fails is delivered
+// again after RetryBaseDelay, doubling with every delivery up to RetryMaxDelay.
+// Without a base delay, or with another consumer, it is delivered again when the
+// queue's own timeout passes, or released straight away to an
+// OrderMessageReleaser, which has no such timeout.
+type ConsumeOrdersUseCase struct {
+	MessageConsumer repository.OrderMessageConsumer
+	OrderRepository repository.OrderRepository
+	RetryBaseDelay  time.Duration
+	RetryMaxDelay   time.Duration
+}
+
+// NewConsumeOrdersUseCase creates a new ConsumeOrdersUseCase.
+func NewConsumeOrdersUseCase(messageConsumer repository.OrderMessageConsumer, orderRepository repository.OrderRepository, retryBaseDelay, retryMaxDelay time.Duration) *ConsumeOrdersUseCase {
+	return &ConsumeOrdersUseCase{MessageConsumer: messageConsumer, OrderRepository: orderRepository, RetryBaseDelay: retryBaseDelay, RetryMaxDelay: retryMaxDelay}
+}
+
+// Execute receives one batch of messages and handles each one. A message is
+// only acknowledged once it has been processed, so messages that fail to
+// decode or save stay on the queue to be delivered again.
+func (uc *ConsumeOrdersUseCase) Execute(ctx context.Context) (*ConsumeOrdersOutputDTO, error) {
+	messages, err := uc.MessageConsumer.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ConsumeOrdersOutputDTO{Received: len(messages)}
+	for _, message := range messages {
+		if err := uc.Handle(ctx, message); err != nil {
+			continue
+		}
+
+		if err := uc.MessageConsumer.Ack(ctx, message); err != nil {
+			log.Printf("Error acknowledging message %s: %v", message.ID, err)
+			continue
+		}
+		output.Processed++
+	}
+
+	return output, nil
+}
+
+// Handle processes a message without acknowledging it. When processing fails
+// it logs the error, asks the consumer to deliver the message again after the
+// retry backoff and returns the error.
+func (uc *ConsumeOrdersUseCase) Handle(ctx context.Context, message *repository.OrderMessage) error {
+	err := uc.Process(ctx, message)
+	if err == nil {
+		return nil
+	}
+	log.Printf("Error processing message %s (delivery %d): %v", message.ID, message.ReceiveCount, err)
+
+	if retrier, ok := uc.MessageConsumer.(repository.OrderMessageRetrier); ok && uc.RetryBaseDelay > 0 {
+		if err := retrier.Retry(ctx, message, uc.retryBackoff(message.ReceiveCount)); err != nil {
+			log.Printf("Error scheduling the retry of message %s: %v", message.ID, err)
+		}
+	} else if releaser, ok := uc.MessageConsumer.(repository.OrderMessageReleaser); ok {
+		if err := releaser.Release(ctx, message); err != nil {
+			log.Printf("Error releasing message %s: %v", message.ID, err)
+		}
+	}
+	return err
+}
+
+// Process saves the order of an OrderCreated message. An order that already
+// exists means the message was delivered before, so it is not an error. Other
+// events carry nothing to save.
+func (uc *ConsumeOrdersUseCase) Process(ctx context.Context, message *repository.OrderMessage) error {
+	order, err := createdOrder(message)
+	if err != nil {
+		return fmt.Errorf("decoding order message: %w", err)
+	}
+	if order == nil {
+		return nil
+	}
+	if err := uc.OrderRepository.Save(ctx, order); err != nil && !errors.Is(err, domain.ErrConflict) {
+		return fmt.Errorf("saving order %d: %w", order.OrderID, err)
+	}
+	return nil
+}
+
+// createdOrder returns the order created by an OrderCreated message, or nil
+// for other events. Messages sent before events were introduced carry the
+// order itself.
+func createdOrder(message *repository.OrderMessage) (*entity.Order, error) {
+	switch message.Type {
+	case "", repository.OrderMessageTypeOrder:
+		var order entity.Order
+		if err := json.Unmarshal(message.Body, &order); err != nil {
+			return nil, err
+		}
+		return &order, nil
+
+	case entity.EventTypeOrderCreated:
+		var envelope repository.EventEnvelope
+		if err := json.Unmarshal(message.Body, &envelope); err != nil {
+			return nil, err
+		}
+		var event entity.OrderCreated
+		if err := json.Unmarshal(envelope.Data, &event); err != nil {
+			return nil, err
+		}
+		return &event.Order, nil
+
+	default:
+		return nil, nil
+	}
+}
+
+// retryBackoff returns how long to wait before delivering a message again
+// after its given delivery failed.
+func (uc *ConsumeOrdersUseCase) retryBackoff(receiveCount int) time.Duration {
+	backoff := uc.RetryBaseDelay
+	for i := 1; i < receiveCount && (uc.RetryMaxDelay <= 0 || backoff < uc.RetryMaxDelay); i++ {
+		backoff *= 2
+	}
+	if uc.RetryMaxDelay > 0 && backoff > uc.RetryMaxDelay {
+		backoff = uc.RetryMaxDelay
+	}
+	return backoff
+}
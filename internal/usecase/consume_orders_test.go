@@ -0,0 +1,227 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/messaging"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// publishOrderCreated publishes the OrderCreated event of a new order.
+func publishOrderCreated(publisher repository.EventPublisher, order *entity.Order) {
+	event := entity.OrderCreated{OrderEvent: entity.OrderEvent{OrderID: order.OrderID, OccurredAt: time.Now()}, Order: *order}
+	envelope, err := repository.NewEventEnvelope(context.Background(), event)
+	Expect(err).NotTo(HaveOccurred())
+	Expect(publisher.Publish(context.Background(), envelope)).To(Succeed())
+}
+
+// publishMalformedOrderCreated publishes an OrderCreated event whose data is not an order.
+func publishMalformedOrderCreated(publisher repository.EventPublisher, orderID int) {
+	envelope := &repository.EventEnvelope{
+		ID:            "0197b6a2-5c1e-7d4a-9f3b-2a6c8e1d4f71",
+		Type:          entity.EventTypeOrderCreated,
+		SchemaVersion: 1,
+		OccurredAt:    time.Now(),
+		OrderID:       orderID,
+		Data:          json.RawMessage(`"not an order"`),
+	}
+	Expect(publisher.Publish(context.Background(), envelope)).To(Succeed())
+}
+
+// retryingConsumer delivers the same failing message with a growing receive
+// count and records the delays it is asked to retry it after.
+type retryingConsumer struct {
+	message *repository.OrderMessage
+	delays  []time.Duration
+}
+
+func (c *retryingConsumer) Receive(ctx context.Context) ([]*repository.OrderMessage, error) {
+	c.message.ReceiveCount++
+	return []*repository.OrderMessage{c.message}, nil
+}
+
+func (c *retryingConsumer) Ack(ctx context.Context, message *repository.OrderMessage) error {
+	return errors.New("the message should not be acknowledged")
+}
+
+func (c *retryingConsumer) Retry(ctx context.Context, message *repository.OrderMessage, delay time.Duration) error {
+	c.delays = append(c.delays, delay)
+	return nil
+}
+
+// releasingConsumer is a retryingConsumer that also records the messages it
+// is asked to release.
+type releasingConsumer struct {
+	retryingConsumer
+	released []string
+}
+
+func (c *releasingConsumer) Release(ctx context.Context, message *repository.OrderMessage) error {
+	c.released = append(c.released, message.ID)
+	return nil
+}
+
+var _ = Describe("ConsumeOrdersUseCase", func() {
+	var (
+		consumeOrdersUseCase *usecase.ConsumeOrdersUseCase
+		messageQueueMock     *messaging.OrderMessageQueueMock
+		orderRepoMock        *database.OrderRepositoryMock
+	)
+
+	BeforeEach(func() {
+		messageQueueMock = messaging.NewOrderMessageQueueMock()
+		orderRepoMock = database.NewOrderRepositoryMock()
+		consumeOrdersUseCase = usecase.NewConsumeOrdersUseCase(messageQueueMock, orderRepoMock, 0, 0)
+	})
+
+	Context("when orders were created", func() {
+		It("should save every order and acknowledge the messages", func() {
+			publishOrderCreated(messageQueueMock, &entity.Order{ID: "order-1", OrderID: 1, Data: "first", Status: entity.OrderStatusPending})
+			publishOrderCreated(messageQueueMock, &entity.Order{ID: "order-2", OrderID: 2, Data: "second", Status: entity.OrderStatusPending})
+
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Received).To(Equal(2))
+			Expect(output.Processed).To(Equal(2))
+
+			saved, err := orderRepoMock.GetByOrderID(context.Background(), 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saved.Data).To(Equal("second"))
+
+			output, err = consumeOrdersUseCase.Execute(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Received).To(Equal(0))
+		})
+	})
+
+	Context("when an order was already saved", func() {
+		It("should acknowledge the redelivered message", func() {
+			order := &entity.Order{ID: "order-3", OrderID: 3, Data: "third", Status: entity.OrderStatusPending}
+			Expect(orderRepoMock.Save(context.Background(), order)).To(Succeed())
+			publishOrderCreated(messageQueueMock, order)
+
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Processed).To(Equal(1))
+		})
+	})
+
+	DescribeTable("when the queue carries CloudEvents",
+		func(format messaging.MessageFormat) {
+			messageQueueMock.Format = format
+			publishOrderCreated(messageQueueMock, &entity.Order{ID: "order-5", OrderID: 5, Data: "fifth", Status: entity.OrderStatusPending})
+
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Processed).To(Equal(1))
+			saved, err := orderRepoMock.GetByOrderID(context.Background(), 5)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saved.Data).To(Equal("fifth"))
+		},
+		Entry("in the structured format", messaging.MessageFormatCloudEventsStructured),
+		Entry("in the binary format", messaging.MessageFormatCloudEventsBinary),
+	)
+
+	Context("when an event other than OrderCreated is received", func() {
+		It("should acknowledge the message without saving anything", func() {
+			envelope, err := repository.NewEventEnvelope(context.Background(), entity.OrderRefunded{OrderEvent: entity.OrderEvent{OrderID: 4}, RefundID: "refund-1", Status: entity.OrderStatusRefunded})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messageQueueMock.Publish(context.Background(), envelope)).To(Succeed())
+
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Processed).To(Equal(1))
+			_, err = orderRepoMock.GetByOrderID(context.Background(), 4)
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Context("when a message cannot be processed", func() {
+		It("should retry it with exponential backoff up to the maximum delay", func() {
+			consumer := &retryingConsumer{message: &repository.OrderMessage{ID: "m-1", Type: entity.EventTypeOrderCreated, Body: []byte(`{`)}}
+			consumeOrdersUseCase = usecase.NewConsumeOrdersUseCase(consumer, orderRepoMock, time.Second, 5*time.Second)
+
+			for range 5 {
+				output, err := consumeOrdersUseCase.Execute(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output.Processed).To(Equal(0))
+			}
+
+			Expect(consumer.delays).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}))
+		})
+
+		It("should leave it to the queue's timeout without a retry delay", func() {
+			consumer := &retryingConsumer{message: &repository.OrderMessage{ID: "m-1", Type: entity.EventTypeOrderCreated, Body: []byte(`{`)}}
+			consumeOrdersUseCase = usecase.NewConsumeOrdersUseCase(consumer, orderRepoMock, 0, 0)
+
+			_, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumer.delays).To(BeEmpty())
+		})
+
+		It("should release it straight away without a retry delay when the consumer holds failed messages", func() {
+			consumer := &releasingConsumer{retryingConsumer: retryingConsumer{message: &repository.OrderMessage{ID: "m-1", Type: entity.EventTypeOrderCreated, Body: []byte(`{`)}}}
+			consumeOrdersUseCase = usecase.NewConsumeOrdersUseCase(consumer, orderRepoMock, 0, 0)
+
+			_, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(consumer.released).To(Equal([]string{"m-1"}))
+			Expect(consumer.delays).To(BeEmpty())
+		})
+
+		It("should move it to the dead-letter queue after the maximum number of deliveries", func() {
+			messageQueueMock.VisibilityTimeout = time.Hour
+			messageQueueMock.MaxReceives = 2
+			consumeOrdersUseCase = usecase.NewConsumeOrdersUseCase(messageQueueMock, orderRepoMock, 10*time.Millisecond, time.Second)
+			publishMalformedOrderCreated(messageQueueMock, 9)
+
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Received).To(Equal(1))
+			Expect(output.Processed).To(Equal(0))
+
+			// The retry delay, not the hour-long visibility timeout, brings the message back.
+			Eventually(func() int {
+				output, err := consumeOrdersUseCase.Execute(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				return output.Received
+			}).Should(Equal(1))
+
+			// The next receive after the second failure moves it instead of delivering it.
+			Eventually(func() []*messaging.DeadLetter {
+				output, err := consumeOrdersUseCase.Execute(context.Background())
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output.Received).To(Equal(0))
+				return messageQueueMock.DeadLetters()
+			}).Should(HaveLen(1))
+			deadLetter := messageQueueMock.DeadLetters()[0]
+			Expect(deadLetter.Type).To(Equal(entity.EventTypeOrderCreated))
+			Expect(deadLetter.ReceiveCount).To(Equal(2))
+		})
+	})
+
+	Context("when the queue is empty", func() {
+		It("should do nothing", func() {
+			output, err := consumeOrdersUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Received).To(Equal(0))
+			Expect(output.Processed).To(Equal(0))
+		})
+	})
+})
@@ -3,53 +3,117 @@ package usecase
 import (
 	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/domain/repository"
-	"time"
+	"context"
 )
 
+// OrderItemDTO is the data transfer object for one line of an order.
+type OrderItemDTO struct {
+	SKU         string       `json:"sku"`
+	Description string       `json:"description"`
+	Quantity    int          `json:"quantity"`
+	UnitPrice   entity.Money `json:"unit_price"`
+}
+
 // CreateOrderInputDTO is the data transfer object for creating an order.
+// TaxRate is in basis points.
 type CreateOrderInputDTO struct {
-	Data    string `json:"Data"`
-	OrderID int    `json:"OrderId"`
-	Status  string `json:"Status"`
+	Data    string         `json:"Data"`
+	OrderID int            `json:"OrderId"`
+	Status  string         `json:"Status"`
+	Items   []OrderItemDTO `json:"items"`
+	TaxRate int            `json:"tax_rate_bps"`
 }
 
 // CreateOrderOutputDTO is the data transfer object for the result of creating an order.
 type CreateOrderOutputDTO struct {
-	Data    string `json:"Data"`
-	OrderID int    `json:"OrderId"`
-	Status  string `json:"Status"`
+	ID       string             `json:"id"`
+	Data     string             `json:"Data"`
+	OrderID  int                `json:"OrderId"`
+	Status   entity.OrderStatus `json:"Status"`
+	Items    []OrderItemDTO     `json:"items,omitempty"`
+	TaxRate  int                `json:"tax_rate_bps,omitempty"`
+	Subtotal entity.Money       `json:"subtotal,omitzero"`
+	Tax      entity.Money       `json:"tax,omitzero"`
+	Total    entity.Money       `json:"total,omitzero"`
+}
+
+// newOrderItems maps item DTOs to order items.
+func newOrderItems(items []OrderItemDTO) []entity.OrderItem {
+	if len(items) == 0 {
+		return nil
+	}
+	orderItems := make([]entity.OrderItem, 0, len(items))
+	for _, item := range items {
+		orderItems = append(orderItems, entity.OrderItem(item))
+	}
+	return orderItems
+}
+
+// newOrderItemDTOs maps order items to item DTOs.
+func newOrderItemDTOs(items []entity.OrderItem) []OrderItemDTO {
+	if len(items) == 0 {
+		return nil
+	}
+	dtos := make([]OrderItemDTO, 0, len(items))
+	for _, item := range items {
+		dtos = append(dtos, OrderItemDTO(item))
+	}
+	return dtos
 }
 
-// CreateOrderUseCase is the use case for creating an order.
+// CreateOrderUseCase is the use case for creating an order. The order and its
+// OrderCreated event are stored together in the outbox; RelayOutboxUseCase
+// publishes the event afterwards.
 type CreateOrderUseCase struct {
-	MessageQueue repository.OrderMessageQueue
+	Outbox repository.OrderOutbox
 }
 
 // NewCreateOrderUseCase creates a new CreateOrderUseCase.
-func NewCreateOrderUseCase(messageQueue repository.OrderMessageQueue) *CreateOrderUseCase {
-	return &CreateOrderUseCase{MessageQueue: messageQueue}
+func NewCreateOrderUseCase(outbox repository.OrderOutbox) *CreateOrderUseCase {
+	return &CreateOrderUseCase{Outbox: outbox}
 }
 
-// Execute executes the use case.
-func (uc *CreateOrderUseCase) Execute(input CreateOrderInputDTO) (*CreateOrderOutputDTO, error) {
-	order := entity.Order{
-		Data:      input.Data,
-		OrderID:   input.OrderID,
-		Status:    input.Status,
-		Paid:      false, // Default to false on creation
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// Execute executes the use case. The order gets a new internal ID and totals
+// calculated from its items, and orders created without a status start out Pending.
+func (uc *CreateOrderUseCase) Execute(ctx context.Context, input CreateOrderInputDTO) (*CreateOrderOutputDTO, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	status := entity.OrderStatusPending
+	if input.Status != "" {
+		var err error
+		status, err = entity.ParseOrderStatus(input.Status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	order, err := entity.NewOrder(input.OrderID, input.Data, status, newOrderItems(input.Items), input.TaxRate)
+	if err != nil {
+		return nil, err
 	}
 
-	err := uc.MessageQueue.Send(&order)
+	// NewOrder raises OrderCreated and nothing else.
+	messages, err := newOutboxMessages(ctx, order.PullEvents())
 	if err != nil {
 		return nil, err
 	}
 
+	if err := uc.Outbox.SaveWithMessage(ctx, order, messages[0]); err != nil {
+		return nil, err
+	}
+
 	output := &CreateOrderOutputDTO{
-		Data:    order.Data,
-		OrderID: order.OrderID,
-		Status:  order.Status,
+		ID:       order.ID,
+		Data:     order.Data,
+		OrderID:  order.OrderID,
+		Status:   order.Status,
+		Items:    newOrderItemDTOs(order.Items),
+		TaxRate:  order.TaxRate,
+		Subtotal: order.Subtotal,
+		Tax:      order.Tax,
+		Total:    order.Total,
 	}
 
 	return output, nil
@@ -1,43 +1,229 @@
 package usecase_test
 
 import (
+	"GoCleanArch/internal/correlation"
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
 	"GoCleanArch/internal/infra/messaging"
 	"GoCleanArch/internal/usecase"
+	"GoCleanArch/internal/validation"
+	"context"
+	"encoding/json"
+	"errors"
 
+	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
-
 var _ = Describe("CreateOrderUseCase", func() {
 	var (
 		createOrderUseCase *usecase.CreateOrderUseCase
-		messageQueueMock   *messaging.OrderMessageQueueMock
+		orderRepoMock      *database.OrderRepositoryMock
 	)
 
 	BeforeEach(func() {
-		messageQueueMock = messaging.NewOrderMessageQueueMock()
-		createOrderUseCase = usecase.NewCreateOrderUseCase(messageQueueMock)
+		orderRepoMock = database.NewOrderRepositoryMock()
+		createOrderUseCase = usecase.NewCreateOrderUseCase(orderRepoMock)
 	})
 
 	Context("when creating a new order", func() {
-		It("should send the order to the message queue and return the correct DTO", func() {
+		It("should save the order with an outbox message and return the correct DTO", func() {
 			input := usecase.CreateOrderInputDTO{
 				Data:    "21/06/2025",
 				OrderID: 78910,
-				Status:  "Processing",
+				Status:  "Pending",
 			}
 
-			output, err := createOrderUseCase.Execute(input)
+			output, err := createOrderUseCase.Execute(context.Background(), input)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).NotTo(BeNil())
 			Expect(output.Data).To(Equal(input.Data))
 			Expect(output.OrderID).To(Equal(input.OrderID))
-			Expect(output.Status).To(Equal(input.Status))
+			Expect(output.Status).To(Equal(entity.OrderStatusPending))
+
+			id, err := uuid.Parse(output.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(id.Version()).To(Equal(uuid.Version(7)))
+
+			saved, err := orderRepoMock.GetByOrderID(context.Background(), 78910)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saved.Data).To(Equal(input.Data))
+
+			byID, err := orderRepoMock.GetByID(context.Background(), output.ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(byID.OrderID).To(Equal(78910))
+
+			pending, err := orderRepoMock.FetchPending(context.Background(), 1, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(HaveLen(1))
+			Expect(pending[0].OrderID).To(Equal(78910))
+		})
+
+		It("should default the status to Pending", func() {
+			output, err := createOrderUseCase.Execute(context.Background(), usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78911})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Status).To(Equal(entity.OrderStatusPending))
+		})
+
+		It("should stamp the OrderCreated envelope with the request's correlation ID", func() {
+			ctx := correlation.WithID(context.Background(), "checkout-42")
+
+			_, err := createOrderUseCase.Execute(ctx, usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78912})
+			Expect(err).NotTo(HaveOccurred())
+
+			pending, err := orderRepoMock.FetchPending(context.Background(), 1, 10)
+			Expect(err).NotTo(HaveOccurred())
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(pending[0].Payload, &envelope)).To(Succeed())
+			Expect(envelope.CorrelationID).To(Equal("checkout-42"))
+			Expect(envelope.OrderID).To(Equal(78912))
+			Expect(envelope.SchemaVersion).To(Equal(1))
+		})
+
+		It("should send OrderCreated to the queue once the outbox is relayed", func() {
+			messageQueueMock := messaging.NewOrderMessageQueueMock()
+			consumerRepoMock := database.NewOrderRepositoryMock()
+			unsubscribe := messageQueueMock.Subscribe(usecase.NewConsumeOrdersUseCase(messageQueueMock, consumerRepoMock, 0, 0).Handle)
+			DeferCleanup(unsubscribe)
+
+			_, err := createOrderUseCase.Execute(context.Background(), usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78913})
+			Expect(err).NotTo(HaveOccurred())
+			_, err = usecase.NewRelayOutboxUseCase(orderRepoMock, messageQueueMock, 10, 3).Execute(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+
+			messages, err := messageQueueMock.WaitFor(1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(1))
+			Expect(messages[0].Type).To(Equal(entity.EventTypeOrderCreated))
+			Eventually(func() error {
+				_, err := consumerRepoMock.GetByOrderID(context.Background(), 78913)
+				return err
+			}).Should(Succeed())
+		})
+	})
+
+	Context("when the order has items", func() {
+		It("should save the items and return the totals", func() {
+			input := usecase.CreateOrderInputDTO{
+				OrderID: 78920,
+				Items: []usecase.OrderItemDTO{
+					{SKU: "BOOK", Description: "Clean Architecture", Quantity: 2, UnitPrice: entity.Money{Amount: 2500, Currency: "EUR"}},
+					{SKU: "PEN", Quantity: 1, UnitPrice: entity.Money{Amount: 300, Currency: "EUR"}},
+				},
+				TaxRate: 2000,
+			}
+
+			output, err := createOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Items).To(Equal(input.Items))
+			Expect(output.Subtotal).To(Equal(entity.Money{Amount: 5300, Currency: "EUR"}))
+			Expect(output.Tax).To(Equal(entity.Money{Amount: 1060, Currency: "EUR"}))
+			Expect(output.Total).To(Equal(entity.Money{Amount: 6360, Currency: "EUR"}))
 
-			// Here you could also assert that the message was 'sent' by checking logs
-			// or by modifying the mock to store the sent message.
+			saved, err := orderRepoMock.GetByOrderID(context.Background(), 78920)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(saved.Items).To(HaveLen(2))
+			Expect(saved.Total).To(Equal(output.Total))
+
+			pending, err := orderRepoMock.FetchPending(context.Background(), 1, 10)
+			Expect(err).NotTo(HaveOccurred())
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(pending[0].Payload, &envelope)).To(Succeed())
+			Expect(envelope.Type).To(Equal(entity.EventTypeOrderCreated))
+			var event entity.OrderCreated
+			Expect(json.Unmarshal(envelope.Data, &event)).To(Succeed())
+			Expect(event.Order.Items).To(Equal(saved.Items))
+			Expect(event.Order.Total).To(Equal(output.Total))
+		})
+
+		It("should report every invalid item field", func() {
+			input := usecase.CreateOrderInputDTO{
+				OrderID: 78921,
+				Items: []usecase.OrderItemDTO{
+					{SKU: "BOOK", Quantity: 1, UnitPrice: entity.Money{Amount: 2500, Currency: "EUR"}},
+					{SKU: "", Quantity: 0, UnitPrice: entity.Money{Amount: -1, Currency: "USD"}},
+					{SKU: "PEN", Quantity: 1, UnitPrice: entity.Money{Amount: 300, Currency: "euro"}},
+				},
+				TaxRate: -5,
+			}
+
+			_, err := createOrderUseCase.Execute(context.Background(), input)
+
+			var fieldErrors validation.Errors
+			Expect(errors.As(err, &fieldErrors)).To(BeTrue())
+			fields := make([]string, 0, len(fieldErrors))
+			for _, fieldError := range fieldErrors {
+				fields = append(fields, fieldError.Field)
+			}
+			Expect(fields).To(ConsistOf(
+				"items[1].sku",
+				"items[1].quantity",
+				"items[1].unit_price.amount",
+				"items[1].unit_price.currency",
+				"items[2].unit_price.currency",
+				"tax_rate_bps",
+			))
+		})
+	})
+
+	Context("when the order already exists", func() {
+		It("should return a conflict without adding another outbox message", func() {
+			input := usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78913}
+			_, err := createOrderUseCase.Execute(context.Background(), input)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = createOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+			pending, _ := orderRepoMock.FetchPending(context.Background(), 1, 10)
+			Expect(pending).To(HaveLen(1))
+		})
+	})
+
+	Context("when the status is unknown", func() {
+		It("should reject the order", func() {
+			input := usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78912, Status: "Processing"}
+
+			output, err := createOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+			Expect(output).To(BeNil())
+		})
+	})
+
+	Context("when the status is not one an order starts in", func() {
+		It("should reject the order", func() {
+			for _, status := range []string{"Paid", "PartiallyRefunded", "Refunded", "Shipped"} {
+				input := usecase.CreateOrderInputDTO{Data: "21/06/2025", OrderID: 78913, Status: status}
+
+				output, err := createOrderUseCase.Execute(context.Background(), input)
+
+				Expect(err).To(MatchError(domain.ErrValidation), status)
+				Expect(output).To(BeNil())
+			}
+			Expect(orderRepoMock.GetByOrderID(context.Background(), 78913)).Error().To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Context("when several fields are invalid", func() {
+		It("should report every field error", func() {
+			input := usecase.CreateOrderInputDTO{Data: " ", OrderID: 0, Status: "Processing"}
+
+			_, err := createOrderUseCase.Execute(context.Background(), input)
+
+			var fieldErrors validation.Errors
+			Expect(errors.As(err, &fieldErrors)).To(BeTrue())
+			fields := make([]string, 0, len(fieldErrors))
+			for _, fieldError := range fieldErrors {
+				fields = append(fields, fieldError.Field)
+			}
+			Expect(fields).To(ConsistOf("OrderId", "Data", "Status"))
 		})
 	})
 })
@@ -0,0 +1,26 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"context"
+)
+
+// DeleteOrderInputDTO is the data transfer object for deleting an order.
+type DeleteOrderInputDTO struct {
+	OrderID int `json:"orderId"`
+}
+
+// DeleteOrderUseCase is the use case for deleting an order.
+type DeleteOrderUseCase struct {
+	OrderRepository repository.OrderRepository
+}
+
+// NewDeleteOrderUseCase creates a new DeleteOrderUseCase.
+func NewDeleteOrderUseCase(orderRepository repository.OrderRepository) *DeleteOrderUseCase {
+	return &DeleteOrderUseCase{OrderRepository: orderRepository}
+}
+
+// Execute executes the use case.
+func (uc *DeleteOrderUseCase) Execute(ctx context.Context, input DeleteOrderInputDTO) error {
+	return uc.OrderRepository.Delete(ctx, input.OrderID)
+}
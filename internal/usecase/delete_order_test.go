@@ -0,0 +1,40 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/usecase"
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DeleteOrderUseCase", func() {
+	var (
+		deleteOrderUseCase *usecase.DeleteOrderUseCase
+		orderRepoMock      *database.OrderRepositoryMock
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		deleteOrderUseCase = usecase.NewDeleteOrderUseCase(orderRepoMock)
+
+		orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-4001", Data: "06/07/2025", OrderID: 4001, Status: entity.OrderStatusPending})
+	})
+
+	It("should remove an existing order", func() {
+		err := deleteOrderUseCase.Execute(context.Background(), usecase.DeleteOrderInputDTO{OrderID: 4001})
+
+		Expect(err).NotTo(HaveOccurred())
+		_, err = orderRepoMock.GetByOrderID(context.Background(), 4001)
+		Expect(err).To(MatchError(domain.ErrOrderNotFound))
+	})
+
+	It("should return not found for a missing order", func() {
+		err := deleteOrderUseCase.Execute(context.Background(), usecase.DeleteOrderInputDTO{OrderID: 4002})
+
+		Expect(err).To(MatchError(domain.ErrOrderNotFound))
+	})
+})
@@ -0,0 +1,36 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// newOutboxMessages returns one outbox message per event, in the order the
+// events happened, due for publishing straight away. Use cases collect the
+// events with Order.PullEvents before storing the change that raised them, so
+// that no stored copy of the order carries them, and store the messages in the
+// same write; RelayOutboxUseCase publishes them afterwards.
+func newOutboxMessages(ctx context.Context, events []entity.Event) ([]*repository.OutboxMessage, error) {
+	now := time.Now()
+	messages := make([]*repository.OutboxMessage, 0, len(events))
+	for _, event := range events {
+		envelope, err := repository.NewEventEnvelope(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+		payload, err := json.Marshal(envelope)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, &repository.OutboxMessage{
+			OrderID:       event.EventOrderID(),
+			Payload:       payload,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		})
+	}
+	return messages, nil
+}
@@ -3,17 +3,71 @@ package usecase
 import (
 	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/domain/repository"
+	"context"
+	"time"
 )
 
-// GetAllOrdersUseCase retrieves all orders.
+// GetAllOrdersInputDTO is the data transfer object for listing orders.
+// Zero values leave a filter off or fall back to the default sort and page size.
+type GetAllOrdersInputDTO struct {
+	Status        string
+	Paid          *bool
+	CreatedFrom   time.Time
+	CreatedTo     time.Time
+	SortBy        string
+	SortDirection string
+	Limit         int
+	Cursor        string
+}
+
+// GetAllOrdersOutputDTO is the data transfer object for one page of orders.
+type GetAllOrdersOutputDTO struct {
+	Orders     []*entity.Order `json:"orders"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// GetAllOrdersUseCase retrieves a filtered, sorted page of orders.
 type GetAllOrdersUseCase struct {
 	OrderRepository repository.OrderRepository
 }
 
+// NewGetAllOrdersUseCase creates a new GetAllOrdersUseCase.
 func NewGetAllOrdersUseCase(orderRepo repository.OrderRepository) *GetAllOrdersUseCase {
 	return &GetAllOrdersUseCase{OrderRepository: orderRepo}
 }
 
-func (uc *GetAllOrdersUseCase) Execute() ([]*entity.Order, error) {
-	return uc.OrderRepository.GetAll()
+// Execute executes the use case. Pass the returned NextCursor back in to get the following page.
+func (uc *GetAllOrdersUseCase) Execute(ctx context.Context, input GetAllOrdersInputDTO) (*GetAllOrdersOutputDTO, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	query, err := repository.OrderQuery{
+		Status:        entity.OrderStatus(input.Status),
+		Paid:          input.Paid,
+		CreatedFrom:   input.CreatedFrom,
+		CreatedTo:     input.CreatedTo,
+		SortBy:        repository.OrderSortField(input.SortBy),
+		SortDirection: repository.SortDirection(input.SortDirection),
+		Limit:         input.Limit,
+		Cursor:        input.Cursor,
+	}.Normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := uc.OrderRepository.Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &GetAllOrdersOutputDTO{
+		Orders:     page.Orders,
+		NextCursor: page.NextCursor,
+	}
+	if output.Orders == nil {
+		output.Orders = []*entity.Order{}
+	}
+
+	return output, nil
 }
@@ -0,0 +1,111 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetAllOrdersUseCase", func() {
+	var (
+		getAllOrdersUseCase *usecase.GetAllOrdersUseCase
+		orderRepoMock       *database.OrderRepositoryMock
+		base                time.Time
+	)
+
+	orderIDs := func(orders []*entity.Order) []int {
+		ids := make([]int, 0, len(orders))
+		for _, order := range orders {
+			ids = append(ids, order.OrderID)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		getAllOrdersUseCase = usecase.NewGetAllOrdersUseCase(orderRepoMock)
+
+		base = time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		for i, status := range []entity.OrderStatus{
+			entity.OrderStatusPending,
+			entity.OrderStatusPaid,
+			entity.OrderStatusPending,
+			entity.OrderStatusPaid,
+			entity.OrderStatusPending,
+		} {
+			orderRepoMock.Save(context.Background(), &entity.Order{
+				ID:        fmt.Sprintf("order-%d", 5001+i),
+				OrderID:   5001 + i,
+				Status:    status,
+				Paid:      status == entity.OrderStatusPaid,
+				CreatedAt: base.Add(time.Duration(i) * time.Hour),
+				UpdatedAt: base.Add(time.Duration(i) * time.Hour),
+			})
+		}
+	})
+
+	It("should return the newest orders first by default", func() {
+		output, err := getAllOrdersUseCase.Execute(context.Background(), usecase.GetAllOrdersInputDTO{})
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orderIDs(output.Orders)).To(Equal([]int{5005, 5004, 5003, 5002, 5001}))
+		Expect(output.NextCursor).To(BeEmpty())
+	})
+
+	It("should filter by status, paid flag and creation time", func() {
+		paid := false
+		input := usecase.GetAllOrdersInputDTO{
+			Status:        "Pending",
+			Paid:          &paid,
+			CreatedFrom:   base.Add(time.Hour),
+			CreatedTo:     base.Add(4 * time.Hour),
+			SortDirection: "asc",
+		}
+
+		output, err := getAllOrdersUseCase.Execute(context.Background(), input)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orderIDs(output.Orders)).To(Equal([]int{5003}))
+	})
+
+	It("should page through every order exactly once", func() {
+		input := usecase.GetAllOrdersInputDTO{SortBy: "order_id", SortDirection: "asc", Limit: 2}
+		var seen []int
+
+		for {
+			output, err := getAllOrdersUseCase.Execute(context.Background(), input)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(len(output.Orders)).To(BeNumerically("<=", 2))
+			seen = append(seen, orderIDs(output.Orders)...)
+			if output.NextCursor == "" {
+				break
+			}
+			input.Cursor = output.NextCursor
+		}
+
+		Expect(seen).To(Equal([]int{5001, 5002, 5003, 5004, 5005}))
+	})
+
+	It("should reject a cursor issued for a different sort", func() {
+		first, err := getAllOrdersUseCase.Execute(context.Background(), usecase.GetAllOrdersInputDTO{Limit: 1})
+		Expect(err).NotTo(HaveOccurred())
+
+		input := usecase.GetAllOrdersInputDTO{SortBy: "order_id", Limit: 1, Cursor: first.NextCursor}
+		_, err = getAllOrdersUseCase.Execute(context.Background(), input)
+
+		Expect(err).To(MatchError(domain.ErrValidation))
+	})
+
+	It("should reject an oversized page", func() {
+		_, err := getAllOrdersUseCase.Execute(context.Background(), usecase.GetAllOrdersInputDTO{Limit: 1000})
+
+		Expect(err).To(MatchError(domain.ErrValidation))
+	})
+})
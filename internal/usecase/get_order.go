@@ -1,24 +1,48 @@
 package usecase
 
 import (
+	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/domain/repository"
+	"context"
 	"log"
 )
 
-// GetOrderByIDInputDTO is the data transfer object for getting an order by ID.
+// GetOrderByIDInputDTO is the data transfer object for getting an order by its internal ID.
 type GetOrderByIDInputDTO struct {
-	OrderID int `json:"orderId"`
+	ID string `json:"id"`
 }
 
 // GetOrderByIDOutputDTO is the data transfer object for the result of getting an order.
 type GetOrderByIDOutputDTO struct {
-	Data    string `json:"Data"`
-	OrderID int    `json:"OrderId"`
-	Status  string `json:"Status"`
-	Paid    bool   `json:"Paid"`
+	ID       string             `json:"id"`
+	Data     string             `json:"Data"`
+	OrderID  int                `json:"OrderId"`
+	Status   entity.OrderStatus `json:"Status"`
+	Paid     bool               `json:"Paid"`
+	Items    []OrderItemDTO     `json:"items,omitempty"`
+	TaxRate  int                `json:"tax_rate_bps,omitempty"`
+	Subtotal entity.Money       `json:"subtotal,omitzero"`
+	Tax      entity.Money       `json:"tax,omitzero"`
+	Total    entity.Money       `json:"total,omitzero"`
 }
 
-// GetOrderByIDUseCase is the use case for getting an order by ID.
+// newGetOrderOutput maps an order to the result of getting it.
+func newGetOrderOutput(order *entity.Order) *GetOrderByIDOutputDTO {
+	return &GetOrderByIDOutputDTO{
+		ID:       order.ID,
+		Data:     order.Data,
+		OrderID:  order.OrderID,
+		Status:   order.Status,
+		Paid:     order.Paid,
+		Items:    newOrderItemDTOs(order.Items),
+		TaxRate:  order.TaxRate,
+		Subtotal: order.Subtotal,
+		Tax:      order.Tax,
+		Total:    order.Total,
+	}
+}
+
+// GetOrderByIDUseCase is the use case for getting an order by its internal ID.
 type GetOrderByIDUseCase struct {
 	OrderRepository repository.OrderRepository
 }
@@ -29,19 +53,12 @@ func NewGetOrderByIDUseCase(orderRepository repository.OrderRepository) *GetOrde
 }
 
 // Execute executes the use case.
-func (uc *GetOrderByIDUseCase) Execute(input GetOrderByIDInputDTO) (*GetOrderByIDOutputDTO, error) {
-	log.Printf("Executing GetOrderByIDUseCase with OrderID: %d", input.OrderID)
-	order, err := uc.OrderRepository.GetByOrderID(input.OrderID)
+func (uc *GetOrderByIDUseCase) Execute(ctx context.Context, input GetOrderByIDInputDTO) (*GetOrderByIDOutputDTO, error) {
+	log.Printf("Executing GetOrderByIDUseCase with ID: %s", input.ID)
+	order, err := uc.OrderRepository.GetByID(ctx, input.ID)
 	if err != nil {
 		return nil, err
 	}
 
-	output := &GetOrderByIDOutputDTO{
-		Data:    order.Data,
-		OrderID: order.OrderID,
-		Status:  order.Status,
-		Paid:    order.Paid,
-	}
-
-	return output, nil
+	return newGetOrderOutput(order), nil
 }
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"log"
+)
+
+// GetOrderByOrderIDInputDTO is the data transfer object for getting an order by its business OrderID.
+type GetOrderByOrderIDInputDTO struct {
+	OrderID int `json:"orderId"`
+}
+
+// GetOrderByOrderIDUseCase is the use case for getting an order by its business OrderID.
+type GetOrderByOrderIDUseCase struct {
+	OrderRepository repository.OrderRepository
+}
+
+// NewGetOrderByOrderIDUseCase creates a new GetOrderByOrderIDUseCase.
+func NewGetOrderByOrderIDUseCase(orderRepository repository.OrderRepository) *GetOrderByOrderIDUseCase {
+	return &GetOrderByOrderIDUseCase{OrderRepository: orderRepository}
+}
+
+// Execute executes the use case.
+func (uc *GetOrderByOrderIDUseCase) Execute(ctx context.Context, input GetOrderByOrderIDInputDTO) (*GetOrderByIDOutputDTO, error) {
+	log.Printf("Executing GetOrderByOrderIDUseCase with OrderID: %d", input.OrderID)
+	order, err := uc.OrderRepository.GetByOrderID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newGetOrderOutput(order), nil
+}
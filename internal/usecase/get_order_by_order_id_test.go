@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GetOrderByOrderIDUseCase", func() {
+	var (
+		getOrderByOrderIDUseCase *usecase.GetOrderByOrderIDUseCase
+		orderRepoMock            *database.OrderRepositoryMock
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		getOrderByOrderIDUseCase = usecase.NewGetOrderByOrderIDUseCase(orderRepoMock)
+	})
+
+	Context("when an order exists", func() {
+		It("should return the order with its internal ID", func() {
+			existingOrder := &entity.Order{
+				ID:        "0197a3c0-0000-7000-8000-000000445566",
+				Data:      "23/06/2025",
+				OrderID:   445566,
+				Status:    entity.OrderStatusShipped,
+				Paid:      true,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			orderRepoMock.Save(context.Background(), existingOrder)
+
+			output, err := getOrderByOrderIDUseCase.Execute(context.Background(), usecase.GetOrderByOrderIDInputDTO{OrderID: 445566})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.ID).To(Equal(existingOrder.ID))
+			Expect(output.OrderID).To(Equal(existingOrder.OrderID))
+			Expect(output.Status).To(Equal(existingOrder.Status))
+		})
+	})
+
+	Context("when an order does not exist", func() {
+		It("should return an error", func() {
+			output, err := getOrderByOrderIDUseCase.Execute(context.Background(), usecase.GetOrderByOrderIDInputDTO{OrderID: 999999})
+
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+			Expect(output).To(BeNil())
+		})
+	})
+})
@@ -1,16 +1,17 @@
 package usecase_test
 
 import (
+	"GoCleanArch/internal/domain"
 	"GoCleanArch/internal/domain/entity"
 	"GoCleanArch/internal/infra/database"
 	"GoCleanArch/internal/usecase"
+	"context"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
-
 var _ = Describe("GetOrderByIDUseCase", func() {
 	var (
 		getOrderByIDUseCase *usecase.GetOrderByIDUseCase
@@ -25,34 +26,71 @@ var _ = Describe("GetOrderByIDUseCase", func() {
 	Context("when an order exists", func() {
 		It("should return the correct order details", func() {
 			existingOrder := &entity.Order{
+				ID:        "0197a3c0-0000-7000-8000-000000112233",
 				Data:      "22/06/2025",
 				OrderID:   112233,
-				Status:    "Delivered",
+				Status:    entity.OrderStatusDelivered,
 				Paid:      true,
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			orderRepoMock.Save(existingOrder)
+			orderRepoMock.Save(context.Background(), existingOrder)
 
-			input := usecase.GetOrderByIDInputDTO{OrderID: 112233}
-			output, err := getOrderByIDUseCase.Execute(input)
+			input := usecase.GetOrderByIDInputDTO{ID: existingOrder.ID}
+			output, err := getOrderByIDUseCase.Execute(context.Background(), input)
 
 			Expect(err).NotTo(HaveOccurred())
 			Expect(output).NotTo(BeNil())
+			Expect(output.ID).To(Equal(existingOrder.ID))
 			Expect(output.OrderID).To(Equal(existingOrder.OrderID))
 			Expect(output.Status).To(Equal(existingOrder.Status))
 			Expect(output.Paid).To(Equal(existingOrder.Paid))
 		})
 	})
 
+	Context("when the order has items", func() {
+		It("should return the items and totals", func() {
+			existingOrder := &entity.Order{
+				ID:      "0197a3c0-0000-7000-8000-000000112234",
+				OrderID: 112234,
+				Status:  entity.OrderStatusPending,
+				Items: []entity.OrderItem{
+					{SKU: "MUG", Description: "Coffee mug", Quantity: 4, UnitPrice: entity.Money{Amount: 800, Currency: "GBP"}},
+				},
+			}
+			Expect(existingOrder.CalculateTotals()).To(Succeed())
+			orderRepoMock.Save(context.Background(), existingOrder)
+
+			output, err := getOrderByIDUseCase.Execute(context.Background(), usecase.GetOrderByIDInputDTO{ID: existingOrder.ID})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Items).To(Equal([]usecase.OrderItemDTO{
+				{SKU: "MUG", Description: "Coffee mug", Quantity: 4, UnitPrice: entity.Money{Amount: 800, Currency: "GBP"}},
+			}))
+			Expect(output.Subtotal).To(Equal(entity.Money{Amount: 3200, Currency: "GBP"}))
+			Expect(output.Total).To(Equal(entity.Money{Amount: 3200, Currency: "GBP"}))
+		})
+	})
+
 	Context("when an order does not exist", func() {
 		It("should return an error", func() {
-			input := usecase.GetOrderByIDInputDTO{OrderID: 999999}
-			output, err := getOrderByIDUseCase.Execute(input)
+			input := usecase.GetOrderByIDInputDTO{ID: "0197a3c0-0000-7000-8000-000000999999"}
+			output, err := getOrderByIDUseCase.Execute(context.Background(), input)
+
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+			Expect(output).To(BeNil())
+		})
+	})
+
+	Context("when the context is cancelled", func() {
+		It("should stop and return the context error", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			output, err := getOrderByIDUseCase.Execute(ctx, usecase.GetOrderByIDInputDTO{ID: "0197a3c0-0000-7000-8000-000000112233"})
 
-			Expect(err).To(HaveOccurred())
+			Expect(err).To(MatchError(context.Canceled))
 			Expect(output).To(BeNil())
-			Expect(err.Error()).To(Equal("order not found"))
 		})
 	})
 })
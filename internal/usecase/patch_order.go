@@ -0,0 +1,93 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/repository"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// PatchOrderInputDTO is the data transfer object for partially updating an order.
+// Patch is an RFC 7386 JSON Merge Patch applied to the order's Data and Status.
+type PatchOrderInputDTO struct {
+	OrderID int
+	Patch   json.RawMessage
+}
+
+// PatchOrderUseCase is the use case for partially updating an order. Like
+// UpdateOrderUseCase it stores the order and its events together in the outbox.
+type PatchOrderUseCase struct {
+	OrderRepository repository.OrderRepository
+	Outbox          repository.OrderOutbox
+}
+
+// NewPatchOrderUseCase creates a new PatchOrderUseCase.
+func NewPatchOrderUseCase(orderRepository repository.OrderRepository, outbox repository.OrderOutbox) *PatchOrderUseCase {
+	return &PatchOrderUseCase{OrderRepository: orderRepository, Outbox: outbox}
+}
+
+// Execute executes the use case. Fields missing from the patch keep their
+// current value and fields set to null are cleared.
+func (uc *PatchOrderUseCase) Execute(ctx context.Context, input PatchOrderInputDTO) (*UpdateOrderOutputDTO, error) {
+	order, err := uc.OrderRepository.GetByOrderID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := json.Marshal(UpdateOrderInputDTO{Data: order.Data, Status: string(order.Status)})
+	if err != nil {
+		return nil, err
+	}
+	patched, err := mergePatch(current, input.Patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid merge patch: %v", domain.ErrValidation, err)
+	}
+
+	var update UpdateOrderInputDTO
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&update); err != nil {
+		return nil, fmt.Errorf("%w: invalid merge patch: %v", domain.ErrValidation, err)
+	}
+	update.OrderID = order.OrderID
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	return applyOrderUpdate(ctx, uc.Outbox, order, update)
+}
+
+// mergePatch applies an RFC 7386 JSON Merge Patch to a JSON document.
+func mergePatch(target, patch []byte) ([]byte, error) {
+	var targetValue, patchValue interface{}
+	if err := json.Unmarshal(target, &targetValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatchValue(targetValue, patchValue))
+}
+
+// mergePatchValue merges patch into target: objects are merged key by key,
+// null removes a key and any other value replaces the target outright.
+func mergePatchValue(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatchValue(targetObject[key], value)
+	}
+	return targetObject
+}
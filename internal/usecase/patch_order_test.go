@@ -0,0 +1,72 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/usecase"
+	"GoCleanArch/internal/validation"
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PatchOrderUseCase", func() {
+	var (
+		patchOrderUseCase *usecase.PatchOrderUseCase
+		orderRepoMock     *database.OrderRepositoryMock
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		patchOrderUseCase = usecase.NewPatchOrderUseCase(orderRepoMock, orderRepoMock)
+
+		orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-3001", Data: "05/07/2025", OrderID: 3001, Status: entity.OrderStatusPending})
+	})
+
+	It("should only change the fields present in the patch", func() {
+		input := usecase.PatchOrderInputDTO{OrderID: 3001, Patch: []byte(`{"Status":"Cancelled"}`)}
+
+		output, err := patchOrderUseCase.Execute(context.Background(), input)
+
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output.Status).To(Equal(entity.OrderStatusCancelled))
+		Expect(output.Data).To(Equal("05/07/2025"))
+	})
+
+	It("should clear fields set to null and validate the result", func() {
+		input := usecase.PatchOrderInputDTO{OrderID: 3001, Patch: []byte(`{"Data":null}`)}
+
+		_, err := patchOrderUseCase.Execute(context.Background(), input)
+
+		var fieldErrors validation.Errors
+		Expect(errors.As(err, &fieldErrors)).To(BeTrue())
+		Expect(fieldErrors).To(ContainElement(validation.FieldError{Field: "Data", Message: "is required"}))
+	})
+
+	It("should reject fields the order does not have", func() {
+		input := usecase.PatchOrderInputDTO{OrderID: 3001, Patch: []byte(`{"Paid":true}`)}
+
+		_, err := patchOrderUseCase.Execute(context.Background(), input)
+
+		Expect(err).To(MatchError(domain.ErrValidation))
+	})
+
+	It("should reject a patch that does not fit the order", func() {
+		input := usecase.PatchOrderInputDTO{OrderID: 3001, Patch: []byte(`{"Data":42}`)}
+
+		_, err := patchOrderUseCase.Execute(context.Background(), input)
+
+		Expect(err).To(MatchError(domain.ErrValidation))
+	})
+
+	It("should reject an illegal status transition", func() {
+		input := usecase.PatchOrderInputDTO{OrderID: 3001, Patch: []byte(`{"Status":"Shipped"}`)}
+
+		_, err := patchOrderUseCase.Execute(context.Background(), input)
+
+		Expect(err).To(MatchError(domain.ErrConflict))
+	})
+})
@@ -0,0 +1,192 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PayOrderInputDTO is the data transfer object for paying for an order.
+// OrderID comes from the request path, not the body. PaymentMethod is the
+// payment provider's token for the customer's card or account.
+type PayOrderInputDTO struct {
+	OrderID       int    `json:"-"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+// PaymentOutputDTO is the data transfer object for a payment.
+type PaymentOutputDTO struct {
+	ID                string               `json:"id"`
+	OrderID           int                  `json:"OrderId"`
+	Amount            entity.Money         `json:"amount"`
+	Status            entity.PaymentStatus `json:"status"`
+	ProviderReference string               `json:"provider_reference,omitempty"`
+	CreatedAt         time.Time            `json:"created_at"`
+	CapturedAt        time.Time            `json:"captured_at,omitzero"`
+}
+
+// newPaymentOutput maps a payment to its data transfer object.
+func newPaymentOutput(payment *entity.Payment) *PaymentOutputDTO {
+	return &PaymentOutputDTO{
+		ID:                payment.ID,
+		OrderID:           payment.OrderID,
+		Amount:            payment.Amount,
+		Status:            payment.Status,
+		ProviderReference: payment.ProviderReference,
+		CreatedAt:         payment.CreatedAt,
+		CapturedAt:        payment.CapturedAt,
+	}
+}
+
+// PayOrderUseCase is the use case for charging the total of an order through
+// the payment gateway and marking the order Paid. The Paid order and its
+// events are stored together in the outbox.
+type PayOrderUseCase struct {
+	OrderRepository   repository.OrderRepository
+	PaymentRepository repository.PaymentRepository
+	PaymentGateway    repository.PaymentGateway
+	Outbox            repository.OrderOutbox
+}
+
+// NewPayOrderUseCase creates a new PayOrderUseCase.
+func NewPayOrderUseCase(orderRepository repository.OrderRepository, paymentRepository repository.PaymentRepository, paymentGateway repository.PaymentGateway, outbox repository.OrderOutbox) *PayOrderUseCase {
+	return &PayOrderUseCase{
+		OrderRepository:   orderRepository,
+		PaymentRepository: paymentRepository,
+		PaymentGateway:    paymentGateway,
+		Outbox:            outbox,
+	}
+}
+
+// Execute executes the use case. The order is paid in steps that each leave a
+// record that a retry picks up from:
+//
+//  1. A Pending payment is saved, then the order is claimed by updating it at
+//     the version it was read at. Of two concurrent payments only one claims
+//     the order; the other payment is abandoned and domain.ErrConflict is
+//     returned before anything is charged.
+//  2. The total is authorized and captured, and the payment is updated after
+//     each step.
+//  3. The order is marked Paid and stored with its OrderStatusChanged and
+//     OrderPaid events in the outbox.
+//
+// When a step fails after the payment was saved, the payment stays Pending,
+// Authorized or Captured and the next payment of the order resumes it instead
+// of charging again. Declined payments are recorded too and return an
+// *entity.PaymentDeclinedError.
+func (uc *PayOrderUseCase) Execute(ctx context.Context, input PayOrderInputDTO) (*PaymentOutputDTO, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	order, err := uc.OrderRepository.GetByOrderID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.Status.CanTransitionTo(entity.OrderStatusPaid) {
+		return nil, &entity.InvalidTransitionError{From: order.Status, To: entity.OrderStatusPaid}
+	}
+	if order.Total.Amount <= 0 {
+		return nil, fmt.Errorf("%w: order %d has no total to pay", domain.ErrValidation, order.OrderID)
+	}
+
+	payment, err := uc.unfinishedPayment(ctx, order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	resumed := payment != nil
+	if !resumed {
+		if payment, err = entity.NewPayment(order.OrderID, order.Total); err != nil {
+			return nil, err
+		}
+		if err := uc.PaymentRepository.Save(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	order.UpdatedAt = time.Now()
+	if err := uc.OrderRepository.Update(ctx, order); err != nil {
+		if !resumed {
+			payment.Abandon()
+			if updateErr := uc.PaymentRepository.Update(ctx, payment); updateErr != nil {
+				return nil, errors.Join(err, updateErr)
+			}
+		}
+		return nil, err
+	}
+
+	if payment.Status == entity.PaymentStatusPending {
+		reference, err := uc.PaymentGateway.Authorize(ctx, repository.PaymentRequest{
+			PaymentID:     payment.ID,
+			OrderID:       order.OrderID,
+			Amount:        payment.Amount,
+			PaymentMethod: input.PaymentMethod,
+		})
+		if err != nil {
+			return nil, uc.recordFailure(ctx, payment, err)
+		}
+		payment.Authorize(reference)
+		if err := uc.PaymentRepository.Update(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+	if payment.Status == entity.PaymentStatusAuthorized {
+		if err := uc.PaymentGateway.Capture(ctx, payment.ProviderReference, payment.Amount); err != nil {
+			return nil, uc.recordFailure(ctx, payment, err)
+		}
+		payment.Capture()
+		if err := uc.PaymentRepository.Update(ctx, payment); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := order.Pay(payment); err != nil {
+		return nil, err
+	}
+	messages, err := newOutboxMessages(ctx, order.PullEvents())
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.Outbox.UpdateWithMessages(ctx, order, messages); err != nil {
+		return nil, err
+	}
+
+	return newPaymentOutput(payment), nil
+}
+
+// unfinishedPayment returns the payment an earlier attempt to pay an unpaid
+// order left behind, preferring the one that got furthest: Captured, then
+// Authorized, then Pending. It returns nil when there is none.
+func (uc *PayOrderUseCase) unfinishedPayment(ctx context.Context, orderID int) (*entity.Payment, error) {
+	payments, err := uc.PaymentRepository.ListByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range []entity.PaymentStatus{entity.PaymentStatusCaptured, entity.PaymentStatusAuthorized, entity.PaymentStatusPending} {
+		for i := len(payments) - 1; i >= 0; i-- {
+			if payments[i].Status == status {
+				return payments[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// recordFailure records a payment the gateway declined and returns the
+// gateway error. After any other error the payment keeps its status, so that
+// the next attempt resumes it.
+func (uc *PayOrderUseCase) recordFailure(ctx context.Context, payment *entity.Payment, gatewayErr error) error {
+	var declined *entity.PaymentDeclinedError
+	if !errors.As(gatewayErr, &declined) {
+		return gatewayErr
+	}
+	payment.Decline(declined.Reason)
+	if err := uc.PaymentRepository.Update(ctx, payment); err != nil {
+		return errors.Join(gatewayErr, err)
+	}
+	return gatewayErr
+}
@@ -0,0 +1,200 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/payment"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// failingOutbox is an order outbox whose UpdateWithMessages and UpdateWithRefund always fail.
+type failingOutbox struct {
+	*database.OrderRepositoryMock
+}
+
+func (o failingOutbox) UpdateWithMessages(ctx context.Context, order *entity.Order, messages []*repository.OutboxMessage) error {
+	return errors.New("database unavailable")
+}
+
+func (o failingOutbox) UpdateWithRefund(ctx context.Context, order *entity.Order, refund *entity.Refund, messages []*repository.OutboxMessage) error {
+	return errors.New("database unavailable")
+}
+
+var _ = Describe("PayOrderUseCase", func() {
+	var (
+		payOrderUseCase *usecase.PayOrderUseCase
+		orderRepoMock   *database.OrderRepositoryMock
+		paymentRepoMock *database.PaymentRepositoryMock
+		gatewayMock     *payment.PaymentGatewayMock
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		paymentRepoMock = database.NewPaymentRepositoryMock()
+		gatewayMock = payment.NewPaymentGatewayMock(map[string]string{"tok_declined": "insufficient_funds"})
+		payOrderUseCase = usecase.NewPayOrderUseCase(orderRepoMock, paymentRepoMock, gatewayMock, orderRepoMock)
+
+		order := &entity.Order{
+			ID:      "order-6001",
+			OrderID: 6001,
+			Status:  entity.OrderStatusPending,
+			Items:   []entity.OrderItem{{SKU: "BOOK", Quantity: 2, UnitPrice: entity.Money{Amount: 1500, Currency: "USD"}}},
+		}
+		Expect(order.CalculateTotals()).To(Succeed())
+		Expect(orderRepoMock.Save(context.Background(), order)).To(Succeed())
+	})
+
+	Context("when the gateway approves the payment", func() {
+		It("should capture the total, record the payment and mark the order Paid", func() {
+			output, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Status).To(Equal(entity.PaymentStatusCaptured))
+			Expect(output.Amount).To(Equal(entity.Money{Amount: 3000, Currency: "USD"}))
+			Expect(output.CapturedAt).NotTo(BeZero())
+			Expect(gatewayMock.Captured(output.ProviderReference)).To(BeTrue())
+
+			payments, err := paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payments).To(HaveLen(1))
+			Expect(payments[0].ID).To(Equal(output.ID))
+
+			order, err := orderRepoMock.GetByOrderID(context.Background(), 6001)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(order.Status).To(Equal(entity.OrderStatusPaid))
+			Expect(order.Paid).To(BeTrue())
+		})
+
+		It("should not charge an order twice", func() {
+			_, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+			payments, _ := paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(payments).To(HaveLen(1))
+		})
+	})
+
+	Context("when the order is paid concurrently", func() {
+		It("should capture only one payment and abandon the others", func() {
+			const attempts = 10
+			var wg sync.WaitGroup
+			errs := make([]error, attempts)
+			for i := range attempts {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, errs[i] = payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+				}()
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				Expect(err).To(MatchError(domain.ErrConflict))
+			}
+			Expect(succeeded).To(Equal(1))
+			payments, err := paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(err).NotTo(HaveOccurred())
+			captured := 0
+			for _, payment := range payments {
+				if payment.Status == entity.PaymentStatusCaptured {
+					captured++
+					continue
+				}
+				Expect(payment.Status).To(Equal(entity.PaymentStatusAbandoned))
+				Expect(payment.ProviderReference).To(BeEmpty())
+			}
+			Expect(captured).To(Equal(1))
+		})
+	})
+
+	Context("when the order cannot be stored after the capture", func() {
+		It("should keep the captured payment and finish it on the next attempt without charging again", func() {
+			failing := usecase.NewPayOrderUseCase(orderRepoMock, paymentRepoMock, gatewayMock, failingOutbox{orderRepoMock})
+			_, err := failing.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+			Expect(err).To(HaveOccurred())
+
+			payments, _ := paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(payments).To(HaveLen(1))
+			Expect(payments[0].Status).To(Equal(entity.PaymentStatusCaptured))
+			order, _ := orderRepoMock.GetByOrderID(context.Background(), 6001)
+			Expect(order.Status).To(Equal(entity.OrderStatusPending))
+
+			output, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_visa"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.ID).To(Equal(payments[0].ID))
+			Expect(output.ProviderReference).To(Equal(payments[0].ProviderReference))
+			payments, _ = paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(payments).To(HaveLen(1))
+			order, _ = orderRepoMock.GetByOrderID(context.Background(), 6001)
+			Expect(order.Status).To(Equal(entity.OrderStatusPaid))
+			events := outboxEvents(orderRepoMock)
+			Expect(events).To(HaveLen(2))
+			Expect(events[1].Type).To(Equal(entity.EventTypeOrderPaid))
+		})
+	})
+
+	Context("when the gateway declines the payment", func() {
+		It("should record the declined payment and leave the order unpaid", func() {
+			output, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001, PaymentMethod: "tok_declined"})
+
+			Expect(output).To(BeNil())
+			Expect(err).To(MatchError(domain.ErrPaymentDeclined))
+			var declined *entity.PaymentDeclinedError
+			Expect(errors.As(err, &declined)).To(BeTrue())
+			Expect(declined.Reason).To(Equal("insufficient_funds"))
+
+			payments, err := paymentRepoMock.ListByOrderID(context.Background(), 6001)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(payments).To(HaveLen(1))
+			Expect(payments[0].Status).To(Equal(entity.PaymentStatusDeclined))
+			Expect(payments[0].DeclineReason).To(Equal("insufficient_funds"))
+
+			order, _ := orderRepoMock.GetByOrderID(context.Background(), 6001)
+			Expect(order.Status).To(Equal(entity.OrderStatusPending))
+			Expect(order.Paid).To(BeFalse())
+		})
+	})
+
+	Context("when the order has nothing to pay", func() {
+		It("should reject the payment", func() {
+			Expect(orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-6002", OrderID: 6002, Data: "no items", Status: entity.OrderStatusPending})).To(Succeed())
+
+			_, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6002, PaymentMethod: "tok_visa"})
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+		})
+	})
+
+	Context("when the order does not exist", func() {
+		It("should return domain.ErrOrderNotFound", func() {
+			_, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 404, PaymentMethod: "tok_visa"})
+
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+
+	Context("when the payment method is missing", func() {
+		It("should return a validation error", func() {
+			_, err := payOrderUseCase.Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 6001})
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+		})
+	})
+})
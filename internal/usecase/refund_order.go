@@ -0,0 +1,232 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/validation"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RefundOrderInputDTO is the data transfer object for refunding an order.
+// OrderID comes from the request path, not the body. Amount is in the minor
+// unit of the payment's currency; leaving it out refunds everything that has
+// not been refunded yet.
+type RefundOrderInputDTO struct {
+	OrderID int    `json:"-"`
+	Amount  *int64 `json:"amount"`
+	Reason  string `json:"reason"`
+}
+
+// RefundOutputDTO is the data transfer object for a refund. RefundedTotal is
+// the sum of every refund of the order so far, and OrderStatus is the order's
+// status after the refund.
+type RefundOutputDTO struct {
+	ID                string             `json:"id"`
+	OrderID           int                `json:"OrderId"`
+	PaymentID         string             `json:"payment_id"`
+	Amount            entity.Money       `json:"amount"`
+	Reason            string             `json:"reason,omitempty"`
+	ProviderReference string             `json:"provider_reference"`
+	CreatedAt         time.Time          `json:"created_at"`
+	RefundedTotal     entity.Money       `json:"refunded_total"`
+	OrderStatus       entity.OrderStatus `json:"order_status"`
+}
+
+// RefundOrderUseCase is the use case for returning part or all of an order's
+// captured payment to the customer. The order and its events are stored
+// together in the outbox.
+type RefundOrderUseCase struct {
+	OrderRepository   repository.OrderRepository
+	PaymentRepository repository.PaymentRepository
+	RefundRepository  repository.RefundRepository
+	PaymentGateway    repository.PaymentGateway
+	Outbox            repository.OrderOutbox
+}
+
+// NewRefundOrderUseCase creates a new RefundOrderUseCase.
+func NewRefundOrderUseCase(orderRepository repository.OrderRepository, paymentRepository repository.PaymentRepository, refundRepository repository.RefundRepository, paymentGateway repository.PaymentGateway, outbox repository.OrderOutbox) *RefundOrderUseCase {
+	return &RefundOrderUseCase{
+		OrderRepository:   orderRepository,
+		PaymentRepository: paymentRepository,
+		RefundRepository:  refundRepository,
+		PaymentGateway:    paymentGateway,
+		Outbox:            outbox,
+	}
+}
+
+// Execute executes the use case. Refunds are bounded by the captured amount
+// less earlier refunds, pending ones included. The refund is made in steps
+// that each leave a record that a retry picks up from:
+//
+//  1. A Pending refund with a new ID is saved, then the order is claimed by
+//     updating it at the version it was read at. Of two concurrent refunds
+//     only one claims the order; the other refund is abandoned and
+//     domain.ErrConflict is returned before anything is refunded.
+//  2. The payment provider refunds the amount under the refund's ID, which it
+//     uses as an idempotency key.
+//  3. The order moves to Refunded once everything has been refunded and to
+//     PartiallyRefunded before that, and is stored with OrderRefunded, after
+//     OrderStatusChanged when the status changes, in the outbox, in the same
+//     transaction that marks the refund Completed.
+//
+// A refund the provider declines is abandoned. When any other step fails the
+// refund stays Pending: the next request for the same amount and reason
+// resumes it without refunding twice, and any other returns
+// domain.ErrConflict.
+func (uc *RefundOrderUseCase) Execute(ctx context.Context, input RefundOrderInputDTO) (*RefundOutputDTO, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	order, err := uc.OrderRepository.GetByOrderID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if !order.Status.CanTransitionTo(entity.OrderStatusRefunded) {
+		return nil, &entity.InvalidTransitionError{From: order.Status, To: entity.OrderStatusRefunded}
+	}
+
+	payment, err := uc.capturedPayment(ctx, order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	refunds, err := uc.RefundRepository.ListByOrderID(ctx, order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	refund, refunded, err := pendingRefund(refunds, payment)
+	if err != nil {
+		return nil, err
+	}
+	refundable := payment.Amount.Amount - refunded.Amount
+
+	resumed := refund != nil
+	if resumed {
+		if !matchesRefund(input, refund, refundable) {
+			return nil, fmt.Errorf("%w: refund %s of order %d is still pending; retry it with the same amount and reason", domain.ErrConflict, refund.ID, order.OrderID)
+		}
+	} else {
+		if refundable <= 0 {
+			return nil, fmt.Errorf("%w: payment %s has already been refunded in full", domain.ErrConflict, payment.ID)
+		}
+		amount := refundable
+		if input.Amount != nil {
+			if *input.Amount > refundable {
+				var v validation.Validator
+				v.AddError("amount", fmt.Sprintf("must not exceed the refundable amount of %d", refundable))
+				return nil, v.Err()
+			}
+			amount = *input.Amount
+		}
+		if refund, err = entity.NewRefund(payment, entity.Money{Amount: amount, Currency: payment.Amount.Currency}, input.Reason); err != nil {
+			return nil, err
+		}
+		if err := uc.RefundRepository.Save(ctx, refund); err != nil {
+			return nil, err
+		}
+	}
+
+	order.UpdatedAt = time.Now()
+	if err := uc.OrderRepository.Update(ctx, order); err != nil {
+		if !resumed {
+			return nil, uc.abandon(ctx, refund, err)
+		}
+		return nil, err
+	}
+
+	reference, err := uc.PaymentGateway.Refund(ctx, payment.ProviderReference, refund.ID, refund.Amount)
+	if err != nil {
+		var declined *entity.PaymentDeclinedError
+		if errors.As(err, &declined) {
+			return nil, uc.abandon(ctx, refund, err)
+		}
+		return nil, err
+	}
+	refund.Complete(reference)
+	if refunded, err = refunded.Add(refund.Amount); err != nil {
+		return nil, err
+	}
+	if err := order.Refund(refund, refunded, payment.Amount); err != nil {
+		return nil, err
+	}
+	messages, err := newOutboxMessages(ctx, order.PullEvents())
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.Outbox.UpdateWithRefund(ctx, order, refund, messages); err != nil {
+		return nil, err
+	}
+
+	return &RefundOutputDTO{
+		ID:                refund.ID,
+		OrderID:           refund.OrderID,
+		PaymentID:         refund.PaymentID,
+		Amount:            refund.Amount,
+		Reason:            refund.Reason,
+		ProviderReference: refund.ProviderReference,
+		CreatedAt:         refund.CreatedAt,
+		RefundedTotal:     refunded,
+		OrderStatus:       order.Status,
+	}, nil
+}
+
+// abandon records that a refund will never be made and returns err.
+func (uc *RefundOrderUseCase) abandon(ctx context.Context, refund *entity.Refund, err error) error {
+	refund.Abandon()
+	if updateErr := uc.RefundRepository.Update(ctx, refund); updateErr != nil {
+		return errors.Join(err, updateErr)
+	}
+	return err
+}
+
+// capturedPayment returns the most recent captured payment of an order.
+func (uc *RefundOrderUseCase) capturedPayment(ctx context.Context, orderID int) (*entity.Payment, error) {
+	payments, err := uc.PaymentRepository.ListByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(payments) - 1; i >= 0; i-- {
+		if payments[i].Status == entity.PaymentStatusCaptured {
+			return payments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: order %d has no captured payment to refund", domain.ErrConflict, orderID)
+}
+
+// pendingRefund returns the oldest Pending refund of a payment, or nil when
+// there is none, and the sum of its other refunds that were not abandoned.
+func pendingRefund(refunds []*entity.Refund, payment *entity.Payment) (*entity.Refund, entity.Money, error) {
+	var pending *entity.Refund
+	refunded := entity.Money{Currency: payment.Amount.Currency}
+	for _, refund := range refunds {
+		if refund.PaymentID != payment.ID || refund.Status == entity.RefundStatusAbandoned {
+			continue
+		}
+		if refund.Status == entity.RefundStatusPending && pending == nil {
+			pending = refund
+			continue
+		}
+		var err error
+		if refunded, err = refunded.Add(refund.Amount); err != nil {
+			return nil, entity.Money{}, err
+		}
+	}
+	return pending, refunded, nil
+}
+
+// matchesRefund reports whether input asks for the pending refund again: the
+// same reason, and the same amount or, without an amount, everything that is
+// refundable apart from it.
+func matchesRefund(input RefundOrderInputDTO, refund *entity.Refund, refundable int64) bool {
+	if input.Reason != refund.Reason {
+		return false
+	}
+	if input.Amount == nil {
+		return refund.Amount.Amount == refundable
+	}
+	return *input.Amount == refund.Amount.Amount
+}
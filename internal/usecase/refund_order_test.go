@@ -0,0 +1,297 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/payment"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// unavailableGateway is a payment gateway whose Refund always fails before
+// reaching the provider.
+type unavailableGateway struct {
+	*payment.PaymentGatewayMock
+}
+
+func (g unavailableGateway) Refund(ctx context.Context, reference, refundID string, amount entity.Money) (string, error) {
+	return "", errors.New("provider unavailable")
+}
+
+var _ = Describe("RefundOrderUseCase", func() {
+	var (
+		refundOrderUseCase *usecase.RefundOrderUseCase
+		orderRepoMock      *database.OrderRepositoryMock
+		refundRepoMock     *database.RefundRepositoryMock
+		gatewayMock        *payment.PaymentGatewayMock
+		captured           *usecase.PaymentOutputDTO
+	)
+
+	amount := func(n int64) *int64 { return &n }
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		paymentRepoMock := database.NewPaymentRepositoryMock()
+		refundRepoMock = database.NewRefundRepositoryMock()
+		orderRepoMock.Refunds = refundRepoMock
+		gatewayMock = payment.NewPaymentGatewayMock(nil)
+		refundOrderUseCase = usecase.NewRefundOrderUseCase(orderRepoMock, paymentRepoMock, refundRepoMock, gatewayMock, orderRepoMock)
+
+		order := &entity.Order{
+			ID:      "order-7001",
+			OrderID: 7001,
+			Status:  entity.OrderStatusPending,
+			Items:   []entity.OrderItem{{SKU: "LAMP", Quantity: 1, UnitPrice: entity.Money{Amount: 5000, Currency: "EUR"}}},
+		}
+		Expect(order.CalculateTotals()).To(Succeed())
+		Expect(orderRepoMock.Save(context.Background(), order)).To(Succeed())
+
+		var err error
+		captured, err = usecase.NewPayOrderUseCase(orderRepoMock, paymentRepoMock, gatewayMock, orderRepoMock).
+			Execute(context.Background(), usecase.PayOrderInputDTO{OrderID: 7001, PaymentMethod: "tok_visa"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(outboxEvents(orderRepoMock)).To(HaveLen(2))
+	})
+
+	Context("when no amount is given", func() {
+		It("should refund the whole payment and mark the order Refunded", func() {
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Reason: "changed mind"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Amount).To(Equal(entity.Money{Amount: 5000, Currency: "EUR"}))
+			Expect(output.PaymentID).To(Equal(captured.ID))
+			Expect(output.RefundedTotal.Amount).To(Equal(int64(5000)))
+			Expect(output.OrderStatus).To(Equal(entity.OrderStatusRefunded))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(5000)))
+
+			order, _ := orderRepoMock.GetByOrderID(context.Background(), 7001)
+			Expect(order.Status).To(Equal(entity.OrderStatusRefunded))
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Reason).To(Equal("changed mind"))
+		})
+
+		It("should add OrderStatusChanged and OrderRefunded to the outbox", func() {
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001})
+			Expect(err).NotTo(HaveOccurred())
+
+			events := outboxEvents(orderRepoMock)[2:]
+			Expect(events).To(HaveLen(2))
+			Expect(events[0].Type).To(Equal(entity.EventTypeOrderStatusChanged))
+			Expect(events[1].Type).To(Equal(entity.EventTypeOrderRefunded))
+			envelope := events[1]
+			Expect(envelope.OrderID).To(Equal(7001))
+			var message entity.OrderRefunded
+			Expect(json.Unmarshal(envelope.Data, &message)).To(Succeed())
+			Expect(message.RefundID).To(Equal(output.ID))
+			Expect(message.Amount.Amount).To(Equal(int64(5000)))
+			Expect(message.Status).To(Equal(entity.OrderStatusRefunded))
+		})
+	})
+
+	Context("when part of the payment is refunded", func() {
+		It("should mark the order PartiallyRefunded until everything is refunded", func() {
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(1500)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.OrderStatus).To(Equal(entity.OrderStatusPartiallyRefunded))
+			Expect(output.RefundedTotal.Amount).To(Equal(int64(1500)))
+
+			output, err = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(1000)})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.OrderStatus).To(Equal(entity.OrderStatusPartiallyRefunded))
+			Expect(output.RefundedTotal.Amount).To(Equal(int64(2500)))
+
+			output, err = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Amount.Amount).To(Equal(int64(2500)))
+			Expect(output.OrderStatus).To(Equal(entity.OrderStatusRefunded))
+
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(3))
+		})
+
+		It("should reject refunding more than is left", func() {
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(4000)})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(1001)})
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(4000)))
+		})
+	})
+
+	Context("when the order cannot be stored after the refund", func() {
+		It("should keep the refund and finish it on the next attempt without refunding again", func() {
+			failing := usecase.NewRefundOrderUseCase(orderRepoMock, refundOrderUseCase.PaymentRepository, refundRepoMock, gatewayMock, failingOutbox{orderRepoMock})
+			_, err := failing.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+			Expect(err).To(HaveOccurred())
+
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusPending))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(2000)))
+
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.ID).To(Equal(refunds[0].ID))
+			Expect(output.OrderStatus).To(Equal(entity.OrderStatusPartiallyRefunded))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(2000)))
+			refunds, _ = refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusCompleted))
+			events := outboxEvents(orderRepoMock)[2:]
+			Expect(events).To(HaveLen(2))
+			Expect(events[1].Type).To(Equal(entity.EventTypeOrderRefunded))
+		})
+	})
+
+	Context("when the refund cannot be marked Completed after the refund", func() {
+		It("should store neither the order nor its events and finish both on the next attempt", func() {
+			orderRepoMock.Refunds = database.NewRefundRepositoryMock()
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+			Expect(err).To(MatchError(domain.ErrRefundNotFound))
+
+			order, _ := orderRepoMock.GetByOrderID(context.Background(), 7001)
+			Expect(order.Status).To(Equal(entity.OrderStatusPaid))
+			Expect(outboxEvents(orderRepoMock)).To(HaveLen(2))
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusPending))
+
+			orderRepoMock.Refunds = refundRepoMock
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.ID).To(Equal(refunds[0].ID))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(2000)))
+			refunds, _ = refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusCompleted))
+			events := outboxEvents(orderRepoMock)[2:]
+			Expect(events).To(HaveLen(2))
+			Expect(events[1].Type).To(Equal(entity.EventTypeOrderRefunded))
+		})
+	})
+
+	Context("when the provider fails before answering", func() {
+		It("should keep the refund Pending and resume it under the same ID", func() {
+			unavailable := usecase.NewRefundOrderUseCase(orderRepoMock, refundOrderUseCase.PaymentRepository, refundRepoMock, unavailableGateway{gatewayMock}, orderRepoMock)
+			_, err := unavailable.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+			Expect(err).To(HaveOccurred())
+
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusPending))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(BeZero())
+
+			output, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000), Reason: "damaged"})
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.ID).To(Equal(refunds[0].ID))
+			Expect(output.RefundedTotal.Amount).To(Equal(int64(2000)))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(2000)))
+			refunds, _ = refundRepoMock.ListByOrderID(context.Background(), 7001)
+			Expect(refunds).To(HaveLen(1))
+			Expect(refunds[0].Status).To(Equal(entity.RefundStatusCompleted))
+		})
+
+		It("should reject a different refund while one is pending", func() {
+			unavailable := usecase.NewRefundOrderUseCase(orderRepoMock, refundOrderUseCase.PaymentRepository, refundRepoMock, unavailableGateway{gatewayMock}, orderRepoMock)
+			_, err := unavailable.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(2000)})
+			Expect(err).To(HaveOccurred())
+
+			_, err = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(1000)})
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(BeZero())
+		})
+	})
+
+	Context("when the order is refunded concurrently", func() {
+		It("should refund only once and abandon the other refunds", func() {
+			const attempts = 10
+			var wg sync.WaitGroup
+			errs := make([]error, attempts)
+			for i := range attempts {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, errs[i] = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001})
+				}()
+			}
+			wg.Wait()
+
+			succeeded := 0
+			for _, err := range errs {
+				if err == nil {
+					succeeded++
+					continue
+				}
+				Expect(err).To(MatchError(domain.ErrConflict))
+			}
+			Expect(succeeded).To(Equal(1))
+			Expect(gatewayMock.Refunded(captured.ProviderReference)).To(Equal(int64(5000)))
+			refunds, _ := refundRepoMock.ListByOrderID(context.Background(), 7001)
+			completed := 0
+			for _, refund := range refunds {
+				if refund.Status == entity.RefundStatusCompleted {
+					completed++
+					continue
+				}
+				Expect(refund.Status).To(Equal(entity.RefundStatusAbandoned))
+			}
+			Expect(completed).To(Equal(1))
+		})
+	})
+
+	Context("when the order was refunded in full", func() {
+		It("should return domain.ErrConflict", func() {
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001})
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+		})
+	})
+
+	Context("when the order has not been paid", func() {
+		It("should return domain.ErrConflict", func() {
+			Expect(orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-7002", OrderID: 7002, Data: "unpaid", Status: entity.OrderStatusPending})).To(Succeed())
+
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7002})
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+		})
+
+		It("should return domain.ErrConflict when the order is Paid without a captured payment", func() {
+			Expect(orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-7003", OrderID: 7003, Data: "marked paid", Status: entity.OrderStatusPaid})).To(Succeed())
+
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7003})
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+		})
+	})
+
+	Context("with invalid input", func() {
+		It("should reject an amount that is not positive", func() {
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 7001, Amount: amount(0)})
+
+			Expect(err).To(MatchError(domain.ErrValidation))
+		})
+
+		It("should return domain.ErrOrderNotFound for a missing order", func() {
+			_, err := refundOrderUseCase.Execute(context.Background(), usecase.RefundOrderInputDTO{OrderID: 404})
+
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+})
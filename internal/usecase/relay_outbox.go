@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+const (
+	// outboxBaseBackoff is the wait before the first retry of a failed outbox message.
+	outboxBaseBackoff = time.Second
+	// outboxMaxBackoff caps the wait between retries.
+	outboxMaxBackoff = 5 * time.Minute
+)
+
+// RelayOutboxOutputDTO is the data transfer object for the result of relaying a batch of outbox messages.
+type RelayOutboxOutputDTO struct {
+	Published int
+	Failed    int
+}
+
+// RelayOutboxUseCase is the use case for publishing the events waiting in the outbox.
+// Messages are published at least once: a crash between publishing and marking a
+// message as sent publishes it again, so consumers must tolerate duplicates.
+type RelayOutboxUseCase struct {
+	Outbox         repository.OrderOutbox
+	EventPublisher repository.EventPublisher
+	BatchSize      int
+	MaxAttempts    int
+}
+
+// NewRelayOutboxUseCase creates a new RelayOutboxUseCase.
+func NewRelayOutboxUseCase(outbox repository.OrderOutbox, eventPublisher repository.EventPublisher, batchSize, maxAttempts int) *RelayOutboxUseCase {
+	return &RelayOutboxUseCase{Outbox: outbox, EventPublisher: eventPublisher, BatchSize: batchSize, MaxAttempts: maxAttempts}
+}
+
+// Execute publishes one batch of pending messages, in a single request when
+// the publisher is a BatchEventPublisher. A message that fails is retried with
+// exponential backoff until it has been tried MaxAttempts times, after which it
+// stays in the outbox for an operator to look at.
+func (uc *RelayOutboxUseCase) Execute(ctx context.Context) (*RelayOutboxOutputDTO, error) {
+	messages, err := uc.Outbox.FetchPending(ctx, uc.MaxAttempts, uc.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	if batchPublisher, ok := uc.EventPublisher.(repository.BatchEventPublisher); ok {
+		errs = uc.publishBatch(ctx, batchPublisher, messages)
+	}
+
+	output := &RelayOutboxOutputDTO{}
+	for i, message := range messages {
+		var err error
+		if errs != nil {
+			err = errs[i]
+		} else {
+			err = uc.publish(ctx, message)
+		}
+		if err != nil {
+			log.Printf("Error publishing outbox message %d for order %d (attempt %d): %v", message.ID, message.OrderID, message.Attempts+1, err)
+			nextAttemptAt := time.Now().Add(outboxBackoff(message.Attempts))
+			if err := uc.Outbox.MarkFailed(ctx, message.ID, err.Error(), nextAttemptAt); err != nil {
+				return output, err
+			}
+			output.Failed++
+			continue
+		}
+
+		if err := uc.Outbox.MarkSent(ctx, message.ID, time.Now()); err != nil {
+			return output, err
+		}
+		output.Published++
+	}
+
+	return output, nil
+}
+
+// publish publishes the event in an outbox message.
+func (uc *RelayOutboxUseCase) publish(ctx context.Context, message *repository.OutboxMessage) error {
+	envelope, err := outboxEnvelope(ctx, message)
+	if err != nil {
+		return err
+	}
+	return uc.EventPublisher.Publish(ctx, envelope)
+}
+
+// publishBatch publishes the events in outbox messages together and returns
+// one error for each message.
+func (uc *RelayOutboxUseCase) publishBatch(ctx context.Context, publisher repository.BatchEventPublisher, messages []*repository.OutboxMessage) []error {
+	errs := make([]error, len(messages))
+	envelopes := make([]*repository.EventEnvelope, 0, len(messages))
+	indexes := make([]int, 0, len(messages))
+	for i, message := range messages {
+		envelope, err := outboxEnvelope(ctx, message)
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		envelopes = append(envelopes, envelope)
+		indexes = append(indexes, i)
+	}
+	if len(envelopes) == 0 {
+		return errs
+	}
+
+	for j, err := range publisher.PublishBatch(ctx, envelopes) {
+		errs[indexes[j]] = err
+	}
+	return errs
+}
+
+// outboxEnvelope returns the event in an outbox message. Messages stored
+// before events were introduced hold the order itself and are published as
+// OrderCreated.
+func outboxEnvelope(ctx context.Context, message *repository.OutboxMessage) (*repository.EventEnvelope, error) {
+	var envelope repository.EventEnvelope
+	if err := json.Unmarshal(message.Payload, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Type != "" {
+		return &envelope, nil
+	}
+
+	var order entity.Order
+	if err := json.Unmarshal(message.Payload, &order); err != nil {
+		return nil, err
+	}
+	event := entity.OrderCreated{OrderEvent: entity.OrderEvent{OrderID: order.OrderID, OccurredAt: order.CreatedAt}, Order: order}
+	return repository.NewEventEnvelope(ctx, event)
+}
+
+// outboxBackoff returns how long to wait before retrying a message that has
+// already failed the given number of times.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxBaseBackoff
+	for i := 0; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > outboxMaxBackoff {
+		backoff = outboxMaxBackoff
+	}
+	return backoff
+}
@@ -0,0 +1,161 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/infra/messaging"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// failingEventPublisher is an EventPublisher whose publishes always fail.
+type failingEventPublisher struct{}
+
+func (failingEventPublisher) Publish(ctx context.Context, envelope *repository.EventEnvelope) error {
+	return errors.New("queue unavailable")
+}
+
+// partlyFailingBatchPublisher publishes batches to a message queue mock, except
+// for the events of one order, which fail.
+type partlyFailingBatchPublisher struct {
+	*messaging.OrderMessageQueueMock
+	failingOrderID int
+	batches        int
+}
+
+func (p *partlyFailingBatchPublisher) PublishBatch(ctx context.Context, envelopes []*repository.EventEnvelope) []error {
+	p.batches++
+	errs := make([]error, len(envelopes))
+	for i, envelope := range envelopes {
+		if envelope.OrderID == p.failingOrderID {
+			errs[i] = errors.New("throttled")
+			continue
+		}
+		errs[i] = p.Publish(ctx, envelope)
+	}
+	return errs
+}
+
+var _ = Describe("RelayOutboxUseCase", func() {
+	var (
+		orderRepoMock    *database.OrderRepositoryMock
+		messageQueueMock *messaging.OrderMessageQueueMock
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		messageQueueMock = messaging.NewOrderMessageQueueMock()
+
+		createOrderUseCase := usecase.NewCreateOrderUseCase(orderRepoMock)
+		_, err := createOrderUseCase.Execute(context.Background(), usecase.CreateOrderInputDTO{Data: "first", OrderID: 1})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = createOrderUseCase.Execute(context.Background(), usecase.CreateOrderInputDTO{Data: "second", OrderID: 2})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	Context("when the queue accepts the messages", func() {
+		It("should publish every pending message and mark it sent", func() {
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, messageQueueMock, 10, 3)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Published).To(Equal(2))
+			Expect(output.Failed).To(Equal(0))
+
+			messages, err := messageQueueMock.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(messages).To(HaveLen(2))
+			Expect(messages[0].Type).To(Equal(entity.EventTypeOrderCreated))
+
+			pending, err := orderRepoMock.FetchPending(context.Background(), 3, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(pending).To(BeEmpty())
+		})
+
+		It("should publish no more than the batch size", func() {
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, messageQueueMock, 1, 3)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Published).To(Equal(1))
+		})
+	})
+
+	Context("when a message was stored before events were introduced", func() {
+		It("should publish the order it holds as OrderCreated", func() {
+			orderRepoMock = database.NewOrderRepositoryMock()
+			order := &entity.Order{ID: "order-3", OrderID: 3, Data: "legacy", Status: entity.OrderStatusPending, CreatedAt: time.Now()}
+			payload, err := json.Marshal(order)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orderRepoMock.SaveWithMessage(context.Background(), order, &repository.OutboxMessage{OrderID: 3, Payload: payload, CreatedAt: time.Now(), NextAttemptAt: time.Now()})).To(Succeed())
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, messageQueueMock, 10, 3)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Published).To(Equal(1))
+			messages, err := messageQueueMock.Receive(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(messages[0].Body, &envelope)).To(Succeed())
+			Expect(envelope.Type).To(Equal(entity.EventTypeOrderCreated))
+			Expect(envelope.OrderID).To(Equal(3))
+		})
+	})
+
+	Context("when the queue rejects the messages", func() {
+		It("should record the failure and back off before the next attempt", func() {
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, failingEventPublisher{}, 10, 3)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Failed).To(Equal(2))
+
+			output, err = relayOutboxUseCase.Execute(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Failed).To(Equal(0))
+		})
+
+		It("should publish a batch in one request and record only the entries that failed", func() {
+			publisher := &partlyFailingBatchPublisher{OrderMessageQueueMock: messageQueueMock, failingOrderID: 2}
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, publisher, 10, 3)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(publisher.batches).To(Equal(1))
+			Expect(output.Published).To(Equal(1))
+			Expect(output.Failed).To(Equal(1))
+			messages := messageQueueMock.Messages()
+			Expect(messages).To(HaveLen(1))
+			var envelope repository.EventEnvelope
+			Expect(json.Unmarshal(messages[0].Body, &envelope)).To(Succeed())
+			Expect(envelope.OrderID).To(Equal(1))
+		})
+
+		It("should stop retrying after the maximum number of attempts", func() {
+			pending, err := orderRepoMock.FetchPending(context.Background(), 1, 10)
+			Expect(err).NotTo(HaveOccurred())
+			for _, message := range pending {
+				Expect(orderRepoMock.MarkFailed(context.Background(), message.ID, "queue unavailable", time.Now().Add(-time.Second))).To(Succeed())
+			}
+			relayOutboxUseCase := usecase.NewRelayOutboxUseCase(orderRepoMock, messageQueueMock, 10, 1)
+
+			output, err := relayOutboxUseCase.Execute(context.Background())
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Published).To(Equal(0))
+			Expect(output.Failed).To(Equal(0))
+		})
+	})
+})
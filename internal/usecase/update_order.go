@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"context"
+	"time"
+)
+
+// UpdateOrderInputDTO is the data transfer object for replacing an order.
+// OrderID identifies the order and comes from the request path, not the body.
+type UpdateOrderInputDTO struct {
+	OrderID int    `json:"-"`
+	Data    string `json:"Data"`
+	Status  string `json:"Status"`
+}
+
+// UpdateOrderOutputDTO is the data transfer object for the result of updating an order.
+type UpdateOrderOutputDTO struct {
+	ID      string             `json:"id"`
+	Data    string             `json:"Data"`
+	OrderID int                `json:"OrderId"`
+	Status  entity.OrderStatus `json:"Status"`
+	Paid    bool               `json:"Paid"`
+}
+
+// UpdateOrderUseCase is the use case for replacing the data and status of an
+// order. The order and its events are stored together in the outbox.
+type UpdateOrderUseCase struct {
+	OrderRepository repository.OrderRepository
+	Outbox          repository.OrderOutbox
+}
+
+// NewUpdateOrderUseCase creates a new UpdateOrderUseCase.
+func NewUpdateOrderUseCase(orderRepository repository.OrderRepository, outbox repository.OrderOutbox) *UpdateOrderUseCase {
+	return &UpdateOrderUseCase{OrderRepository: orderRepository, Outbox: outbox}
+}
+
+// Execute executes the use case.
+func (uc *UpdateOrderUseCase) Execute(ctx context.Context, input UpdateOrderInputDTO) (*UpdateOrderOutputDTO, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	order, err := uc.OrderRepository.GetByOrderID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyOrderUpdate(ctx, uc.Outbox, order, input)
+}
+
+// applyOrderUpdate moves order to the data and status in input, bumps UpdatedAt,
+// and stores the result together with the events the status change raised. A
+// status change must be allowed by the order status transition table; a change
+// of Data alone raises no event.
+func applyOrderUpdate(ctx context.Context, outbox repository.OrderOutbox, order *entity.Order, input UpdateOrderInputDTO) (*UpdateOrderOutputDTO, error) {
+	status, err := entity.ParseOrderStatus(input.Status)
+	if err != nil {
+		return nil, err
+	}
+	if status != order.Status {
+		if err := order.Transition(status); err != nil {
+			return nil, err
+		}
+	}
+	order.Data = input.Data
+	order.UpdatedAt = time.Now()
+
+	messages, err := newOutboxMessages(ctx, order.PullEvents())
+	if err != nil {
+		return nil, err
+	}
+	if err := outbox.UpdateWithMessages(ctx, order, messages); err != nil {
+		return nil, err
+	}
+
+	output := &UpdateOrderOutputDTO{
+		ID:      order.ID,
+		Data:    order.Data,
+		OrderID: order.OrderID,
+		Status:  order.Status,
+		Paid:    order.Paid,
+	}
+
+	return output, nil
+}
@@ -0,0 +1,126 @@
+package usecase_test
+
+import (
+	"GoCleanArch/internal/domain"
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/infra/database"
+	"GoCleanArch/internal/usecase"
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("UpdateOrderUseCase", func() {
+	var (
+		updateOrderUseCase *usecase.UpdateOrderUseCase
+		orderRepoMock      *database.OrderRepositoryMock
+		createdAt          time.Time
+	)
+
+	BeforeEach(func() {
+		orderRepoMock = database.NewOrderRepositoryMock()
+		updateOrderUseCase = usecase.NewUpdateOrderUseCase(orderRepoMock, orderRepoMock)
+
+		createdAt = time.Now().Add(-time.Hour)
+		orderRepoMock.Save(context.Background(), &entity.Order{
+			ID:        "order-2001",
+			Data:      "01/07/2025",
+			OrderID:   2001,
+			Status:    entity.OrderStatusPending,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+	})
+
+	Context("when the new status is allowed", func() {
+		It("should store the updated order with its events and return it", func() {
+			Expect(orderRepoMock.Save(context.Background(), &entity.Order{ID: "order-2002", Data: "01/07/2025", OrderID: 2002, Status: entity.OrderStatusPaid, Paid: true, CreatedAt: createdAt, UpdatedAt: createdAt})).To(Succeed())
+			input := usecase.UpdateOrderInputDTO{OrderID: 2002, Data: "02/07/2025", Status: "Shipped"}
+
+			output, err := updateOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Data).To(Equal("02/07/2025"))
+			Expect(output.Status).To(Equal(entity.OrderStatusShipped))
+			Expect(output.Paid).To(BeTrue())
+
+			stored, err := orderRepoMock.GetByOrderID(context.Background(), 2002)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stored.Data).To(Equal("02/07/2025"))
+			Expect(stored.UpdatedAt).To(BeTemporally(">", createdAt))
+
+			events := outboxEvents(orderRepoMock)
+			Expect(events).To(HaveLen(1))
+			Expect(events[0].Type).To(Equal(entity.EventTypeOrderStatusChanged))
+		})
+
+		It("should add OrderCancelled to the outbox when the order is cancelled", func() {
+			input := usecase.UpdateOrderInputDTO{OrderID: 2001, Data: "01/07/2025", Status: "Cancelled"}
+
+			_, err := updateOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).NotTo(HaveOccurred())
+			events := outboxEvents(orderRepoMock)
+			Expect(events).To(HaveLen(2))
+			envelope := events[1]
+			Expect(envelope.Type).To(Equal(entity.EventTypeOrderCancelled))
+			Expect(envelope.SchemaVersion).To(Equal(1))
+			Expect(envelope.OrderID).To(Equal(2001))
+			Expect(string(envelope.Data)).To(MatchJSON(`{"OrderId":2001,"from":"Pending"}`))
+		})
+	})
+
+	Context("when only the data changes", func() {
+		It("should keep the status and add no event", func() {
+			input := usecase.UpdateOrderInputDTO{OrderID: 2001, Data: "03/07/2025", Status: "Pending"}
+
+			output, err := updateOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.Status).To(Equal(entity.OrderStatusPending))
+			Expect(orderRepoMock.OutboxMessages()).To(BeEmpty())
+		})
+	})
+
+	Context("when the transition is not allowed", func() {
+		It("should return a conflict and leave the order alone", func() {
+			input := usecase.UpdateOrderInputDTO{OrderID: 2001, Data: "04/07/2025", Status: "Delivered"}
+
+			output, err := updateOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).To(MatchError(domain.ErrConflict))
+			Expect(output).To(BeNil())
+
+			stored, _ := orderRepoMock.GetByOrderID(context.Background(), 2001)
+			Expect(stored.Data).To(Equal("01/07/2025"))
+		})
+	})
+
+	Context("when the new status is only reached through a payment or a refund", func() {
+		It("should return a validation error and leave the order alone", func() {
+			for _, status := range []string{"Paid", "PartiallyRefunded", "Refunded"} {
+				input := usecase.UpdateOrderInputDTO{OrderID: 2001, Data: "04/07/2025", Status: status}
+
+				_, err := updateOrderUseCase.Execute(context.Background(), input)
+
+				Expect(err).To(MatchError(domain.ErrValidation))
+			}
+			stored, _ := orderRepoMock.GetByOrderID(context.Background(), 2001)
+			Expect(stored.Status).To(Equal(entity.OrderStatusPending))
+			Expect(stored.Paid).To(BeFalse())
+			Expect(orderRepoMock.OutboxMessages()).To(BeEmpty())
+		})
+	})
+
+	Context("when the order does not exist", func() {
+		It("should return not found", func() {
+			input := usecase.UpdateOrderInputDTO{OrderID: 9999, Data: "x", Status: "Paid"}
+
+			_, err := updateOrderUseCase.Execute(context.Background(), input)
+
+			Expect(err).To(MatchError(domain.ErrOrderNotFound))
+		})
+	})
+})
@@ -1,6 +1,9 @@
 package usecase_test
 
 import (
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/infra/database"
+	"encoding/json"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -11,3 +14,15 @@ func TestUsecases(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Usecase Suite")
 }
+
+// outboxEvents returns the event of every message in the mock outbox, in the
+// order they were added.
+func outboxEvents(outbox *database.OrderRepositoryMock) []*repository.EventEnvelope {
+	var envelopes []*repository.EventEnvelope
+	for _, message := range outbox.OutboxMessages() {
+		var envelope repository.EventEnvelope
+		Expect(json.Unmarshal(message.Payload, &envelope)).To(Succeed())
+		envelopes = append(envelopes, &envelope)
+	}
+	return envelopes
+}
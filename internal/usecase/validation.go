@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"GoCleanArch/internal/domain/entity"
+	"GoCleanArch/internal/domain/repository"
+	"GoCleanArch/internal/validation"
+	"fmt"
+	"math"
+)
+
+const (
+	// MaxOrderDataLength is the longest Data an order may carry.
+	MaxOrderDataLength = 1000
+	// MaxOrderID is the largest OrderID the orders table can store.
+	MaxOrderID = math.MaxInt32
+	// MaxOrderItems is the most items an order may have.
+	MaxOrderItems = 100
+	// MaxSKULength is the longest SKU an item may have.
+	MaxSKULength = 64
+	// MaxItemDescriptionLength is the longest description an item may have.
+	MaxItemDescriptionLength = 255
+	// MaxItemQuantity is the largest quantity of one item.
+	MaxItemQuantity = 10000
+	// MaxUnitPrice is the highest unit price, in minor units. Together with the
+	// other limits it keeps every total well inside an int64.
+	MaxUnitPrice = 1_000_000_000_000
+	// MaxPaymentMethodLength is the longest payment method token accepted.
+	MaxPaymentMethodLength = 255
+	// MaxRefundReasonLength is the longest reason a refund may give.
+	MaxRefundReasonLength = 255
+)
+
+// Validate checks the input for creating an order. Data may be left empty
+// when the order has items.
+func (input CreateOrderInputDTO) Validate() error {
+	var v validation.Validator
+	v.Range("OrderId", input.OrderID, 1, MaxOrderID)
+	if len(input.Items) == 0 {
+		v.Required("Data", input.Data)
+	}
+	v.MaxLength("Data", input.Data, MaxOrderDataLength)
+	if input.Status != "" {
+		v.OneOf("Status", input.Status, string(entity.OrderStatusPending))
+	}
+	v.Check(len(input.Items) <= MaxOrderItems, "items", fmt.Sprintf("must have at most %d items", MaxOrderItems))
+	for i, item := range input.Items {
+		field := fmt.Sprintf("items[%d].", i)
+		v.Required(field+"sku", item.SKU)
+		v.MaxLength(field+"sku", item.SKU, MaxSKULength)
+		v.MaxLength(field+"description", item.Description, MaxItemDescriptionLength)
+		v.Range(field+"quantity", item.Quantity, 1, MaxItemQuantity)
+		v.Check(item.UnitPrice.Amount >= 0 && item.UnitPrice.Amount <= MaxUnitPrice, field+"unit_price.amount", fmt.Sprintf("must be between 0 and %d", MaxUnitPrice))
+		if err := entity.ValidateCurrency(item.UnitPrice.Currency); err != nil {
+			v.AddError(field+"unit_price.currency", "must be an ISO 4217 currency code")
+		} else {
+			v.Check(item.UnitPrice.Currency == input.Items[0].UnitPrice.Currency, field+"unit_price.currency", "must match the currency of the first item")
+		}
+	}
+	v.Range("tax_rate_bps", input.TaxRate, 0, entity.MaxTaxRate)
+	return v.Err()
+}
+
+// Validate checks the input for replacing an order.
+func (input UpdateOrderInputDTO) Validate() error {
+	var v validation.Validator
+	v.Required("Data", input.Data)
+	v.MaxLength("Data", input.Data, MaxOrderDataLength)
+	v.OneOf("Status", input.Status, orderStatusNames()...)
+	return v.Err()
+}
+
+// Validate checks the input for paying for an order.
+func (input PayOrderInputDTO) Validate() error {
+	var v validation.Validator
+	v.Required("payment_method", input.PaymentMethod)
+	v.MaxLength("payment_method", input.PaymentMethod, MaxPaymentMethodLength)
+	return v.Err()
+}
+
+// Validate checks the input for refunding an order. Amount may be left out
+// to refund everything that has not been refunded yet.
+func (input RefundOrderInputDTO) Validate() error {
+	var v validation.Validator
+	if input.Amount != nil {
+		v.Check(*input.Amount > 0, "amount", "must be greater than 0")
+	}
+	v.MaxLength("reason", input.Reason, MaxRefundReasonLength)
+	return v.Err()
+}
+
+// Validate checks the input for listing orders.
+func (input GetAllOrdersInputDTO) Validate() error {
+	var v validation.Validator
+	if input.Status != "" {
+		v.OneOf("status", input.Status, orderStatusNames()...)
+	}
+	if input.SortBy != "" {
+		v.OneOf("sort", input.SortBy, string(repository.OrderSortByCreatedAt), string(repository.OrderSortByUpdatedAt), string(repository.OrderSortByOrderID))
+	}
+	if input.SortDirection != "" {
+		v.OneOf("direction", input.SortDirection, string(repository.SortAscending), string(repository.SortDescending))
+	}
+	if input.Limit != 0 {
+		v.Range("limit", input.Limit, 1, repository.MaxOrderPageSize)
+	}
+	if !input.CreatedFrom.IsZero() && !input.CreatedTo.IsZero() {
+		v.Check(input.CreatedFrom.Before(input.CreatedTo), "created_to", "must be after created_from")
+	}
+	return v.Err()
+}
+
+// orderStatusNames returns the name of every order status.
+func orderStatusNames() []string {
+	statuses := entity.OrderStatuses()
+	names := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		names = append(names, string(status))
+	}
+	return names
+}
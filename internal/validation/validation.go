@@ -0,0 +1,82 @@
+// Package validation checks input against simple field rules and reports every
+// broken rule at once, so that clients can fix a request in one round trip.
+package validation
+
+import (
+	"GoCleanArch/internal/domain"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldError describes why a single field is invalid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of field errors found while validating a value.
+// It matches domain.ErrValidation.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fieldError := range e {
+		messages = append(messages, fieldError.Field+": "+fieldError.Message)
+	}
+	return domain.ErrValidation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e Errors) Unwrap() error {
+	return domain.ErrValidation
+}
+
+// Validator collects field errors. The zero value is ready to use.
+type Validator struct {
+	errors Errors
+}
+
+// AddError records an error for a field.
+func (v *Validator) AddError(field, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Message: message})
+}
+
+// Check records an error for a field unless ok is true.
+func (v *Validator) Check(ok bool, field, message string) {
+	if !ok {
+		v.AddError(field, message)
+	}
+}
+
+// Required checks that a string field is not blank.
+func (v *Validator) Required(field, value string) {
+	v.Check(strings.TrimSpace(value) != "", field, "is required")
+}
+
+// MaxLength checks that a string field has at most max characters.
+func (v *Validator) MaxLength(field, value string, max int) {
+	v.Check(utf8.RuneCountInString(value) <= max, field, fmt.Sprintf("must be at most %d characters", max))
+}
+
+// Range checks that an integer field lies between min and max inclusive.
+func (v *Validator) Range(field string, value, min, max int) {
+	v.Check(value >= min && value <= max, field, fmt.Sprintf("must be between %d and %d", min, max))
+}
+
+// OneOf checks that a string field is one of the allowed values.
+func (v *Validator) OneOf(field, value string, allowed ...string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.AddError(field, "must be one of "+strings.Join(allowed, ", "))
+}
+
+// Err returns the collected errors, or nil if every check passed.
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}